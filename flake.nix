@@ -27,35 +27,61 @@
 
       moduleDefs = {
         "agent-usage" = {
-          src = ./modules/agent-usage;
+          dir = "agent-usage";
           bin = "waybar-agent-usage";
-          vendorHash = null;
+          vendorHash = "sha256-KeMIIdkGhj3DMl65XTkQw/wUQiDS58dHGGhCU0yFT6Q=";
         };
 
         github = {
-          src = ./modules/github;
+          dir = "github";
           bin = "waybar-github";
-          vendorHash = "sha256-SGZah7Hz7cGLk/8cOKkMpA4eGEtRCyENCPAjJQeswsc=";
+          vendorHash = "sha256-aOt8hjaZVp9ce5k3WXsPH2ZlfcvkJMCBaYyjswyEITQ=";
         };
 
         linear = {
-          src = ./modules/linear;
+          dir = "linear";
           bin = "waybar-linear";
-          vendorHash = "sha256-SGZah7Hz7cGLk/8cOKkMpA4eGEtRCyENCPAjJQeswsc=";
+          vendorHash = "sha256-aOt8hjaZVp9ce5k3WXsPH2ZlfcvkJMCBaYyjswyEITQ=";
         };
 
         schedule = {
-          src = ./modules/schedule;
+          dir = "schedule";
           bin = "waybar-schedule";
-          vendorHash = "sha256-v42cUuT2z+y7BKjVTG/cbIQc32HG2YQ7k/zjNfPsL6s=";
+          vendorHash = "sha256-sNCR7TSMdLzYXUSukCeFGZ4SPFcs1FfF/MghSHHAdg4=";
         };
 
         sotto = {
-          src = ./modules/sotto;
+          dir = "sotto";
           bin = "waybar-sotto";
-          vendorHash = "sha256-RZ5TFzYh/bVICuQqvVfcU+o9BCe9brMV0rpRlAjNpE8=";
+          vendorHash = "sha256-QxwtpiCdBjFB/++XPHPIqJ4FGcSIeLEwYMhfZrp8foc=";
+        };
+
+        host = {
+          dir = "host";
+          bin = "waybar-modules";
+          vendorHash = "sha256-dZnET9HsTVMuc5RqMJUuEi7mfP9r49odHMLgI6Njip8=";
+          uses = [
+            "agent-usage"
+            "github"
+            "linear"
+            "schedule"
+            "sotto"
+          ];
         };
       };
+
+      # Modules build against modules/shared (and the host against every
+      # module) through go.mod replace directives, so each source tree carries
+      # those directories too. Their code is vendored, which means changing
+      # them changes the dependents' vendorHash.
+      moduleSrc =
+        def:
+        nixpkgs.lib.fileset.toSource {
+          root = ./modules;
+          fileset = nixpkgs.lib.fileset.unions (
+            map (dir: ./modules + "/${dir}") ([ def.dir "shared" ] ++ (def.uses or [ ]))
+          );
+        };
     in
     {
       packages = forAllSystems (
@@ -66,7 +92,8 @@
             pkgs.buildGoModule {
               pname = def.bin;
               version = "0.1.0";
-              src = def.src;
+              src = moduleSrc def;
+              modRoot = def.dir;
               env.GOWORK = "off";
               vendorHash = def.vendorHash;
               subPackages = [ "cmd/${def.bin}" ];
@@ -1,3 +1,9 @@
 module github.com/rbright/waybar-agent-usage
 
 go 1.25.5
+
+require github.com/godbus/dbus/v5 v5.1.0 // indirect
+
+require github.com/rbright/waybar-shared v0.0.0
+
+replace github.com/rbright/waybar-shared => ../shared
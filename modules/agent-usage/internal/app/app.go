@@ -2,8 +2,11 @@ package app
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"io"
+	"log/slog"
+	"strconv"
 	"strings"
 	"time"
 
@@ -12,9 +15,23 @@ import (
 	"github.com/rbright/waybar-agent-usage/internal/providers"
 	"github.com/rbright/waybar-agent-usage/internal/state"
 	"github.com/rbright/waybar-agent-usage/internal/waybar"
+	"github.com/rbright/waybar-shared/barsignal"
+	"github.com/rbright/waybar-shared/logging"
+	"github.com/rbright/waybar-shared/network"
 )
 
 func Run(ctx context.Context, args []string, cfg config.Runtime, stdout io.Writer) error {
+	if len(args) > 0 && strings.TrimSpace(args[0]) == "logs" {
+		lines, err := parseLogsArgs(args[1:])
+		if err != nil {
+			return err
+		}
+		return logging.Tail(cfg.LogPath, lines, stdout)
+	}
+	if len(args) == 1 && strings.TrimSpace(args[0]) == "doctor" {
+		return runDoctor(ctx, cfg, stdout)
+	}
+
 	provider, refresh, err := parseArgs(args)
 	if err != nil {
 		return err
@@ -23,22 +40,45 @@ func Run(ctx context.Context, args []string, cfg config.Runtime, stdout io.Write
 	icons := waybar.IconConfig{Codex: cfg.CodexIcon, Claude: cfg.ClaudeIcon}
 	cacheStore := state.NewStore(cfg.StateDir)
 
-	cached, _ := cacheStore.Load(provider)
+	cached, loadErr := cacheStore.Load(provider)
+	if loadErr != nil && !errors.Is(loadErr, state.ErrNotFound) {
+		slog.Warn("cache load failed", "provider", provider, "error", loadErr)
+	}
 	if cached != nil && !refresh {
 		if cfg.CacheTTL <= 0 || time.Since(cached.FetchedAt) < cfg.CacheTTL {
+			slog.Debug("cache hit", "provider", provider, "age", time.Since(cached.FetchedAt))
 			output := waybar.Render(cached.Metrics, cached.FetchedAt, icons, "")
 			return writeOutput(stdout, output)
 		}
 	}
 
+	if cfg.NetworkCheck && !network.Online(ctx, providers.UsageURL(provider)) {
+		slog.Info("offline; skipping provider fetch", "provider", provider)
+		if cached != nil {
+			return writeOutput(stdout, waybar.RenderOffline(cached.Metrics, cached.FetchedAt, icons))
+		}
+		output := waybar.RenderError(provider, icons, "Offline: no cached usage yet")
+		output.Class += " offline"
+		return writeOutput(stdout, output)
+	}
+
+	start := time.Now()
 	metrics, fetchErr := fetchProvider(ctx, provider, cfg)
 	if fetchErr == nil {
+		slog.Debug("provider fetched", "provider", provider, "duration", time.Since(start), "refresh", refresh)
 		now := time.Now().UTC()
-		_ = cacheStore.Save(provider, metrics, now) // Best-effort cache persistence.
+		if err := cacheStore.Save(provider, metrics, now); err != nil {
+			// Best-effort cache persistence.
+			slog.Warn("cache save failed", "provider", provider, "error", err)
+		}
+		if refresh {
+			signalBar(cfg, provider)
+		}
 		output := waybar.Render(metrics, now, icons, "")
 		return writeOutput(stdout, output)
 	}
 
+	slog.Warn("provider fetch failed", "provider", provider, "duration", time.Since(start), "cached", cached != nil, "error", fetchErr)
 	if cached != nil {
 		output := waybar.Render(cached.Metrics, cached.FetchedAt, icons, fetchErr.Error())
 		return writeOutput(stdout, output)
@@ -82,6 +122,20 @@ func parseArgs(args []string) (domain.Provider, bool, error) {
 	return provider, refresh, nil
 }
 
+func parseLogsArgs(args []string) (int, error) {
+	if len(args) > 1 {
+		return 0, fmt.Errorf("usage: waybar-agent-usage logs [lines]")
+	}
+	if len(args) == 0 {
+		return 50, nil
+	}
+	lines, err := strconv.Atoi(strings.TrimSpace(args[0]))
+	if err != nil || lines < 1 {
+		return 0, fmt.Errorf("invalid line count %q", args[0])
+	}
+	return lines, nil
+}
+
 func fetchProvider(ctx context.Context, provider domain.Provider, cfg config.Runtime) (domain.Metrics, error) {
 	switch provider {
 	case domain.ProviderCodex:
@@ -93,6 +147,17 @@ func fetchProvider(ctx context.Context, provider domain.Provider, cfg config.Run
 	}
 }
 
+func signalBar(cfg config.Runtime, provider domain.Provider) {
+	signal := cfg.CodexSignal
+	if provider == domain.ProviderClaude {
+		signal = cfg.ClaudeSignal
+	}
+	if err := barsignal.Send(signal); err != nil {
+		// The next interval catches up, so a failed signal only delays the bar.
+		slog.Debug("signal waybar failed", "signal", signal, "error", err)
+	}
+}
+
 func writeOutput(w io.Writer, output waybar.Output) error {
 	payload, err := waybar.Encode(output)
 	if err != nil {
@@ -0,0 +1,39 @@
+package app
+
+import (
+	"context"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/rbright/waybar-agent-usage/internal/config"
+	"github.com/rbright/waybar-shared/doctor"
+)
+
+func runDoctor(_ context.Context, cfg config.Runtime, stdout io.Writer) error {
+	var report doctor.Report
+
+	report.ConfigFile("config", cfg.EnvFile, config.ValidateEnvFile)
+	report.LogLevel(cfg.LogLevel)
+	checkCredentials(&report, "codex credentials", cfg.CodexAccessToken, cfg.CodexAuthFile, "WAYBAR_AI_CODEX_ACCESS_TOKEN")
+	checkCredentials(&report, "claude credentials", cfg.ClaudeAccessToken, cfg.ClaudeCredentialsFile, "WAYBAR_AI_CLAUDE_ACCESS_TOKEN")
+	report.WritableDir("state dir", cfg.StateDir)
+
+	if err := report.Write(stdout); err != nil {
+		return err
+	}
+	return report.Err()
+}
+
+// checkCredentials only warns: either provider may be intentionally unused.
+func checkCredentials(report *doctor.Report, name, token, path, envName string) {
+	if strings.TrimSpace(token) != "" {
+		report.OK(name, envName+" set")
+		return
+	}
+	if _, err := os.Stat(path); err != nil {
+		report.Warn(name, path+" not readable and "+envName+" not set")
+		return
+	}
+	report.OK(name, path)
+}
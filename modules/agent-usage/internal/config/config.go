@@ -5,10 +5,12 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"strconv"
 	"strings"
 	"time"
 
 	"github.com/rbright/waybar-agent-usage/internal/domain"
+	"github.com/rbright/waybar-shared/daemon"
 )
 
 type Runtime struct {
@@ -19,16 +21,27 @@ type Runtime struct {
 	EnvFile       string
 	ClaudeRetries int
 
+	// NetworkCheck skips remote fetches while offline and renders the cache instead.
+	NetworkCheck bool
+
 	CodexHome        string
 	CodexAuthFile    string
 	CodexAccessToken string
 	CodexAccountID   string
 	CodexIcon        string
+	CodexSignal      int
 
 	ClaudeCredentialsFile string
 	ClaudeAccessToken     string
 	ClaudeClientID        string
 	ClaudeIcon            string
+	ClaudeSignal          int
+
+	SocketPath     string
+	DaemonInterval time.Duration
+
+	LogLevel string
+	LogPath  string
 }
 
 func Load() (Runtime, error) {
@@ -69,12 +82,14 @@ func Load() (Runtime, error) {
 			1,
 			domain.ParseInt(os.Getenv("WAYBAR_AI_CLAUDE_REFRESH_RETRIES"), 3),
 		),
+		NetworkCheck: parseBool(os.Getenv("WAYBAR_AI_NETWORK_CHECK"), true),
 
 		CodexHome:        codexHome,
 		CodexAuthFile:    firstNonEmpty(os.Getenv("WAYBAR_AI_CODEX_AUTH_FILE"), filepath.Join(codexHome, "auth.json")),
 		CodexAccessToken: strings.TrimSpace(os.Getenv("WAYBAR_AI_CODEX_ACCESS_TOKEN")),
 		CodexAccountID:   strings.TrimSpace(os.Getenv("WAYBAR_AI_CODEX_ACCOUNT_ID")),
 		CodexIcon:        firstNonEmpty(os.Getenv("WAYBAR_AI_CODEX_ICON"), "\ue7cf"),
+		CodexSignal:      domain.ParseInt(os.Getenv("WAYBAR_AI_CODEX_SIGNAL"), 0),
 
 		ClaudeCredentialsFile: firstNonEmpty(os.Getenv("WAYBAR_AI_CLAUDE_CREDENTIALS_FILE"), filepath.Join(home, ".claude", ".credentials.json")),
 		ClaudeAccessToken:     strings.TrimSpace(os.Getenv("WAYBAR_AI_CLAUDE_ACCESS_TOKEN")),
@@ -82,7 +97,14 @@ func Load() (Runtime, error) {
 			os.Getenv("WAYBAR_AI_CLAUDE_CLIENT_ID"),
 			"9d1c250a-e61b-44d9-88ed-5944d1962f5e",
 		),
-		ClaudeIcon: firstNonEmpty(os.Getenv("WAYBAR_AI_CLAUDE_ICON"), "\ue861"),
+		ClaudeIcon:   firstNonEmpty(os.Getenv("WAYBAR_AI_CLAUDE_ICON"), "\ue861"),
+		ClaudeSignal: domain.ParseInt(os.Getenv("WAYBAR_AI_CLAUDE_SIGNAL"), 0),
+
+		SocketPath:     firstNonEmpty(os.Getenv("WAYBAR_AI_SOCKET_PATH"), daemon.SocketPath("agent-usage")),
+		DaemonInterval: time.Duration(domain.ParseInt(os.Getenv("WAYBAR_AI_DAEMON_INTERVAL_SECONDS"), 60)) * time.Second,
+
+		LogLevel: firstNonEmpty(os.Getenv("WAYBAR_AI_LOG_LEVEL"), "warn"),
+		LogPath:  filepath.Join(stateDir, "agent-usage.log"),
 	}
 
 	if cfg.Timeout <= 0 {
@@ -91,6 +113,15 @@ func Load() (Runtime, error) {
 	if cfg.CacheTTL < 0 {
 		cfg.CacheTTL = 0
 	}
+	if cfg.CodexSignal < 0 || cfg.CodexSignal > 30 {
+		cfg.CodexSignal = 0
+	}
+	if cfg.ClaudeSignal < 0 || cfg.ClaudeSignal > 30 {
+		cfg.ClaudeSignal = 0
+	}
+	if cfg.DaemonInterval <= 0 {
+		cfg.DaemonInterval = 60 * time.Second
+	}
 
 	return cfg, nil
 }
@@ -147,6 +178,40 @@ func LoadEnvFile(path string) error {
 	return nil
 }
 
+// ValidateEnvFile reports lines that the env-file loader would silently skip.
+func ValidateEnvFile(path string) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("open env file %s: %w", path, err)
+	}
+	defer func() {
+		_ = file.Close()
+	}()
+
+	var problems []string
+	scanner := bufio.NewScanner(file)
+	for lineNumber := 1; scanner.Scan(); lineNumber++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		line = strings.TrimSpace(strings.TrimPrefix(line, "export "))
+
+		key, _, ok := strings.Cut(line, "=")
+		if !ok || strings.TrimSpace(key) == "" {
+			problems = append(problems, fmt.Sprintf("line %d", lineNumber))
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return fmt.Errorf("scan env file %s: %w", path, err)
+	}
+
+	if len(problems) > 0 {
+		return fmt.Errorf("%s: malformed KEY=VALUE on %s", path, strings.Join(problems, ", "))
+	}
+	return nil
+}
+
 func firstNonEmpty(values ...string) string {
 	for _, v := range values {
 		if trimmed := strings.TrimSpace(v); trimmed != "" {
@@ -156,6 +221,14 @@ func firstNonEmpty(values ...string) string {
 	return ""
 }
 
+func parseBool(raw string, fallback bool) bool {
+	parsed, err := strconv.ParseBool(strings.TrimSpace(raw))
+	if err != nil {
+		return fallback
+	}
+	return parsed
+}
+
 func maxInt(a, b int) int {
 	if a > b {
 		return a
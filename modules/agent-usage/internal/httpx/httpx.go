@@ -7,6 +7,7 @@ import (
 	"errors"
 	"fmt"
 	"io"
+	"log/slog"
 	"net/http"
 	"strings"
 	"time"
@@ -68,9 +69,11 @@ func DoJSON[T any](
 		req.Header.Set(key, value)
 	}
 
+	start := time.Now()
 	client := &http.Client{Timeout: timeout}
 	resp, err := client.Do(req)
 	if err != nil {
+		slog.Debug("http request failed", "method", method, "host", req.URL.Host, "duration", time.Since(start), "error", err)
 		return zero, fmt.Errorf("request failed: %w", err)
 	}
 	defer func() {
@@ -81,6 +84,7 @@ func DoJSON[T any](
 	if readErr != nil {
 		return zero, fmt.Errorf("read response: %w", readErr)
 	}
+	slog.Debug("http response", "method", method, "host", req.URL.Host, "path", req.URL.Path, "status", resp.StatusCode, "duration", time.Since(start))
 
 	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
 		return zero, &StatusError{StatusCode: resp.StatusCode, Body: strings.TrimSpace(string(payload))}
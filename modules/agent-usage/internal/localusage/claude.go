@@ -6,6 +6,7 @@ import (
 	"encoding/json"
 	"fmt"
 	"io/fs"
+	"log/slog"
 	"os"
 	"path/filepath"
 	"strings"
@@ -55,17 +56,20 @@ func ScanClaude(ctx context.Context, roots []string, sinceDay, untilDay time.Tim
 	minMTime := sinceDay.AddDate(0, 0, -1)
 
 	days := map[string]*dayBucket{}
+	scanned := 0
 
 	for _, root := range roots {
 		if err := ctx.Err(); err != nil {
 			return domain.LocalUsageSummary{}, err
 		}
 		if err := walkClaudeRoot(root, minMTime, func(path string) error {
+			scanned++
 			return scanClaudeFile(path, sinceKey, untilKey, days)
 		}); err != nil {
 			return domain.LocalUsageSummary{}, err
 		}
 	}
+	slog.Debug("scanned claude project logs", "roots", len(roots), "files", scanned)
 
 	return summarizeDays(days), nil
 }
@@ -5,6 +5,7 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"log/slog"
 	"os"
 	"path/filepath"
 	"sort"
@@ -30,6 +31,7 @@ func ScanCodex(ctx context.Context, codexHome string, sinceDay, untilDay time.Ti
 	}
 
 	days := map[string]*dayBucket{}
+	slog.Debug("scanning codex session logs", "files", len(files))
 
 	for _, filePath := range files {
 		if err := ctx.Err(); err != nil {
@@ -0,0 +1,15 @@
+package providers
+
+import "github.com/rbright/waybar-agent-usage/internal/domain"
+
+// UsageURL is the endpoint a fetch for provider talks to, used for connectivity probes.
+func UsageURL(provider domain.Provider) string {
+	switch provider {
+	case domain.ProviderCodex:
+		return codexUsageURL
+	case domain.ProviderClaude:
+		return claudeUsageURL
+	default:
+		return ""
+	}
+}
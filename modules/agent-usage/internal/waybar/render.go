@@ -21,18 +21,30 @@ type IconConfig struct {
 }
 
 func Render(metrics domain.Metrics, fetchedAt time.Time, icons IconConfig, staleError string) Output {
+	if strings.TrimSpace(staleError) != "" {
+		return render(metrics, fetchedAt, icons, "stale", fmt.Sprintf("Cached data (refresh failed): %s", strings.TrimSpace(staleError)))
+	}
+	return render(metrics, fetchedAt, icons, "", "")
+}
+
+// RenderOffline shows cached metrics while the network is down; no fetch was attempted.
+func RenderOffline(metrics domain.Metrics, fetchedAt time.Time, icons IconConfig) Output {
+	return render(metrics, fetchedAt, icons, "offline", "Offline: showing cached data")
+}
+
+func render(metrics domain.Metrics, fetchedAt time.Time, icons IconConfig, extraClass, note string) Output {
 	provider := metrics.Provider
 	icon := iconFor(provider, icons)
 	text := fmt.Sprintf("%s  %s", domain.FormatPercent(metrics.WeeklyRemaining), icon)
 
 	classes := []string{string(provider), severityClass(metrics.WeeklyRemaining)}
-	if strings.TrimSpace(staleError) != "" {
-		classes = append(classes, "stale")
+	if extraClass != "" {
+		classes = append(classes, extraClass)
 	}
 
 	return Output{
 		Text:    text,
-		Tooltip: tooltip(metrics, fetchedAt, staleError),
+		Tooltip: tooltip(metrics, fetchedAt, note),
 		Class:   strings.Join(classes, " "),
 	}
 }
@@ -83,7 +95,7 @@ func severityClass(weeklyRemaining *float64) string {
 	return "normal"
 }
 
-func tooltip(metrics domain.Metrics, fetchedAt time.Time, staleError string) string {
+func tooltip(metrics domain.Metrics, fetchedAt time.Time, note string) string {
 	title := "Claude usage"
 	if metrics.Provider == domain.ProviderCodex {
 		title = "Codex usage"
@@ -112,8 +124,8 @@ func tooltip(metrics domain.Metrics, fetchedAt time.Time, staleError string) str
 	}
 	lines = append(lines, fmt.Sprintf("Updated: %s", domain.RelativeAge(time.Now(), fetchedAt)))
 
-	if strings.TrimSpace(staleError) != "" {
-		lines = append(lines, "", fmt.Sprintf("Cached data (refresh failed): %s", strings.TrimSpace(staleError)))
+	if note != "" {
+		lines = append(lines, "", note)
 	}
 
 	return strings.Join(lines, "\n")
@@ -1,6 +1,7 @@
 package waybar
 
 import (
+	"strings"
 	"testing"
 	"time"
 
@@ -24,3 +25,19 @@ func TestRender_PlacesIconAfterPercent(t *testing.T) {
 		t.Fatalf("unexpected text: %q", out.Text)
 	}
 }
+
+func TestRenderOffline_AddsOfflineClass(t *testing.T) {
+	metrics := domain.Metrics{
+		Provider:        domain.ProviderClaude,
+		WeeklyRemaining: domain.Float64Ptr(50),
+	}
+
+	out := RenderOffline(metrics, time.Unix(0, 0).UTC(), IconConfig{})
+
+	if out.Class != "claude normal offline" {
+		t.Fatalf("unexpected class: %q", out.Class)
+	}
+	if !strings.Contains(out.Tooltip, "Offline") {
+		t.Fatalf("expected offline note in tooltip: %q", out.Tooltip)
+	}
+}
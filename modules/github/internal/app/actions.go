@@ -0,0 +1,205 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/rbright/waybar-github/internal/config"
+	"github.com/rbright/waybar-github/internal/github"
+	"github.com/rbright/waybar-github/internal/state"
+	"github.com/rbright/waybar-shared/itemref"
+)
+
+var actionFailures = map[string]string{
+	"checkout":     "Checkout failed",
+	"approve":      "Approval failed",
+	"rerun-failed": "Re-run failed",
+}
+
+// actionConfirmations are the prompts of actions that change a pull request,
+// which a menu row only starts after the pull request is confirmed.
+var actionConfirmations = map[string]struct{ summary, button string }{
+	"approve":      {summary: "Approve %s #%d?", button: "Approve"},
+	"rerun-failed": {summary: "Re-run failed jobs of %s #%d?", button: "Re-run"},
+}
+
+// runItemAction acts on a listed item. Results are reported as desktop
+// notifications, since menu actions have no terminal, and the bar is refreshed.
+func runItemAction(ctx context.Context, cfg config.Runtime, action string, ref itemref.Ref) error {
+	if cfg.Mode != config.ModePullRequests {
+		return fmt.Errorf("%s is only available in pull request mode", action)
+	}
+	item, ok, err := state.ResolveItem(cfg.ItemsPath, ref)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		// A menu drawn with more rows than are listed now.
+		return itemref.ErrStale
+	}
+
+	if action == "copy-url" {
+		return copyToClipboard(ctx, item.URL)
+	}
+	if !item.IsPullRequest() {
+		return fmt.Errorf("%s only applies to pull requests", action)
+	}
+
+	body := fmt.Sprintf("%s #%d: %s", item.Repository, item.Number, item.Title)
+	if confirmation, ok := actionConfirmations[action]; ok && ref.Key == "" {
+		// A menu row names a slot, and the bar may not have redrawn since the
+		// slot last changed hands. Ask about the pull request it resolves to now;
+		// the confirmation acts on that pull request's key, which goes stale
+		// instead of moving on to another item.
+		notify(prEvent{
+			summary: fmt.Sprintf(confirmation.summary, item.Repository, item.Number),
+			body:    item.Title,
+			url:     item.URL,
+			action:  action,
+			key:     state.ItemKey(item),
+		})
+		return nil
+	}
+
+	hostCfg, authMode := resolveAuth(ctx, cfg.ForHost(itemHost(cfg, item)))
+	var summary string
+	switch {
+	case action == "checkout":
+		var dir string
+		dir, err = checkoutPullRequest(ctx, hostCfg, authMode, item)
+		summary = "Checked out in " + dir
+	case authMode == github.AuthNone:
+		err = errNoAuth
+	case action == "approve":
+		err = github.ApprovePullRequest(ctx, hostCfg, authMode, item.Repository, item.Number)
+		summary = "Approved"
+	case action == "rerun-failed":
+		var rerun int
+		rerun, err = github.RerunFailedJobs(ctx, hostCfg, authMode, item.Repository, item.Number)
+		summary = fmt.Sprintf("Re-running failed jobs in %d workflow run(s)", rerun)
+		if err == nil && rerun == 0 {
+			summary = "No failed workflow runs"
+		}
+	default:
+		return fmt.Errorf("unsupported item action %q", action)
+	}
+	if err != nil {
+		notifyAction(actionFailures[action], body+"\n"+err.Error(), item.URL)
+		return err
+	}
+	notifyAction(summary, body, item.URL)
+
+	if _, err := buildStatus(ctx, cfg); err != nil {
+		return err
+	}
+	signalBar(cfg)
+	return nil
+}
+
+// itemHost finds the configured host an item was fetched from.
+func itemHost(cfg config.Runtime, item github.PullRequest) config.Host {
+	for _, host := range cfg.Hosts {
+		if host.Host == item.Host {
+			return host
+		}
+	}
+	for _, host := range cfg.Hosts {
+		if host.Host == cfg.Host {
+			return host
+		}
+	}
+	return cfg.Hosts[0]
+}
+
+func notifyAction(summary, body, url string) {
+	notify(prEvent{summary: summary, body: body, url: url})
+}
+
+func notify(event prEvent) {
+	if err := spawnNotification(event); err != nil {
+		slog.Warn("desktop notification failed", "summary", event.summary, "error", err)
+	}
+}
+
+func copyToClipboard(ctx context.Context, text string) error {
+	if strings.TrimSpace(text) == "" {
+		return nil
+	}
+	// wl-copy forks to serve the selection; leave its output unattached so Run
+	// returns once the parent exits instead of waiting on the child's pipes.
+	cmd := exec.CommandContext(ctx, "wl-copy")
+	cmd.Stdin = strings.NewReader(text)
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("wl-copy: %w", err)
+	}
+	return nil
+}
+
+// checkoutPullRequest checks the pull request out in its local clone, or in a
+// worktree next to it, and returns the directory used. gh is preferred; without
+// it the pull request ref is fetched from origin with git's own credentials.
+func checkoutPullRequest(ctx context.Context, cfg config.Runtime, authMode github.AuthMode, item github.PullRequest) (string, error) {
+	clone, err := cloneFor(cfg, item.Repository)
+	if err != nil {
+		return "", err
+	}
+
+	dir := clone
+	if cfg.CheckoutWorktree {
+		dir = fmt.Sprintf("%s-pr-%d", clone, item.Number)
+		if _, err := os.Stat(dir); errors.Is(err, os.ErrNotExist) {
+			if err := runIn(ctx, clone, nil, "git", "worktree", "add", "--detach", dir); err != nil {
+				return "", err
+			}
+		}
+	}
+
+	if _, err := exec.LookPath("gh"); err == nil && authMode != github.AuthNone {
+		var env []string
+		if authMode == github.AuthToken {
+			env = []string{"GH_TOKEN=" + cfg.Token, "GH_HOST=" + cfg.Host}
+		}
+		return dir, runIn(ctx, dir, env, "gh", "pr", "checkout", strconv.Itoa(item.Number))
+	}
+
+	branch := fmt.Sprintf("pr-%d", item.Number)
+	if err := runIn(ctx, dir, nil, "git", "fetch", "origin", fmt.Sprintf("pull/%d/head:%s", item.Number, branch)); err != nil {
+		return "", err
+	}
+	return dir, runIn(ctx, dir, nil, "git", "switch", branch)
+}
+
+// cloneFor maps a repository to its local clone: WAYBAR_GITHUB_CLONES first,
+// then a directory named after the repository under WAYBAR_GITHUB_CLONE_ROOT.
+func cloneFor(cfg config.Runtime, repository string) (string, error) {
+	if clone, ok := cfg.Clones[strings.ToLower(repository)]; ok {
+		return clone, nil
+	}
+	if cfg.CloneRoot != "" {
+		_, name, _ := strings.Cut(repository, "/")
+		clone := filepath.Join(cfg.CloneRoot, name)
+		if info, err := os.Stat(clone); err == nil && info.IsDir() {
+			return clone, nil
+		}
+	}
+	return "", fmt.Errorf("no local clone of %s; set WAYBAR_GITHUB_CLONES or WAYBAR_GITHUB_CLONE_ROOT", repository)
+}
+
+func runIn(ctx context.Context, dir string, env []string, name string, args ...string) error {
+	cmd := exec.CommandContext(ctx, name, args...)
+	cmd.Dir = dir
+	if len(env) > 0 {
+		cmd.Env = append(os.Environ(), env...)
+	}
+	if output, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("%s %s: %s", name, strings.Join(args, " "), fallbackString(firstLine(string(output)), err.Error()))
+	}
+	return nil
+}
@@ -2,20 +2,28 @@ package app
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"io"
-	"os/exec"
+	"log/slog"
+	"slices"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/rbright/waybar-github/internal/config"
 	"github.com/rbright/waybar-github/internal/github"
 	"github.com/rbright/waybar-github/internal/state"
 	"github.com/rbright/waybar-github/internal/waybar"
+	"github.com/rbright/waybar-shared/barsignal"
+	"github.com/rbright/waybar-shared/itemref"
+	"github.com/rbright/waybar-shared/logging"
+	"github.com/rbright/waybar-shared/network"
+	"github.com/rbright/waybar-shared/opener"
 )
 
 func Run(ctx context.Context, args []string, cfg config.Runtime, stdout io.Writer) error {
-	cmd, index, err := parseArgs(args)
+	cmd, ref, lines, err := parseArgs(args)
 	if err != nil {
 		return err
 	}
@@ -28,39 +36,112 @@ func Run(ctx context.Context, args []string, cfg config.Runtime, stdout io.Write
 		}
 		return writeOutput(stdout, out)
 	case "refresh":
-		_, err := buildStatus(ctx, cfg)
-		return err
+		if _, err := buildStatus(ctx, cfg); err != nil {
+			return err
+		}
+		signalBar(cfg)
+		return nil
 	case "open-dashboard":
-		return openURL(ctx, fmt.Sprintf("https://%s/pulls", cfg.Host))
+		switch cfg.Mode {
+		case config.ModeNotifications:
+			return openURL(ctx, cfg, fmt.Sprintf("https://%s/notifications", cfg.Host))
+		case config.ModeActions:
+			return openURL(ctx, cfg, actionsDashboardURL(cfg))
+		}
+		return openURL(ctx, cfg, fmt.Sprintf("https://%s/pulls", cfg.Host))
 	case "open-item":
-		return openItem(ctx, cfg, index)
+		switch cfg.Mode {
+		case config.ModeNotifications:
+			return openNotification(ctx, cfg, ref)
+		case config.ModeActions:
+			return openWorkflowRun(ctx, cfg, ref)
+		}
+		return openItem(ctx, cfg, ref)
+	case "copy-url", "checkout", "approve", "rerun-failed":
+		return runItemAction(ctx, cfg, cmd, ref)
+	case "mark-all-read":
+		return markAllRead(ctx, cfg)
+	case "logs":
+		return logging.Tail(cfg.LogPath, lines, stdout)
+	case "doctor":
+		return runDoctor(ctx, cfg, stdout)
+	case "store-token":
+		return storeToken(ctx, cfg, stdout)
+	case desktopNotifyCommand:
+		event := prEvent{summary: args[1], body: args[2], url: args[3], key: ref.Key}
+		if len(args) == 6 {
+			event.action = args[4]
+		}
+		return runDesktopNotify(ctx, cfg, event)
 	default:
 		return fmt.Errorf("unsupported command %q", cmd)
 	}
 }
 
-func parseArgs(args []string) (command string, index int, err error) {
+// parseArgs returns the command, the item reference of item commands and the
+// line count of logs.
+func parseArgs(args []string) (command string, ref itemref.Ref, lines int, err error) {
 	if len(args) == 0 {
-		return "status", 0, nil
+		return "status", itemref.Ref{}, 0, nil
 	}
 
 	switch strings.TrimSpace(args[0]) {
-	case "status", "refresh", "open-dashboard":
+	case "status", "refresh", "open-dashboard", "mark-all-read", "doctor", "store-token":
 		if len(args) > 1 {
-			return "", 0, fmt.Errorf("unexpected argument %q", args[1])
+			return "", itemref.Ref{}, 0, fmt.Errorf("unexpected argument %q", args[1])
 		}
-		return strings.TrimSpace(args[0]), 0, nil
+		return strings.TrimSpace(args[0]), itemref.Ref{}, 0, nil
 	case "open-item":
 		if len(args) != 2 {
-			return "", 0, fmt.Errorf("usage: waybar-github open-item <index>")
+			return "", itemref.Ref{}, 0, fmt.Errorf("usage: waybar-github open-item <N|id:KEY>")
+		}
+		ref, refErr := itemref.Parse(args[1])
+		if refErr != nil {
+			return "", itemref.Ref{}, 0, refErr
+		}
+		return "open-item", ref, 0, nil
+	case "copy-url", "checkout", "approve", "rerun-failed":
+		action := strings.TrimSpace(args[0])
+		if len(args) != 2 {
+			return "", itemref.Ref{}, 0, fmt.Errorf("usage: waybar-github %s <N|id:KEY>", action)
 		}
-		n, convErr := strconv.Atoi(strings.TrimSpace(args[1]))
-		if convErr != nil || n < 1 {
-			return "", 0, fmt.Errorf("invalid item index %q", args[1])
+		ref, refErr := itemref.Parse(args[1])
+		if refErr != nil {
+			return "", itemref.Ref{}, 0, refErr
 		}
-		return "open-item", n, nil
+		return action, ref, 0, nil
+	case desktopNotifyCommand:
+		// Internal: spawned detached with summary, body and URL, and for a
+		// confirmation the item action and the item's id:KEY reference.
+		switch len(args) {
+		case 4:
+			return desktopNotifyCommand, itemref.Ref{}, 0, nil
+		case 6:
+			if _, ok := actionConfirmations[args[4]]; !ok {
+				return "", itemref.Ref{}, 0, fmt.Errorf("unsupported item action %q", args[4])
+			}
+			ref, refErr := itemref.Parse(args[5])
+			if refErr != nil || ref.Key == "" {
+				return "", itemref.Ref{}, 0, fmt.Errorf("invalid item key %q", args[5])
+			}
+			return desktopNotifyCommand, ref, 0, nil
+		}
+		return "", itemref.Ref{}, 0, fmt.Errorf("usage: waybar-github %s <summary> <body> <url> [<action> <id:KEY>]", desktopNotifyCommand)
+	case "logs":
+		if len(args) > 2 {
+			return "", itemref.Ref{}, 0, fmt.Errorf("usage: waybar-github logs [lines]")
+		}
+		lines = 50
+		if len(args) == 2 {
+			n, convErr := strconv.Atoi(strings.TrimSpace(args[1]))
+			if convErr != nil || n < 1 {
+				return "", itemref.Ref{}, 0, fmt.Errorf("invalid line count %q", args[1])
+			}
+			lines = n
+		}
+		return "logs", itemref.Ref{}, lines, nil
 	default:
-		return "", 0, fmt.Errorf("usage: waybar-github <status|refresh|open-dashboard|open-item N>")
+		return "", itemref.Ref{}, 0, fmt.Errorf("usage: waybar-github [pull-requests|notifications|actions] <status|refresh|open-dashboard|open-item N|copy-url N|checkout N|approve N|rerun-failed N|mark-all-read|logs [N]|doctor|store-token>")
 	}
 }
 
@@ -68,11 +149,106 @@ func buildStatus(ctx context.Context, cfg config.Runtime) (waybar.Output, error)
 	if err := state.EnsureDirs(cfg.StateDir, cfg.MenuDir); err != nil {
 		return waybar.Output{}, err
 	}
+	switch cfg.Mode {
+	case config.ModeNotifications:
+		return buildNotificationsStatus(ctx, cfg)
+	case config.ModeActions:
+		return buildActionsStatus(ctx, cfg)
+	}
 
-	authMode := github.DetectAuth(ctx, cfg)
-	if authMode == github.AuthNone {
-		statusLine := "Run 'gh auth login' or set GITHUB_TOKEN"
-		if err := state.SaveItems(cfg.ItemsPath, []github.PullRequest{}); err != nil {
+	// Check before DetectAuth: its `gh auth status` fallback needs the network.
+	if cfg.NetworkCheck && !network.Online(ctx, cfg.APIURL) {
+		slog.Info("offline; skipping github fetch")
+		meta, err := state.LoadMeta(cfg.MetaPath)
+		if err != nil {
+			return waybar.Output{}, err
+		}
+		return renderCached(cfg, meta, "offline", "Offline")
+	}
+
+	now := time.Now().UTC()
+	fetches := fetchHosts(ctx, cfg, now)
+	succeeded := slices.ContainsFunc(fetches, func(fetch hostFetch) bool { return fetch.err == nil })
+	if !succeeded {
+		return renderFailure(cfg, fetches[0])
+	}
+
+	previous, err := state.LoadMeta(cfg.MetaPath)
+	if err != nil {
+		return waybar.Output{}, err
+	}
+	previousItems, _, err := state.LoadItems(cfg.ItemsPath)
+	if err != nil {
+		return waybar.Output{}, err
+	}
+	result, meta, degraded := mergeFetches(cfg, fetches, previous, previousItems, now)
+	if cfg.SortByReviewWait {
+		sortByReviewWait(result.Items)
+	}
+
+	if cfg.DesktopNotifications {
+		notifyChanges(ctx, cfg, fetches, previousItems)
+	}
+
+	slots, err := state.SaveItems(cfg.ItemsPath, result.Items)
+	if err != nil {
+		return waybar.Output{}, err
+	}
+
+	if err := state.SaveMeta(cfg.MetaPath, meta); err != nil {
+		return waybar.Output{}, err
+	}
+
+	statusLine := "No matching pull requests"
+	if result.Count > 0 {
+		statusLine = "Open pull requests"
+	}
+
+	if err := state.WriteMenu(cfg.MenuPath, state.MenuData{
+		StatusLine:         statusLine,
+		Items:              result.Items,
+		Searches:           result.Searches,
+		ItemActions:        cfg.ItemActions,
+		GroupByRepository:  cfg.GroupByRepository,
+		RepositorySubmenus: cfg.RepositorySubmenus,
+		SeparateDrafts:     cfg.Filter.Drafts == config.DraftsSeparate,
+		Now:                now,
+		Slots:              slots,
+	}); err != nil {
+		return waybar.Output{}, err
+	}
+
+	tooltip := buildTooltip(result.Count, result.Searches, result.Items) + staleReviewNote(cfg, result.Items, now) + quotaSummary(cfg) + "\nClick to open dropdown"
+
+	className := "clear"
+	if result.Count > 0 {
+		className = "normal"
+	}
+	if degraded {
+		className += " degraded"
+	}
+
+	return waybar.Output{
+		Text:    barText(result.Count, result.Searches),
+		Tooltip: tooltip,
+		Class:   className + itemClasses(cfg, result.Items, now),
+	}, nil
+}
+
+// renderFailure handles a refresh in which no host succeeded; with several
+// hosts the first one's failure decides.
+func renderFailure(cfg config.Runtime, fetch hostFetch) (waybar.Output, error) {
+	if !fetch.limitedUntil.IsZero() {
+		meta, err := state.LoadMeta(cfg.MetaPath)
+		if err != nil {
+			return waybar.Output{}, err
+		}
+		return renderCached(cfg, meta, "rate-limited", rateLimitedReason(fetch.limitedUntil))
+	}
+
+	if errors.Is(fetch.err, errNoAuth) {
+		statusLine := "Run 'gh auth login', set GITHUB_TOKEN or run waybar-github store-token"
+		if _, err := state.SaveItems(cfg.ItemsPath, []github.PullRequest{}); err != nil {
 			return waybar.Output{}, err
 		}
 		if err := state.WriteMenu(cfg.MenuPath, state.MenuData{StatusLine: statusLine}); err != nil {
@@ -85,83 +261,282 @@ func buildStatus(ctx context.Context, cfg config.Runtime) (waybar.Output, error)
 		}, nil
 	}
 
-	result, err := github.FetchPullRequests(ctx, cfg, authMode)
-	if err != nil {
-		statusLine := "GitHub API request failed"
-		if saveErr := state.SaveItems(cfg.ItemsPath, []github.PullRequest{}); saveErr != nil {
-			return waybar.Output{}, saveErr
+	// Keep the last good items and menu through transient failures.
+	if meta, metaErr := state.LoadMeta(cfg.MetaPath); metaErr == nil && network.WithinStaleAge(meta.FetchedAt, cfg.StaleAfter) {
+		return renderCached(cfg, meta, "stale", "Refresh failed: "+fetch.err.Error())
+	}
+	statusLine := "GitHub API request failed"
+	if _, saveErr := state.SaveItems(cfg.ItemsPath, []github.PullRequest{}); saveErr != nil {
+		return waybar.Output{}, saveErr
+	}
+	if metaErr := state.SaveMeta(cfg.MetaPath, state.Meta{}); metaErr != nil {
+		return waybar.Output{}, metaErr
+	}
+	if menuErr := state.WriteMenu(cfg.MenuPath, state.MenuData{StatusLine: statusLine}); menuErr != nil {
+		return waybar.Output{}, menuErr
+	}
+	return waybar.Output{
+		Text:    "!",
+		Tooltip: fmt.Sprintf("GitHub pull requests: %s", fetch.err.Error()),
+		Class:   "error",
+	}, nil
+}
+
+// itemClasses flags red or running checks and mergeable approvals on the user's
+// own pull requests, and review requests waiting past the SLA.
+func itemClasses(cfg config.Runtime, items []github.PullRequest, now time.Time) string {
+	failing, pending, ready := false, false, false
+	for _, item := range items {
+		if !item.Authored {
+			continue
 		}
-		if menuErr := state.WriteMenu(cfg.MenuPath, state.MenuData{StatusLine: statusLine}); menuErr != nil {
-			return waybar.Output{}, menuErr
+		failing = failing || item.CIFailing()
+		pending = pending || item.CIPending()
+		ready = ready || item.Ready()
+	}
+
+	var classes string
+	switch {
+	case failing:
+		classes += " ci-failing"
+	case pending:
+		classes += " ci-pending"
+	}
+	if ready {
+		classes += " ready"
+	}
+	if slices.ContainsFunc(items, func(item github.PullRequest) bool { return reviewOverdue(cfg, item, now) }) {
+		classes += " stale-reviews"
+	}
+	return classes
+}
+
+// reviewOverdue reports whether the user's review request has waited longer
+// than the SLA, counting work-week time only. Work days are local days, whatever
+// zone now is in.
+func reviewOverdue(cfg config.Runtime, item github.PullRequest, now time.Time) bool {
+	if cfg.ReviewSLA <= 0 || !item.ReviewRequested || item.ReviewRequestedAt.IsZero() {
+		return false
+	}
+	return cfg.WorkWeek.Elapsed(item.ReviewRequestedAt, now.Local()) > cfg.ReviewSLA
+}
+
+// staleReviewNote counts overdue review requests for the tooltip.
+func staleReviewNote(cfg config.Runtime, items []github.PullRequest, now time.Time) string {
+	overdue := make(map[string]bool)
+	for _, item := range items {
+		if reviewOverdue(cfg, item, now) {
+			overdue[item.URL] = true
 		}
-		return waybar.Output{
-			Text:    "!",
-			Tooltip: fmt.Sprintf("GitHub pull requests: %s", err.Error()),
-			Class:   "error",
-		}, nil
 	}
+	switch len(overdue) {
+	case 0:
+		return ""
+	case 1:
+		return "\n1 review request past SLA"
+	default:
+		return fmt.Sprintf("\n%d review requests past SLA", len(overdue))
+	}
+}
 
-	if err := state.SaveItems(cfg.ItemsPath, result.Items); err != nil {
-		return waybar.Output{}, err
+// sortByReviewWait moves items with pending review requests to the front, the
+// longest-waiting first, and keeps search order for the rest.
+func sortByReviewWait(items []github.PullRequest) {
+	slices.SortStableFunc(items, func(a, b github.PullRequest) int {
+		if a.ReviewRequestedAt.IsZero() != b.ReviewRequestedAt.IsZero() {
+			if a.ReviewRequestedAt.IsZero() {
+				return 1
+			}
+			return -1
+		}
+		return a.ReviewRequestedAt.Compare(b.ReviewRequestedAt)
+	})
+}
+
+// itemDetail names the reviewers behind a pull request's review state, or lists
+// an issue's or discussion's labels.
+func itemDetail(item github.PullRequest) string {
+	if !item.IsPullRequest() {
+		if labels := item.LabelSummary(); labels != "" {
+			return " [" + labels + "]"
+		}
+		return ""
 	}
 
-	statusLine := "No matching pull requests"
-	if result.Count > 0 {
-		statusLine = "Open pull requests"
+	summary := item.ReviewSummary()
+	if summary == "" {
+		return ""
 	}
 
-	if err := state.WriteMenu(cfg.MenuPath, state.MenuData{StatusLine: statusLine, Items: result.Items}); err != nil {
-		return waybar.Output{}, err
+	var names []string
+	switch item.ReviewDecision {
+	case github.ReviewApproved, github.ReviewChangesRequested:
+		for _, review := range item.Reviews {
+			if review.State == item.ReviewDecision && review.Author != "" {
+				names = append(names, review.Author)
+			}
+		}
+	default:
+		names = item.RequestedReviewers
+	}
+	if len(names) == 0 {
+		return " — " + summary
 	}
+	return fmt.Sprintf(" — %s (%s)", summary, strings.Join(names, ", "))
+}
 
-	tooltip := fmt.Sprintf("GitHub pull requests: %d", result.Count)
-	if len(result.Items) > 0 {
-		lines := make([]string, 0, len(result.Items))
-		for _, item := range result.Items {
-			lines = append(lines, fmt.Sprintf("%s #%d: %s", item.Repository, item.Number, item.Title))
+// resolveAuth prefers gh, then a configured token, then the keyring.
+func resolveAuth(ctx context.Context, cfg config.Runtime) (config.Runtime, github.AuthMode) {
+	authMode := github.DetectAuth(ctx, cfg)
+	if authMode == github.AuthNone {
+		// gh is unavailable and no token is configured; try the keyring last.
+		cfg = withKeyringToken(ctx, cfg)
+		if cfg.Token != "" {
+			authMode = github.AuthToken
 		}
-		tooltip += "\n" + strings.Join(lines, "\n")
 	}
-	tooltip += "\nClick to open dropdown"
+	return cfg, authMode
+}
 
-	className := "clear"
-	if result.Count > 0 {
-		className = "normal"
+// barText shows one count per named search, e.g. "3·5", or the total for a single search.
+func barText(count int, searches []github.SearchCount) string {
+	if len(searches) < 2 {
+		return github.CountLabel(count, searches...)
+	}
+	counts := make([]string, 0, len(searches))
+	for _, search := range searches {
+		counts = append(counts, github.CountLabel(search.Count, search))
+	}
+	return strings.Join(counts, "·")
+}
+
+func buildTooltip(count int, searches []github.SearchCount, items []github.PullRequest) string {
+	if len(searches) < 2 {
+		tooltip := "GitHub pull requests: " + github.CountLabel(count, searches...) + hiddenNote(github.TotalHidden(searches), github.TotalCapped(searches))
+		if len(items) > 0 {
+			lines := make([]string, 0, len(items))
+			for _, item := range items {
+				lines = append(lines, fmt.Sprintf("%s #%d: %s", item.Repository, item.Number, item.Title)+itemDetail(item))
+			}
+			tooltip += "\n" + strings.Join(lines, "\n")
+		}
+		return tooltip
+	}
+
+	withHost := github.SpansHosts(searches)
+	lines := []string{"GitHub pull requests: " + github.CountLabel(count, searches...)}
+	for _, search := range searches {
+		lines = append(lines, search.Label(withHost)+": "+github.CountLabel(search.Count, search)+hiddenNote(search.Hidden, search.Capped))
+		if search.Error != "" {
+			lines = append(lines, "  ⚠ "+search.Error)
+		}
+		for _, item := range items {
+			if search.Matches(item) {
+				lines = append(lines, fmt.Sprintf("  %s #%d: %s", item.Repository, item.Number, item.Title)+itemDetail(item))
+			}
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+// hiddenNote tells apart results hidden by filters from those over the
+// per-repository cap, e.g. ", 4 hidden, 2 capped".
+func hiddenNote(hidden, capped int) string {
+	note := ""
+	if hidden > 0 {
+		note += fmt.Sprintf(", %d hidden", hidden)
+	}
+	if capped > 0 {
+		note += fmt.Sprintf(", %d capped", capped)
+	}
+	return note
+}
+
+// renderCached shows the last successful fetch without touching saved items or
+// the menu, so the dropdown keeps working while offline or after a failed refresh.
+func renderCached(cfg config.Runtime, meta state.Meta, className, reason string) (waybar.Output, error) {
+	if meta.FetchedAt.IsZero() {
+		return waybar.Output{
+			Text:    "?",
+			Tooltip: fmt.Sprintf("GitHub pull requests: %s; nothing cached yet", reason),
+			Class:   className,
+		}, nil
+	}
+
+	items, _, err := state.LoadItems(cfg.ItemsPath)
+	if err != nil {
+		return waybar.Output{}, err
 	}
 
+	tooltip := buildTooltip(meta.Count, meta.Searches, items) + staleReviewNote(cfg, items, time.Now()) + quotaSummary(cfg)
+	tooltip += "\n\n" + network.CachedNote(reason, meta.FetchedAt)
 	return waybar.Output{
-		Text:    strconv.Itoa(result.Count),
+		Text:    barText(meta.Count, meta.Searches),
 		Tooltip: tooltip,
-		Class:   className,
+		Class:   className + itemClasses(cfg, items, time.Now()),
 	}, nil
 }
 
-func openItem(ctx context.Context, cfg config.Runtime, index int) error {
-	items, err := state.LoadItems(cfg.ItemsPath)
+func rateLimitedReason(until time.Time) string {
+	return "Rate limited; next refresh after " + until.Local().Format("15:04")
+}
+
+// quotaSummary shows each host's saved GraphQL budget, one "API quota" line per host.
+func quotaSummary(cfg config.Runtime) string {
+	var summary string
+	for _, host := range cfg.Hosts {
+		// Best-effort: the quota line is informational.
+		limits, _ := state.LoadRateLimit(host.RateLimitPath)
+		quota := quotaLine(limits)
+		if quota == "" {
+			continue
+		}
+		if len(cfg.Hosts) > 1 {
+			summary += fmt.Sprintf("\nAPI quota (%s): %s", host.Host, quota)
+		} else {
+			summary += "\nAPI quota: " + quota
+		}
+	}
+	return summary
+}
+
+// quotaLine formats a budget, e.g. "4890/5000, resets 14:05", or "" when unknown.
+func quotaLine(limits state.RateLimit) string {
+	if limits.Limit == 0 {
+		return ""
+	}
+	line := fmt.Sprintf("%d/%d", limits.Remaining, limits.Limit)
+	if !limits.ResetAt.IsZero() {
+		line += ", resets " + limits.ResetAt.Local().Format("15:04")
+	}
+	return line
+}
+
+func openItem(ctx context.Context, cfg config.Runtime, ref itemref.Ref) error {
+	item, ok, err := state.ResolveItem(cfg.ItemsPath, ref)
 	if err != nil {
 		return err
 	}
-	if len(items) == 0 || index > len(items) {
-		return nil
+	if !ok {
+		return itemref.ErrStale
 	}
 
-	url := strings.TrimSpace(items[index-1].URL)
+	url := strings.TrimSpace(item.URL)
 	if url == "" {
 		return nil
 	}
-	return openURL(ctx, url)
+	return openURL(ctx, cfg, url)
 }
 
-func openURL(ctx context.Context, url string) error {
-	if _, err := exec.LookPath("xdg-open"); err != nil {
-		return fmt.Errorf("xdg-open not found")
-	}
+func openURL(ctx context.Context, cfg config.Runtime, url string) error {
+	return opener.Open(ctx, cfg.Opener, url)
+}
 
-	cmd := exec.CommandContext(ctx, "xdg-open", url)
-	if err := cmd.Start(); err != nil {
-		return fmt.Errorf("open url: %w", err)
+func signalBar(cfg config.Runtime) {
+	if err := barsignal.Send(cfg.Signal); err != nil {
+		// The next interval catches up, so a failed signal only delays the bar.
+		slog.Debug("signal waybar failed", "signal", cfg.Signal, "error", err)
 	}
-	return nil
 }
 
 func writeOutput(w io.Writer, output waybar.Output) error {
@@ -177,3 +552,10 @@ func writeOutput(w io.Writer, output waybar.Output) error {
 	}
 	return nil
 }
+
+func fallbackString(value, fallback string) string {
+	if strings.TrimSpace(value) == "" {
+		return fallback
+	}
+	return strings.TrimSpace(value)
+}
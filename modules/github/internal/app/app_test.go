@@ -0,0 +1,1215 @@
+package app
+
+import (
+	"context"
+	"encoding/xml"
+	"errors"
+	"io"
+	"net/http"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/rbright/waybar-github/internal/config"
+	"github.com/rbright/waybar-github/internal/github"
+	"github.com/rbright/waybar-github/internal/githubtest"
+	"github.com/rbright/waybar-github/internal/state"
+	"github.com/rbright/waybar-shared/itemref"
+)
+
+// loadTestConfig loads the pull request config against server, with state in a
+// temporary directory and a fake gh that is signed out unless a test says so.
+func loadTestConfig(t *testing.T, server *githubtest.Server, env map[string]string) (config.Runtime, *githubtest.GH) {
+	t.Helper()
+	return loadModeConfig(t, config.ModePullRequests, server, env)
+}
+
+// loadModeConfig is loadTestConfig for any mode.
+func loadModeConfig(t *testing.T, mode string, server *githubtest.Server, env map[string]string) (config.Runtime, *githubtest.GH) {
+	t.Helper()
+	gh := githubtest.InstallGH(t)
+	// gh's token is cached per process; start and end each test without one.
+	github.ResetGHTokens()
+	t.Cleanup(github.ResetGHTokens)
+	dir := t.TempDir()
+	defaults := map[string]string{
+		"HOME":                                dir,
+		"XDG_CONFIG_HOME":                     filepath.Join(dir, "config"),
+		"XDG_STATE_HOME":                      filepath.Join(dir, "state"),
+		"WAYBAR_GITHUB_CONFIG_FILE":           filepath.Join(dir, "missing.env"),
+		"WAYBAR_GITHUB_GRAPHQL_URL":           server.URL,
+		"WAYBAR_GITHUB_API_URL":               strings.TrimSuffix(server.URL, "/graphql"),
+		"WAYBAR_GITHUB_TOKEN":                 "test-token",
+		"WAYBAR_GITHUB_SECRET_ATTRIBUTES":     "",
+		"WAYBAR_GITHUB_NETWORK_CHECK":         "false",
+		"WAYBAR_GITHUB_DESKTOP_NOTIFICATIONS": "false",
+		"WAYBAR_GITHUB_HOSTS":                 "",
+		"WAYBAR_GITHUB_SEARCHES":              "",
+		"WAYBAR_GITHUB_INCLUDE_REPOS":         "",
+		"WAYBAR_GITHUB_EXCLUDE_REPOS":         "",
+	}
+	for name, value := range env {
+		defaults[name] = value
+	}
+	for name, value := range defaults {
+		t.Setenv(name, value)
+	}
+
+	cfg, err := config.Load(mode)
+	if err != nil {
+		t.Fatalf("load config: %v", err)
+	}
+	return cfg, gh
+}
+
+// menuRow is one GtkMenuItem of the dropdown.
+type menuRow struct {
+	ID    string
+	Label string
+	// Menu is the id of the GtkMenu holding the row, "menu" at the top level.
+	Menu string
+}
+
+// readMenu parses the menu file as XML, so markup that would break Waybar's
+// dropdown fails the test.
+func readMenu(t *testing.T, path string) []menuRow {
+	t.Helper()
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("read menu: %v", err)
+	}
+
+	decoder := xml.NewDecoder(strings.NewReader(string(raw)))
+	var rows []menuRow
+	// objects holds the id of each open object, with "" for anything but a GtkMenu.
+	var objects []string
+	inLabel := false
+	for {
+		token, err := decoder.Token()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			t.Fatalf("menu is not valid XML: %v\n%s", err, raw)
+		}
+		switch token := token.(type) {
+		case xml.StartElement:
+			attr := func(name string) string {
+				for _, a := range token.Attr {
+					if a.Name.Local == name {
+						return a.Value
+					}
+				}
+				return ""
+			}
+			if token.Name.Local == "object" && strings.HasSuffix(attr("class"), "MenuItem") {
+				rows = append(rows, menuRow{ID: attr("id"), Menu: enclosingMenu(objects)})
+			}
+			switch {
+			case token.Name.Local == "object" && attr("class") == "GtkMenu":
+				objects = append(objects, attr("id"))
+			case token.Name.Local == "object":
+				objects = append(objects, "")
+			case token.Name.Local == "property" && attr("name") == "label":
+				inLabel = true
+			}
+		case xml.CharData:
+			if inLabel && len(rows) > 0 {
+				rows[len(rows)-1].Label += string(token)
+			}
+		case xml.EndElement:
+			inLabel = false
+			if token.Name.Local == "object" {
+				objects = objects[:len(objects)-1]
+			}
+		}
+	}
+	return rows
+}
+
+func enclosingMenu(objects []string) string {
+	for i := len(objects) - 1; i >= 0; i-- {
+		if objects[i] != "" {
+			return objects[i]
+		}
+	}
+	return ""
+}
+
+func rowLabels(rows []menuRow) []string {
+	labels := make([]string, 0, len(rows))
+	for _, row := range rows {
+		labels = append(labels, row.Label)
+	}
+	return labels
+}
+
+func hasRow(rows []menuRow, id, labelPrefix string) bool {
+	return slices.ContainsFunc(rows, func(row menuRow) bool {
+		return row.ID == id && strings.HasPrefix(row.Label, labelPrefix)
+	})
+}
+
+func TestBuildStatusRendersRecordedSearch(t *testing.T) {
+	server := githubtest.NewServer(t, githubtest.Reply(t, http.StatusOK, "search_pull_requests"))
+	cfg, _ := loadTestConfig(t, server, nil)
+
+	output, err := buildStatus(context.Background(), cfg)
+	if err != nil {
+		t.Fatalf("build status: %v", err)
+	}
+	if output.Text != "3" {
+		t.Fatalf("expected text 3, got %q", output.Text)
+	}
+	if output.Class != "normal ci-failing ready" {
+		t.Fatalf("unexpected class %q", output.Class)
+	}
+	for _, want := range []string{
+		"GitHub pull requests: 3",
+		"acme/app #42: Fix login redirect — ready to merge",
+		`acme/api #7: Escape <menu> & "labels" — awaiting 2 reviewers (octocat, platform)`,
+		"API quota: 4987/5000",
+	} {
+		if !strings.Contains(output.Tooltip, want) {
+			t.Fatalf("expected tooltip to contain %q, got:\n%s", want, output.Tooltip)
+		}
+	}
+
+	items, slots, err := state.LoadItems(cfg.ItemsPath)
+	if err != nil {
+		t.Fatalf("load items: %v", err)
+	}
+	if len(items) != 3 {
+		t.Fatalf("expected 3 saved items, got %d", len(items))
+	}
+
+	rows := readMenu(t, cfg.MenuPath)
+	for _, want := range []menuRow{
+		{ID: "open_dashboard", Label: "Open GitHub Pull Requests"},
+		{ID: "item_1", Label: "✗ #42 Fix login redirect · ready to merge"},
+		{ID: "item_2", Label: `✓ #7 Escape <menu> & "labels" · awaiting 2 reviewers`},
+		{ID: "item_3", Label: "● #43 WIP: collapse whitespace (draft)"},
+		{ID: "open_1", Label: "Open in browser"},
+		{ID: "approve_2", Label: "Approve"},
+		{ID: "rerun_failed_1", Label: "Re-run failed jobs"},
+		{ID: "refresh", Label: "Refresh"},
+	} {
+		if !hasRow(rows, want.ID, want.Label) {
+			t.Fatalf("expected row %s %q, got %q", want.ID, want.Label, rowLabels(rows))
+		}
+	}
+	if hasRow(rows, "approve_1", "") {
+		t.Fatal("expected no approve action on the user's own pull request")
+	}
+
+	// Every row id has to resolve to the item it shows, through the item's slot.
+	for n, want := range items {
+		got, ok, err := state.ResolveItem(cfg.ItemsPath, itemref.Ref{Slot: slots[n]})
+		if err != nil || !ok || got.URL != want.URL {
+			t.Fatalf("expected slot %d to open %s, got %s (%v, %v)", slots[n], want.URL, got.URL, ok, err)
+		}
+	}
+}
+
+func TestBuildStatusRepositorySubmenus(t *testing.T) {
+	server := githubtest.NewServer(t, githubtest.Reply(t, http.StatusOK, "search_pull_requests"))
+	cfg, _ := loadTestConfig(t, server, map[string]string{"WAYBAR_GITHUB_REPOSITORY_SUBMENUS": "true"})
+
+	if _, err := buildStatus(context.Background(), cfg); err != nil {
+		t.Fatalf("build status: %v", err)
+	}
+
+	rows := readMenu(t, cfg.MenuPath)
+	for _, want := range []menuRow{
+		{ID: "repository_1", Label: "acme/app (2)", Menu: "menu"},
+		{ID: "item_1", Label: "✗ #42 Fix login redirect", Menu: "repository_1_menu"},
+		{ID: "item_3", Label: "● #43 WIP: collapse whitespace", Menu: "repository_1_menu"},
+		{ID: "repository_2", Label: "acme/api (1)", Menu: "menu"},
+		{ID: "item_2", Label: `✓ #7 Escape <menu> & "labels"`, Menu: "repository_2_menu"},
+		{ID: "open_2", Label: "Open in browser", Menu: "item_2_menu"},
+	} {
+		if !slices.ContainsFunc(rows, func(row menuRow) bool {
+			return row.ID == want.ID && row.Menu == want.Menu && strings.HasPrefix(row.Label, want.Label)
+		}) {
+			t.Fatalf("expected row %s %q in %s, got %+v", want.ID, want.Label, want.Menu, rows)
+		}
+	}
+	if slices.ContainsFunc(rows, func(row menuRow) bool { return row.Label == "acme/app" }) {
+		t.Fatalf("expected no repository sections alongside submenus, got %q", rowLabels(rows))
+	}
+}
+
+func TestBuildStatusSearchSections(t *testing.T) {
+	server := githubtest.NewServer(t, githubtest.Reply(t, http.StatusOK, "search_sections"))
+	cfg, _ := loadTestConfig(t, server, map[string]string{
+		"WAYBAR_GITHUB_SEARCHES": "Review requested=is:open is:pr review-requested:@me;Involved=is:open is:pr involves:@me",
+	})
+
+	output, err := buildStatus(context.Background(), cfg)
+	if err != nil {
+		t.Fatalf("build status: %v", err)
+	}
+	if output.Text != "1·3" {
+		t.Fatalf("expected per-section counts, got %q", output.Text)
+	}
+	for _, want := range []string{"GitHub pull requests: 3\n", "Review requested: 1\n  acme/api #7:", "Involved: 3\n  acme/app #42:"} {
+		if !strings.Contains(output.Tooltip, want) {
+			t.Fatalf("expected tooltip to contain %q, got %q", want, output.Tooltip)
+		}
+	}
+
+	meta, err := state.LoadMeta(cfg.MetaPath)
+	if err != nil || meta.Count != 3 {
+		t.Fatalf("expected 3 distinct pull requests saved, got %d (%v)", meta.Count, err)
+	}
+
+	labels := rowLabels(readMenu(t, cfg.MenuPath))
+	review := slices.Index(labels, "Review requested (1)")
+	involved := slices.Index(labels, "Involved (3)")
+	if review < 0 || involved < review {
+		t.Fatalf("expected a section per search, got %q", labels)
+	}
+	var listed []string
+	for _, label := range labels[review+1 : involved] {
+		if strings.Contains(label, "#") {
+			listed = append(listed, label)
+		}
+	}
+	if len(listed) != 1 || !strings.Contains(listed[0], "#7 ") {
+		t.Fatalf("expected only #7 under review requested, got %q", listed)
+	}
+}
+
+// Waybar maps open_N to "open-item N" statically, so a refresh that lands
+// between rendering the menu and the click must not make N open another item.
+func TestOpenItemAfterRefreshBetweenRenderAndClick(t *testing.T) {
+	server := githubtest.NewServer(t,
+		githubtest.Reply(t, http.StatusOK, "search_page_2"),
+		githubtest.Reply(t, http.StatusOK, "search_pull_requests"),
+		githubtest.Reply(t, http.StatusOK, "partial_data"),
+	)
+	cfg, _ := loadTestConfig(t, server, map[string]string{"WAYBAR_GITHUB_ITEM_ACTIONS": "false"})
+
+	opened := func(raw string) (string, error) {
+		t.Helper()
+		_, ref, _, err := parseArgs([]string{"open-item", raw})
+		if err != nil {
+			t.Fatalf("parse args: %v", err)
+		}
+		item, ok, err := state.ResolveItem(cfg.ItemsPath, ref)
+		if !ok {
+			return "", err
+		}
+		return "#" + strconv.Itoa(item.Number), err
+	}
+
+	if _, err := buildStatus(context.Background(), cfg); err != nil {
+		t.Fatalf("first build: %v", err)
+	}
+	if rows := readMenu(t, cfg.MenuPath); !hasRow(rows, "open_1", "● #43") {
+		t.Fatalf("expected #43 as open_1, got %q", rowLabels(rows))
+	}
+
+	// #42 and #7 are listed ahead of #43, which keeps its row id.
+	if _, err := buildStatus(context.Background(), cfg); err != nil {
+		t.Fatalf("second build: %v", err)
+	}
+	if got, err := opened("1"); got != "#43" || err != nil {
+		t.Fatalf("expected open_1 to keep opening #43, got %q (%v)", got, err)
+	}
+	rows := readMenu(t, cfg.MenuPath)
+	if !hasRow(rows, "open_1", "● #43") || !hasRow(rows, "open_2", "✗ #42") || !hasRow(rows, "open_3", "✓ #7") {
+		t.Fatalf("expected rows keyed by slot, got %+v", rows)
+	}
+
+	// #43 and #7 go away; clicks on their rows are refused, not redirected.
+	if _, err := buildStatus(context.Background(), cfg); err != nil {
+		t.Fatalf("third build: %v", err)
+	}
+	for _, raw := range []string{"1", "3", "id:PR_kwDOAbc0043"} {
+		if got, err := opened(raw); !errors.Is(err, itemref.ErrStale) {
+			t.Fatalf("expected %s to be refused as stale, got %q (%v)", raw, got, err)
+		}
+	}
+	if got, err := opened("2"); got != "#42" || err != nil {
+		t.Fatalf("expected open_2 to open #42, got %q (%v)", got, err)
+	}
+}
+
+// A row that no longer resolves is refused in every mode rather than ignored.
+func TestOpenCommandsRefuseStaleRows(t *testing.T) {
+	server := githubtest.NewServer(t, githubtest.Reply(t, http.StatusOK, "search_pull_requests"))
+	refs := []string{"9", "id:gone"}
+
+	cfg, _ := loadTestConfig(t, server, nil)
+	if _, err := buildStatus(context.Background(), cfg); err != nil {
+		t.Fatalf("build status: %v", err)
+	}
+	for _, raw := range refs {
+		if err := Run(context.Background(), []string{"open-item", raw}, cfg, io.Discard); !errors.Is(err, itemref.ErrStale) {
+			t.Fatalf("pull requests %s: expected a stale row, got %v", raw, err)
+		}
+	}
+
+	cfg, _ = loadModeConfig(t, config.ModeNotifications, server, nil)
+	if _, err := state.SaveNotifications(cfg.ItemsPath, []github.Notification{{ID: "1001", URL: "https://github.com/acme/api/pull/7"}}); err != nil {
+		t.Fatalf("save notifications: %v", err)
+	}
+	for _, raw := range refs {
+		if err := Run(context.Background(), []string{"open-item", raw}, cfg, io.Discard); !errors.Is(err, itemref.ErrStale) {
+			t.Fatalf("notifications %s: expected a stale row, got %v", raw, err)
+		}
+	}
+
+	cfg, _ = loadModeConfig(t, config.ModeActions, server, nil)
+	if _, err := state.SaveWorkflowRuns(cfg.ItemsPath, []github.WorkflowRun{{ID: 11, URL: "https://github.com/acme/app/actions/runs/11"}}); err != nil {
+		t.Fatalf("save workflow runs: %v", err)
+	}
+	for _, raw := range refs {
+		if err := Run(context.Background(), []string{"open-item", raw}, cfg, io.Discard); !errors.Is(err, itemref.ErrStale) {
+			t.Fatalf("actions %s: expected a stale row, got %v", raw, err)
+		}
+	}
+}
+
+func TestBuildStatusPartialData(t *testing.T) {
+	server := githubtest.NewServer(t, githubtest.Reply(t, http.StatusOK, "partial_data"))
+	cfg, _ := loadTestConfig(t, server, nil)
+
+	output, err := buildStatus(context.Background(), cfg)
+	if err != nil {
+		t.Fatalf("build status: %v", err)
+	}
+	if output.Text != "1" || !strings.HasPrefix(output.Class, "normal") {
+		t.Fatalf("expected the readable pull request, got %+v", output)
+	}
+	rows := readMenu(t, cfg.MenuPath)
+	if !hasRow(rows, "item_1", "✗ #42 Fix login redirect") || hasRow(rows, "item_2", "") {
+		t.Fatalf("expected one item row, got %q", rowLabels(rows))
+	}
+}
+
+func TestBuildStatusFailures(t *testing.T) {
+	tests := []struct {
+		name  string
+		reply githubtest.Response
+		want  string
+	}{
+		{name: "graphql errors", reply: githubtest.Reply(t, http.StatusOK, "graphql_errors"), want: "Field 'reviewRequested' doesn't exist"},
+		{name: "unauthorized", reply: githubtest.Reply(t, http.StatusUnauthorized, "unauthorized"), want: "status 401"},
+		{name: "server error", reply: githubtest.Reply(t, http.StatusBadGateway, "server_error"), want: "status 502"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := githubtest.NewServer(t, tt.reply)
+			cfg, _ := loadTestConfig(t, server, nil)
+
+			output, err := buildStatus(context.Background(), cfg)
+			if err != nil {
+				t.Fatalf("build status: %v", err)
+			}
+			if output.Text != "!" || output.Class != "error" || !strings.Contains(output.Tooltip, tt.want) {
+				t.Fatalf("expected an error containing %q, got %+v", tt.want, output)
+			}
+
+			items, _, err := state.LoadItems(cfg.ItemsPath)
+			if err != nil || len(items) != 0 {
+				t.Fatalf("expected no saved items, got %d (%v)", len(items), err)
+			}
+			rows := readMenu(t, cfg.MenuPath)
+			if !slices.Contains(rowLabels(rows), "GitHub API request failed") {
+				t.Fatalf("expected the failure in the menu, got %q", rowLabels(rows))
+			}
+		})
+	}
+}
+
+func TestBuildStatusKeepsLastGoodMenuThroughFailure(t *testing.T) {
+	server := githubtest.NewServer(t,
+		githubtest.Reply(t, http.StatusOK, "search_pull_requests"),
+		githubtest.Reply(t, http.StatusBadGateway, "server_error"),
+	)
+	cfg, _ := loadTestConfig(t, server, nil)
+
+	if _, err := buildStatus(context.Background(), cfg); err != nil {
+		t.Fatalf("first build: %v", err)
+	}
+	output, err := buildStatus(context.Background(), cfg)
+	if err != nil {
+		t.Fatalf("second build: %v", err)
+	}
+	if output.Text != "3" || !strings.HasPrefix(output.Class, "stale") || !strings.Contains(output.Tooltip, "Refresh failed") {
+		t.Fatalf("expected the cached items marked stale, got %+v", output)
+	}
+	rows := readMenu(t, cfg.MenuPath)
+	if !hasRow(rows, "item_1", "✗ #42 Fix login redirect") {
+		t.Fatalf("expected the last good menu, got %q", rowLabels(rows))
+	}
+}
+
+func TestBuildStatusRateLimited(t *testing.T) {
+	server := githubtest.NewServer(t, githubtest.Reply(t, http.StatusOK, "rate_limited"))
+	cfg, _ := loadTestConfig(t, server, nil)
+
+	output, err := buildStatus(context.Background(), cfg)
+	if err != nil {
+		t.Fatalf("build status: %v", err)
+	}
+	if !strings.HasPrefix(output.Class, "rate-limited") {
+		t.Fatalf("expected rate-limited, got %+v", output)
+	}
+	limits, err := state.LoadRateLimit(cfg.RateLimitPath)
+	if err != nil || limits.BlockedUntil.IsZero() {
+		t.Fatalf("expected fetching to pause, got %+v (%v)", limits, err)
+	}
+
+	if _, err := buildStatus(context.Background(), cfg); err != nil {
+		t.Fatalf("second build: %v", err)
+	}
+	if got := len(server.Requests()); got != 1 {
+		t.Fatalf("expected no request while paused, got %d", got)
+	}
+}
+
+func TestBuildStatusThroughGH(t *testing.T) {
+	server := githubtest.NewServer(t, githubtest.Reply(t, http.StatusInternalServerError, "server_error"))
+	cfg, gh := loadTestConfig(t, server, map[string]string{"WAYBAR_GITHUB_TOKEN": ""})
+	gh.Respond(t, "auth status", "", 0)
+	gh.Respond(t, "api graphql", githubtest.Reply(t, http.StatusOK, "search_pull_requests").Included(), 0)
+
+	output, err := buildStatus(context.Background(), cfg)
+	if err != nil {
+		t.Fatalf("build status: %v", err)
+	}
+	if output.Text != "3" {
+		t.Fatalf("expected text 3, got %+v", output)
+	}
+	if got := len(server.Requests()); got != 0 {
+		t.Fatalf("expected every request to go through gh, got %d HTTP requests", got)
+	}
+	rows := readMenu(t, cfg.MenuPath)
+	if !hasRow(rows, "item_2", `✓ #7 Escape <menu> & "labels"`) {
+		t.Fatalf("expected the fetched items, got %q", rowLabels(rows))
+	}
+}
+
+// Each test reads gh's token afresh, so one test's token never leaks into the next.
+func TestBuildStatusWithGHToken(t *testing.T) {
+	for _, token := range []string{"gho_first", "gho_second"} {
+		t.Run(token, func(t *testing.T) {
+			server := githubtest.NewServer(t, githubtest.Reply(t, http.StatusOK, "search_pull_requests"))
+			cfg, gh := loadTestConfig(t, server, map[string]string{"WAYBAR_GITHUB_TOKEN": ""})
+			gh.Respond(t, "auth status", "", 0)
+			gh.Respond(t, "auth token", token+"\n", 0)
+
+			if _, err := buildStatus(context.Background(), cfg); err != nil {
+				t.Fatalf("build status: %v", err)
+			}
+			if got := server.Requests()[0].Authorization; got != "Bearer "+token {
+				t.Fatalf("expected gh's token, got %q", got)
+			}
+		})
+	}
+}
+
+func TestBuildStatusWithoutAuth(t *testing.T) {
+	server := githubtest.NewServer(t, githubtest.Reply(t, http.StatusOK, "search_pull_requests"))
+	cfg, _ := loadTestConfig(t, server, map[string]string{"WAYBAR_GITHUB_TOKEN": ""})
+
+	output, err := buildStatus(context.Background(), cfg)
+	if err != nil {
+		t.Fatalf("build status: %v", err)
+	}
+	if output.Text != "?" || output.Class != "unknown" {
+		t.Fatalf("expected the sign-in hint, got %+v", output)
+	}
+	if got := len(server.Requests()); got != 0 {
+		t.Fatalf("expected no request without auth, got %d", got)
+	}
+	if _, err := os.Stat(cfg.MenuPath); err != nil {
+		t.Fatalf("expected a menu: %v", err)
+	}
+}
+
+// Only the user's own pull requests colour the bar; red checks win over running ones.
+func TestItemClassesFlagsAuthoredCI(t *testing.T) {
+	mine := func(ci string) github.PullRequest { return github.PullRequest{Authored: true, CIState: ci} }
+	theirs := func(ci string) github.PullRequest { return github.PullRequest{CIState: ci} }
+
+	tests := []struct {
+		name  string
+		items []github.PullRequest
+		want  string
+	}{
+		{name: "failing", items: []github.PullRequest{mine(github.CIStateSuccess), mine(github.CIStateFailure), mine(github.CIStatePending)}, want: " ci-failing"},
+		{name: "error", items: []github.PullRequest{mine(github.CIStateError)}, want: " ci-failing"},
+		{name: "pending", items: []github.PullRequest{mine(github.CIStateExpected), mine(github.CIStateSuccess)}, want: " ci-pending"},
+		{name: "green", items: []github.PullRequest{mine(github.CIStateSuccess), mine("")}, want: ""},
+		{name: "someone else's", items: []github.PullRequest{theirs(github.CIStateFailure), theirs(github.CIStatePending)}, want: ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := itemClasses(config.Runtime{}, tt.items, time.Now()); got != tt.want {
+				t.Fatalf("expected %q, got %q", tt.want, got)
+			}
+		})
+	}
+}
+
+// The tooltip names the reviewers behind the review state.
+func TestItemDetailNamesReviewers(t *testing.T) {
+	reviews := []github.Review{
+		{Author: "hubot", State: github.ReviewApproved},
+		{Author: "monalisa", State: github.ReviewChangesRequested},
+		{Author: "octocat", State: github.ReviewApproved},
+	}
+	tests := []struct {
+		name string
+		item github.PullRequest
+		want string
+	}{
+		{name: "approved", item: github.PullRequest{ReviewDecision: github.ReviewApproved, Reviews: reviews}, want: " — approved (hubot, octocat)"},
+		{name: "changes requested", item: github.PullRequest{ReviewDecision: github.ReviewChangesRequested, Reviews: reviews}, want: " — changes requested (monalisa)"},
+		{name: "awaiting", item: github.PullRequest{ReviewDecision: github.ReviewRequired, RequestedReviewers: []string{"hubot", "platform"}}, want: " — awaiting 2 reviewers (hubot, platform)"},
+		{name: "nobody named", item: github.PullRequest{ReviewDecision: github.ReviewRequired}, want: " — review required"},
+		{name: "no review state", item: github.PullRequest{}, want: ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := itemDetail(tt.item); got != tt.want {
+				t.Fatalf("expected %q, got %q", tt.want, got)
+			}
+		})
+	}
+}
+
+// GitHub's X-Poll-Interval holds the next request back, and the one after it
+// is conditional so an unchanged inbox costs no rate limit.
+func TestBuildNotificationsStatusPollInterval(t *testing.T) {
+	page := githubtest.Reply(t, http.StatusOK, "notifications")
+	page.Header.Set("Last-Modified", "Tue, 30 Apr 2024 09:00:00 GMT")
+	page.Header.Set("X-Poll-Interval", "60")
+	server := githubtest.NewServer(t, page, githubtest.Response{Status: http.StatusNotModified, Header: http.Header{"X-Poll-Interval": {"60"}}})
+	cfg, _ := loadModeConfig(t, config.ModeNotifications, server, nil)
+
+	for range 2 {
+		output, err := buildNotificationsStatus(context.Background(), cfg)
+		if err != nil {
+			t.Fatalf("build status: %v", err)
+		}
+		if output.Text != "2" || output.Class != "normal" {
+			t.Fatalf("expected two notifications, got %+v", output)
+		}
+	}
+	if got := len(server.Requests()); got != 1 {
+		t.Fatalf("expected the poll interval to hold back the second request, got %d requests", got)
+	}
+
+	meta, err := state.LoadNotificationsMeta(cfg.MetaPath)
+	if err != nil {
+		t.Fatalf("load meta: %v", err)
+	}
+	meta.CheckedAt = meta.CheckedAt.Add(-time.Minute)
+	if err := state.SaveNotificationsMeta(cfg.MetaPath, meta); err != nil {
+		t.Fatalf("save meta: %v", err)
+	}
+
+	output, err := buildNotificationsStatus(context.Background(), cfg)
+	if err != nil {
+		t.Fatalf("build status: %v", err)
+	}
+	if output.Text != "2" {
+		t.Fatalf("expected a 304 to keep the inbox, got %+v", output)
+	}
+	requests := server.Requests()
+	if len(requests) != 2 || requests[1].Header.Get("If-Modified-Since") != "Tue, 30 Apr 2024 09:00:00 GMT" {
+		t.Fatalf("expected one conditional request once the interval passed, got %+v", requests)
+	}
+	if items, _, err := state.LoadNotifications(cfg.ItemsPath); err != nil || len(items) != 2 {
+		t.Fatalf("expected the saved notifications to survive a 304, got %d (%v)", len(items), err)
+	}
+}
+
+func TestPrEvents(t *testing.T) {
+	now := time.Date(2024, 5, 1, 9, 0, 0, 0, time.UTC)
+	commented := time.Date(2024, 4, 30, 9, 0, 0, 0, time.UTC)
+	review := github.PullRequest{ID: "PR_7", Number: 7, Repository: "acme/api", Title: "Add retries", URL: "https://github.com/acme/api/pull/7", ReviewRequested: true}
+	mine := github.PullRequest{ID: "PR_42", Number: 42, Repository: "acme/app", Title: "Fix login", URL: "https://github.com/acme/app/pull/42", Authored: true, CommentedAt: []time.Time{commented}}
+	seen := state.Seen{}
+
+	summaries := func(events []prEvent) []string {
+		var out []string
+		for _, event := range events {
+			out = append(out, event.summary+": "+event.body)
+		}
+		return out
+	}
+
+	events := prEvents([]github.PullRequest{review, mine, review}, nil, seen, now)
+	if got := summaries(events); !slices.Equal(got, []string{"Review requested: acme/api #7: Add retries"}) {
+		t.Fatalf("expected one review request and no comments on first sight, got %q", got)
+	}
+	if events := prEvents([]github.PullRequest{review, mine}, nil, seen, now); len(events) != 0 {
+		t.Fatalf("expected nothing new, got %q", summaries(events))
+	}
+
+	review.ReviewRequested = false
+	mine.CIState = github.CIStateFailure
+	mine.CommentedAt = append(mine.CommentedAt, commented.Add(time.Hour), commented.Add(2*time.Hour))
+	events = prEvents([]github.PullRequest{review, mine}, nil, seen, now)
+	if got := summaries(events); !slices.Equal(got, []string{"CI failed: acme/app #42: Fix login", "2 new comment(s): acme/app #42: Fix login"}) {
+		t.Fatalf("expected red CI and two new comments, got %q", got)
+	}
+
+	// A review requested again after it cleared, and a merge, notify once each.
+	review.ReviewRequested = true
+	merged := github.PullRequest{ID: "PR_40", Number: 40, Repository: "acme/app", Title: "Bump deps"}
+	events = prEvents([]github.PullRequest{review, mine}, []github.PullRequest{merged}, seen, now)
+	if got := summaries(events); !slices.Equal(got, []string{"Review requested: acme/api #7: Add retries", "Pull request merged: acme/app #40: Bump deps"}) {
+		t.Fatalf("expected the new request and the merge, got %q", got)
+	}
+	if events := prEvents([]github.PullRequest{review, mine}, []github.PullRequest{merged}, seen, now); len(events) != 0 {
+		t.Fatalf("expected nothing new, got %q", summaries(events))
+	}
+}
+
+// Seen-sets written when the comments mark held a count must not report every
+// comment as new.
+func TestPrEventsUpgradesCommentCountMark(t *testing.T) {
+	now := time.Date(2024, 5, 1, 9, 0, 0, 0, time.UTC)
+	mine := github.PullRequest{ID: "PR_42", Authored: true, CommentedAt: []time.Time{now.Add(-time.Hour)}}
+	seen := state.Seen{"comments:PR_42": {Value: "3", At: now.Add(-time.Hour)}}
+
+	if events := prEvents([]github.PullRequest{mine}, nil, seen, now); len(events) != 0 {
+		t.Fatalf("expected no events from a count mark, got %+v", events)
+	}
+	if got := seen["comments:PR_42"].Value; got != now.Add(-time.Hour).Format(time.RFC3339Nano) {
+		t.Fatalf("expected the mark to hold the newest comment, got %q", got)
+	}
+}
+
+func TestMergeFetchesDegradesFailedHosts(t *testing.T) {
+	// The stale window is measured against the clock.
+	now := time.Now().UTC()
+	cfg := config.Runtime{StaleAfter: 15 * time.Minute}
+	searches := []config.Search{{Name: "Review requested"}, {Name: "Mine"}}
+	hostCfg := func(host string) config.Runtime {
+		return config.Runtime{Host: host, Searches: searches}
+	}
+
+	ok := hostFetch{cfg: hostCfg("github.com"), result: github.FetchResult{
+		Count:    2,
+		Items:    []github.PullRequest{{ID: "PR_1", Host: "github.com", Search: "Mine"}, {ID: "PR_2", Host: "github.com", Search: "Mine"}},
+		Searches: []github.SearchCount{{Name: "Review requested", Host: "github.com"}, {Name: "Mine", Host: "github.com", Count: 2}},
+	}}
+	previous := state.Meta{
+		Searches: []github.SearchCount{
+			{Name: "Review requested", Host: "ghe.example.com", Count: 1, Error: "Refresh failed: old"},
+			{Name: "Mine", Host: "ghe.example.com", Count: 1},
+		},
+		HostFetchedAt: map[string]time.Time{"ghe.example.com": now.Add(-5 * time.Minute)},
+		HostCount:     map[string]int{"ghe.example.com": 1},
+	}
+	previousItems := []github.PullRequest{{ID: "PR_9", Host: "ghe.example.com", Search: "Review requested"}, {ID: "PR_9", Host: "ghe.example.com", Search: "Mine"}, {ID: "PR_1", Host: "github.com"}}
+
+	tests := []struct {
+		name      string
+		failed    hostFetch
+		lastGood  time.Time
+		wantItems int
+		wantCount int
+		wantError string
+		// wantCached is whether the failed host keeps its last good fetch time.
+		wantCached bool
+	}{
+		{
+			name:       "within the stale window",
+			failed:     hostFetch{cfg: hostCfg("ghe.example.com"), err: errors.New("timeout")},
+			lastGood:   now.Add(-5 * time.Minute),
+			wantItems:  4,
+			wantCount:  3,
+			wantError:  "Refresh failed: timeout",
+			wantCached: true,
+		},
+		{
+			name:      "too old to show",
+			failed:    hostFetch{cfg: hostCfg("ghe.example.com"), err: errors.New("timeout")},
+			lastGood:  now.Add(-time.Hour),
+			wantItems: 2,
+			wantCount: 2,
+			wantError: "Refresh failed: timeout",
+		},
+		{
+			name:       "rate limited keeps any age",
+			failed:     hostFetch{cfg: hostCfg("ghe.example.com"), err: errors.New("rate limited"), limitedUntil: now.Add(time.Hour)},
+			lastGood:   now.Add(-time.Hour),
+			wantItems:  4,
+			wantCount:  3,
+			wantError:  rateLimitedReason(now.Add(time.Hour)),
+			wantCached: true,
+		},
+		{
+			name:      "signed out",
+			failed:    hostFetch{cfg: hostCfg("ghe.example.com"), err: errNoAuth},
+			lastGood:  now.Add(-time.Hour),
+			wantItems: 2,
+			wantCount: 2,
+			wantError: "Not signed in; run 'gh auth login -h ghe.example.com'",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			previous := previous
+			previous.HostFetchedAt = map[string]time.Time{"ghe.example.com": tt.lastGood}
+
+			merged, meta, degraded := mergeFetches(cfg, []hostFetch{ok, tt.failed}, previous, previousItems, now)
+			if !degraded {
+				t.Fatal("expected a degraded result")
+			}
+			if len(merged.Items) != tt.wantItems || merged.Count != tt.wantCount || meta.Count != tt.wantCount {
+				t.Fatalf("expected %d items counted %d, got %d items, count %d, meta count %d", tt.wantItems, tt.wantCount, len(merged.Items), merged.Count, meta.Count)
+			}
+			if len(merged.Searches) != 4 {
+				t.Fatalf("expected a section per host and search, got %+v", merged.Searches)
+			}
+			failed := merged.Searches[2:]
+			if failed[0].Host != "ghe.example.com" || failed[0].Error != tt.wantError || failed[1].Error != "" {
+				t.Fatalf("expected only the failed host's first section to carry %q, got %+v", tt.wantError, failed)
+			}
+			if merged.Searches[0].Error != "" || merged.Searches[1].Error != "" {
+				t.Fatalf("expected the healthy host's sections to be clean, got %+v", merged.Searches[:2])
+			}
+			if !meta.HostFetchedAt["github.com"].Equal(now) {
+				t.Fatalf("expected github.com fetched now, got %v", meta.HostFetchedAt)
+			}
+			at, cached := meta.HostFetchedAt["ghe.example.com"]
+			if cached != tt.wantCached || (cached && !at.Equal(tt.lastGood)) {
+				t.Fatalf("expected ghe.example.com cached=%v from %s, got %v", tt.wantCached, tt.lastGood, meta.HostFetchedAt)
+			}
+		})
+	}
+}
+
+func TestBuildStatusIssuesAndDiscussions(t *testing.T) {
+	server := githubtest.NewServer(t, githubtest.Reply(t, http.StatusOK, "search_issues_discussions"))
+	cfg, _ := loadTestConfig(t, server, map[string]string{
+		"WAYBAR_GITHUB_SEARCHES": "Issues=is:issue involves:@me;Discussions=is:discussion involves:@me",
+	})
+
+	output, err := buildStatus(context.Background(), cfg)
+	if err != nil {
+		t.Fatalf("build status: %v", err)
+	}
+	for _, want := range []string{"acme/app #101: Login loops on Safari [bug, p1]", "acme/app #12: Roadmap for v2 [Q&A, answered]"} {
+		if !strings.Contains(output.Tooltip, want) {
+			t.Fatalf("expected tooltip to contain %q, got %q", want, output.Tooltip)
+		}
+	}
+
+	rows := readMenu(t, cfg.MenuPath)
+	for _, want := range []string{"⊙ acme/app #101 Login loops on Safari · bug, p1", "💬 acme/app #12 Roadmap for v2 · Q&A, answered"} {
+		if !slices.ContainsFunc(rows, func(row menuRow) bool { return strings.HasPrefix(row.Label, want) }) {
+			t.Fatalf("expected row %q, got %q", want, rowLabels(rows))
+		}
+	}
+}
+
+// stubNotifications records desktop notifications instead of spawning them.
+func stubNotifications(t *testing.T) *[]prEvent {
+	t.Helper()
+	original := spawnNotification
+	t.Cleanup(func() { spawnNotification = original })
+	events := new([]prEvent)
+	spawnNotification = func(event prEvent) error {
+		*events = append(*events, event)
+		return nil
+	}
+	return events
+}
+
+// restCalls lists the REST requests the server received as "METHOD path".
+func restCalls(server *githubtest.Server) []string {
+	var calls []string
+	for _, request := range server.Requests() {
+		if request.Path != "/graphql" {
+			calls = append(calls, request.Method+" "+request.Path)
+		}
+	}
+	return calls
+}
+
+func restReply(status int, body string) githubtest.Response {
+	return githubtest.Response{Status: status, Header: http.Header{"Content-Type": {"application/json"}}, Body: []byte(body)}
+}
+
+func TestApproveConfirmsThenActsOnKey(t *testing.T) {
+	server := githubtest.NewServer(t,
+		githubtest.Reply(t, http.StatusOK, "search_pull_requests"),
+		restReply(http.StatusOK, `{"id": 1, "state": "APPROVED"}`),
+		githubtest.Reply(t, http.StatusOK, "search_pull_requests"),
+	)
+	cfg, _ := loadTestConfig(t, server, nil)
+	events := stubNotifications(t)
+	if _, err := buildStatus(context.Background(), cfg); err != nil {
+		t.Fatalf("build status: %v", err)
+	}
+
+	// The menu row only asks; nothing is approved until the key comes back.
+	if err := runItemAction(context.Background(), cfg, "approve", itemref.Ref{Slot: 2}); err != nil {
+		t.Fatalf("approve slot: %v", err)
+	}
+	if len(*events) != 1 {
+		t.Fatalf("expected one confirmation, got %+v", *events)
+	}
+	confirm := (*events)[0]
+	if confirm.summary != "Approve acme/api #7?" || confirm.action != "approve" || confirm.key != "PR_kwDOAbc0007" {
+		t.Fatalf("unexpected confirmation %+v", confirm)
+	}
+	if calls := restCalls(server); len(calls) != 0 {
+		t.Fatalf("expected no API call before confirming, got %q", calls)
+	}
+
+	if err := runItemAction(context.Background(), cfg, confirm.action, itemref.Ref{Key: confirm.key}); err != nil {
+		t.Fatalf("approve key: %v", err)
+	}
+	if calls := restCalls(server); !slices.Equal(calls, []string{"POST /repos/acme/api/pulls/7/reviews"}) {
+		t.Fatalf("unexpected API calls %q", calls)
+	}
+	if got := (*events)[len(*events)-1].summary; got != "Approved" {
+		t.Fatalf("expected an approved notification, got %q", got)
+	}
+}
+
+func TestItemActionsRefuseStaleItems(t *testing.T) {
+	server := githubtest.NewServer(t, githubtest.Reply(t, http.StatusOK, "search_pull_requests"))
+	cfg, _ := loadTestConfig(t, server, nil)
+	events := stubNotifications(t)
+	if _, err := buildStatus(context.Background(), cfg); err != nil {
+		t.Fatalf("build status: %v", err)
+	}
+
+	refs := []itemref.Ref{{Key: "PR_kwDOAbc0099"}, {Slot: 9}}
+	for _, action := range []string{"approve", "rerun-failed", "checkout", "copy-url"} {
+		for _, ref := range refs {
+			if err := runItemAction(context.Background(), cfg, action, ref); !errors.Is(err, itemref.ErrStale) {
+				t.Fatalf("%s %+v: expected a stale item to be refused, got %v", action, ref, err)
+			}
+		}
+	}
+	if calls := restCalls(server); len(calls) != 0 || len(*events) != 0 {
+		t.Fatalf("expected no API calls or notifications, got %q and %+v", calls, *events)
+	}
+}
+
+func TestRerunFailedJobs(t *testing.T) {
+	server := githubtest.NewServer(t,
+		githubtest.Reply(t, http.StatusOK, "search_pull_requests"),
+		restReply(http.StatusOK, `{"head": {"sha": "abc123"}}`),
+		restReply(http.StatusOK, `{"total_count": 2, "workflow_runs": [{"id": 11}, {"id": 12}]}`),
+		restReply(http.StatusCreated, `{}`),
+		restReply(http.StatusCreated, `{}`),
+		githubtest.Reply(t, http.StatusOK, "search_pull_requests"),
+	)
+	cfg, _ := loadTestConfig(t, server, nil)
+	events := stubNotifications(t)
+	if _, err := buildStatus(context.Background(), cfg); err != nil {
+		t.Fatalf("build status: %v", err)
+	}
+
+	if err := runItemAction(context.Background(), cfg, "rerun-failed", itemref.Ref{Slot: 1}); err != nil {
+		t.Fatalf("rerun slot: %v", err)
+	}
+	confirm := (*events)[0]
+	if confirm.summary != "Re-run failed jobs of acme/app #42?" || confirm.key != "PR_kwDOAbc0042" {
+		t.Fatalf("unexpected confirmation %+v", confirm)
+	}
+
+	if err := runItemAction(context.Background(), cfg, "rerun-failed", itemref.Ref{Key: confirm.key}); err != nil {
+		t.Fatalf("rerun key: %v", err)
+	}
+	want := []string{
+		"GET /repos/acme/app/pulls/42",
+		"GET /repos/acme/app/actions/runs?head_sha=abc123&per_page=50&status=failure",
+		"POST /repos/acme/app/actions/runs/11/rerun-failed-jobs",
+		"POST /repos/acme/app/actions/runs/12/rerun-failed-jobs",
+	}
+	if calls := restCalls(server); !slices.Equal(calls, want) {
+		t.Fatalf("expected API calls %q, got %q", want, calls)
+	}
+	if got := (*events)[len(*events)-1].summary; got != "Re-running failed jobs in 2 workflow run(s)" {
+		t.Fatalf("unexpected result notification %q", got)
+	}
+}
+
+func TestParseDesktopNotifyConfirmation(t *testing.T) {
+	_, ref, _, err := parseArgs([]string{"desktop-notify", "Approve acme/api #7?", "Fix", "https://github.com/acme/api/pull/7", "approve", "id:PR_kwDOAbc0007"})
+	if err != nil || ref.Key != "PR_kwDOAbc0007" {
+		t.Fatalf("expected the confirmation key, got %+v (%v)", ref, err)
+	}
+	for _, args := range [][]string{
+		{"desktop-notify", "s", "b", "u", "approve", "2"},
+		{"desktop-notify", "s", "b", "u", "open-item", "id:PR_kwDOAbc0007"},
+		{"desktop-notify", "s", "b", "u", "approve"},
+	} {
+		if _, _, _, err := parseArgs(args); err == nil {
+			t.Fatalf("expected %q to be rejected", args)
+		}
+	}
+}
+
+func TestCopyURL(t *testing.T) {
+	server := githubtest.NewServer(t, githubtest.Reply(t, http.StatusOK, "search_pull_requests"))
+	cfg, gh := loadTestConfig(t, server, nil)
+	if _, err := buildStatus(context.Background(), cfg); err != nil {
+		t.Fatalf("build status: %v", err)
+	}
+
+	// PATH holds only the fake gh, so the fake wl-copy sticks to builtins.
+	clipboard := filepath.Join(t.TempDir(), "clipboard")
+	script := "#!/bin/sh\nIFS= read -r text\nprintf '%s' \"$text\" > '" + clipboard + "'\n"
+	if err := os.WriteFile(filepath.Join(gh.Dir(), "wl-copy"), []byte(script), 0o755); err != nil {
+		t.Fatalf("write fake wl-copy: %v", err)
+	}
+
+	if err := runItemAction(context.Background(), cfg, "copy-url", itemref.Ref{Slot: 2}); err != nil {
+		t.Fatalf("copy url: %v", err)
+	}
+	if raw, err := os.ReadFile(clipboard); err != nil || string(raw) != "https://github.com/acme/api/pull/7" {
+		t.Fatalf("expected the URL on the clipboard, got %q (%v)", raw, err)
+	}
+}
+
+func TestCheckoutThroughGH(t *testing.T) {
+	server := githubtest.NewServer(t, githubtest.Reply(t, http.StatusOK, "search_pull_requests"))
+	clone := t.TempDir()
+	cfg, gh := loadTestConfig(t, server, map[string]string{"WAYBAR_GITHUB_CLONES": "acme/api=" + clone})
+	gh.Respond(t, "pr checkout", "", 0)
+	events := stubNotifications(t)
+	if _, err := buildStatus(context.Background(), cfg); err != nil {
+		t.Fatalf("build status: %v", err)
+	}
+
+	if err := runItemAction(context.Background(), cfg, "checkout", itemref.Ref{Slot: 2}); err != nil {
+		t.Fatalf("checkout: %v", err)
+	}
+	if calls := gh.Calls(t); !slices.ContainsFunc(calls, func(call []string) bool {
+		return slices.Equal(call, []string{"pr", "checkout", "7"})
+	}) {
+		t.Fatalf("expected gh pr checkout 7, got %q", calls)
+	}
+	if got := (*events)[0].summary; got != "Checked out in "+clone {
+		t.Fatalf("unexpected result notification %q", got)
+	}
+}
+
+func TestCheckoutWithGit(t *testing.T) {
+	gitPath, err := exec.LookPath("git")
+	if err != nil {
+		t.Skip("git is not installed")
+	}
+	git := func(dir string, args ...string) string {
+		t.Helper()
+		cmd := exec.Command(gitPath, append([]string{"-c", "user.name=Test", "-c", "user.email=test@example.com", "-c", "init.defaultBranch=main"}, args...)...)
+		cmd.Dir = dir
+		output, err := cmd.CombinedOutput()
+		if err != nil {
+			t.Fatalf("git %s: %v\n%s", strings.Join(args, " "), err, output)
+		}
+		return strings.TrimSpace(string(output))
+	}
+
+	// origin carries the pull request only as refs/pull/7/head, which a clone
+	// does not fetch.
+	origin := t.TempDir()
+	git(origin, "init", "-q")
+	git(origin, "commit", "-q", "--allow-empty", "-m", "base")
+	git(origin, "switch", "-q", "-c", "feature")
+	git(origin, "commit", "-q", "--allow-empty", "-m", "change")
+	head := git(origin, "rev-parse", "HEAD")
+	git(origin, "update-ref", "refs/pull/7/head", head)
+	git(origin, "switch", "-q", "main")
+	git(origin, "branch", "-q", "-D", "feature")
+	clone := filepath.Join(t.TempDir(), "api")
+	git(origin, "clone", "-q", origin, clone)
+
+	server := githubtest.NewServer(t, githubtest.Reply(t, http.StatusOK, "search_pull_requests"))
+	cfg, _ := loadTestConfig(t, server, map[string]string{"WAYBAR_GITHUB_CLONE_ROOT": filepath.Dir(clone)})
+	stubNotifications(t)
+	if _, err := buildStatus(context.Background(), cfg); err != nil {
+		t.Fatalf("build status: %v", err)
+	}
+	// Without gh on PATH, checkout falls back to git.
+	bin := t.TempDir()
+	if err := os.Symlink(gitPath, filepath.Join(bin, "git")); err != nil {
+		t.Fatalf("link git: %v", err)
+	}
+	t.Setenv("PATH", bin)
+
+	if err := runItemAction(context.Background(), cfg, "checkout", itemref.Ref{Slot: 2}); err != nil {
+		t.Fatalf("checkout: %v", err)
+	}
+	if branch := git(clone, "branch", "--show-current"); branch != "pr-7" {
+		t.Fatalf("expected branch pr-7, got %q", branch)
+	}
+	if got := git(clone, "rev-parse", "HEAD"); got != head {
+		t.Fatalf("expected the pull request head %s, got %s", head, got)
+	}
+}
+
+func TestBuildStatusReportsHiddenAndCapped(t *testing.T) {
+	server := githubtest.NewServer(t, githubtest.Reply(t, http.StatusOK, "search_pull_requests"))
+	cfg, _ := loadTestConfig(t, server, map[string]string{
+		"WAYBAR_GITHUB_EXCLUDE_REPOS": "acme/api",
+		"WAYBAR_GITHUB_MAX_PER_REPO":  "1",
+	})
+
+	output, err := buildStatus(context.Background(), cfg)
+	if err != nil {
+		t.Fatalf("build status: %v", err)
+	}
+	if output.Text != "1" || !strings.HasPrefix(output.Tooltip, "GitHub pull requests: 1, 1 hidden, 1 capped\n") {
+		t.Fatalf("expected one item with the hidden and capped notes, got %q and %q", output.Text, output.Tooltip)
+	}
+	rows := rowLabels(readMenu(t, cfg.MenuPath))
+	for _, want := range []string{"1 hidden by filters", "1 over the per-repository cap"} {
+		if !slices.Contains(rows, want) {
+			t.Fatalf("expected row %q, got %q", want, rows)
+		}
+	}
+}
+
+func TestBuildStatusSeparatesDrafts(t *testing.T) {
+	server := githubtest.NewServer(t, githubtest.Reply(t, http.StatusOK, "search_pull_requests"))
+	cfg, _ := loadTestConfig(t, server, map[string]string{"WAYBAR_GITHUB_DRAFTS": "separate"})
+
+	if _, err := buildStatus(context.Background(), cfg); err != nil {
+		t.Fatalf("build status: %v", err)
+	}
+	rows := rowLabels(readMenu(t, cfg.MenuPath))
+	drafts := slices.Index(rows, "Drafts (1)")
+	if drafts < 0 {
+		t.Fatalf("expected a Drafts section, got %q", rows)
+	}
+	draft := slices.IndexFunc(rows, func(label string) bool { return strings.HasPrefix(label, "● acme/app #43") })
+	ready := slices.IndexFunc(rows, func(label string) bool { return strings.HasPrefix(label, "✗ #42") })
+	if draft < drafts || ready > drafts {
+		t.Fatalf("expected only the draft below the Drafts section, got %q", rows)
+	}
+}
+
+// The status path passes UTC; work days still have to be the user's local days.
+func TestReviewOverdueCountsLocalDays(t *testing.T) {
+	original := time.Local
+	t.Cleanup(func() { time.Local = original })
+	time.Local = time.FixedZone("UTC+10", 10*60*60)
+
+	week, err := config.ParseWorkWeek("mon-fri")
+	if err != nil {
+		t.Fatalf("parse: %v", err)
+	}
+	cfg := config.Runtime{ReviewSLA: 8 * time.Hour, WorkWeek: week}
+	// Friday 20:00 UTC is Saturday morning locally, and Monday 01:00 UTC is 11:00.
+	item := github.PullRequest{ReviewRequested: true, ReviewRequestedAt: time.Date(2026, 3, 6, 20, 0, 0, 0, time.UTC)}
+	now := time.Date(2026, 3, 9, 1, 0, 0, 0, time.UTC)
+
+	if !reviewOverdue(cfg, item, now) {
+		t.Fatal("expected 11h of local Monday to exceed the 8h SLA")
+	}
+	if reviewOverdue(cfg, item, now.Add(-4*time.Hour)) {
+		t.Fatal("expected 7h of local Monday to be within the SLA")
+	}
+}
+
+func TestNotifyRedBranches(t *testing.T) {
+	cfg := config.Runtime{SeenPath: filepath.Join(t.TempDir(), "seen.json")}
+	events := stubNotifications(t)
+	app := github.WatchedBranch{Repository: "acme/app", Branch: "main"}
+	api := github.WatchedBranch{Repository: "acme/api", Branch: "main"}
+	run := func(branch github.WatchedBranch, workflow, conclusion string) github.WorkflowRun {
+		return github.WorkflowRun{Repository: branch.Repository, Branch: branch.Branch, Workflow: workflow, Title: "Bump deps", URL: "https://github.com/" + branch.Repository + "/actions/runs/1", Status: "completed", Conclusion: conclusion}
+	}
+	red := []github.WorkflowRun{run(app, "CI", "failure"), run(app, "Lint", "timed_out"), run(app, "Docs", "success")}
+	green := []github.WorkflowRun{run(app, "CI", "success"), run(app, "Lint", "success")}
+
+	steps := []struct {
+		name     string
+		branches []github.WatchedBranch
+		runs     []github.WorkflowRun
+		want     []string
+	}{
+		// The first run only records which branches are already red.
+		{name: "first run", branches: []github.WatchedBranch{app}, runs: red},
+		{name: "still red", branches: []github.WatchedBranch{app}, runs: red},
+		{name: "green", branches: []github.WatchedBranch{app}, runs: green},
+		{name: "red again", branches: []github.WatchedBranch{app}, runs: red, want: []string{"acme/app@main is failing|CI, Lint failed: Bump deps"}},
+		// A failed fetch says nothing about the branch, so its mark survives.
+		{name: "fetch failed", branches: []github.WatchedBranch{{Repository: "acme/app", Branch: "main", Error: "Refresh failed"}}},
+		{name: "red after the failure", branches: []github.WatchedBranch{app}, runs: red},
+		{name: "another branch", branches: []github.WatchedBranch{app, api}, runs: append(slices.Clone(red), run(api, "CI", "startup_failure")), want: []string{"acme/api@main is failing|CI failed: Bump deps"}},
+	}
+	for _, step := range steps {
+		*events = nil
+		notifyRedBranches(cfg, step.branches, step.runs)
+		var got []string
+		for _, event := range *events {
+			got = append(got, event.summary+"|"+event.body)
+		}
+		if !slices.Equal(got, step.want) {
+			t.Fatalf("%s: expected notifications %q, got %q", step.name, step.want, got)
+		}
+	}
+
+	seen, _, err := state.LoadSeen(cfg.SeenPath)
+	if err != nil {
+		t.Fatalf("load seen: %v", err)
+	}
+	if _, ok := seen["red:acme/app@main"]; !ok {
+		t.Fatalf("expected the red branch to stay marked, got %v", seen)
+	}
+}
+
+// A branch red for longer than the seen-set keeps entries is not announced again.
+func TestNotifyRedBranchesRefreshesAgingMarks(t *testing.T) {
+	cfg := config.Runtime{SeenPath: filepath.Join(t.TempDir(), "seen.json")}
+	events := stubNotifications(t)
+	old := time.Now().UTC().Add(-seenMaxAge - 24*time.Hour)
+	if err := state.SaveSeen(cfg.SeenPath, state.Seen{"red:acme/app@main": {At: old}, "red:acme/gone@main": {At: old}}); err != nil {
+		t.Fatalf("save seen: %v", err)
+	}
+
+	branch := github.WatchedBranch{Repository: "acme/app", Branch: "main"}
+	runs := []github.WorkflowRun{{Repository: "acme/app", Branch: "main", Workflow: "CI", Conclusion: "failure"}}
+	notifyRedBranches(cfg, []github.WatchedBranch{branch}, runs)
+	if len(*events) != 0 {
+		t.Fatalf("expected no notification for a branch already marked red, got %+v", *events)
+	}
+
+	seen, _, err := state.LoadSeen(cfg.SeenPath)
+	if err != nil {
+		t.Fatalf("load seen: %v", err)
+	}
+	if mark, ok := seen["red:acme/app@main"]; !ok || !mark.At.After(old) {
+		t.Fatalf("expected the mark to be refreshed, got %v", seen)
+	}
+	if _, ok := seen["red:acme/gone@main"]; ok {
+		t.Fatalf("expected the unwatched branch's old mark to be pruned, got %v", seen)
+	}
+}
+
+func TestServiceCommandTimeout(t *testing.T) {
+	server := githubtest.NewServer(t, githubtest.Reply(t, http.StatusOK, "search_pull_requests"))
+	cfg, _ := loadTestConfig(t, server, nil)
+	service := Service(cfg)
+
+	if got := service.CommandTimeout([]string{"checkout", "1"}); got != 2*time.Minute {
+		t.Fatalf("expected checkout to get 2m, got %s", got)
+	}
+	if got := service.CommandTimeout([]string{"status"}); got != service.Timeout {
+		t.Fatalf("expected status to keep the default %s, got %s", service.Timeout, got)
+	}
+}
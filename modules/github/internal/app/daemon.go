@@ -0,0 +1,51 @@
+package app
+
+import (
+	"context"
+	"io"
+	"time"
+
+	"github.com/rbright/waybar-github/internal/config"
+	"github.com/rbright/waybar-github/internal/desktop"
+	"github.com/rbright/waybar-shared/daemon"
+)
+
+// Timeout bounds one command run for args.
+func Timeout(cfg config.Runtime, args []string) time.Duration {
+	timeout := cfg.Timeout + 5*time.Second
+	if timeout < 10*time.Second {
+		timeout = 10 * time.Second
+	}
+	if len(args) > 0 && args[0] == "desktop-notify" {
+		// Detached notifier processes wait for a click, not for the API.
+		timeout = desktop.MaxWait
+	}
+	if len(args) > 0 && args[0] == "checkout" {
+		// Fetching a pull request into a large clone can outlast an API call.
+		timeout = max(timeout, 2*time.Minute)
+	}
+	return timeout
+}
+
+// Service serves Run for cfg from a daemon.
+func Service(cfg config.Runtime) daemon.Service {
+	return daemon.Service{
+		Name:       serviceName(cfg.Mode),
+		SocketPath: cfg.SocketPath,
+		Interval:   cfg.DaemonInterval,
+		Timeout:    Timeout(cfg, nil),
+		CommandTimeout: func(args []string) time.Duration {
+			return Timeout(cfg, args)
+		},
+		Handler: func(ctx context.Context, args []string, stdout io.Writer) error {
+			return Run(ctx, args, cfg, stdout)
+		},
+	}
+}
+
+func serviceName(mode string) string {
+	if mode == config.ModePullRequests {
+		return "github"
+	}
+	return "github-" + mode
+}
@@ -0,0 +1,132 @@
+package app
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os/exec"
+	"strings"
+	"time"
+
+	"github.com/rbright/waybar-github/internal/config"
+	"github.com/rbright/waybar-github/internal/state"
+	"github.com/rbright/waybar-shared/doctor"
+	"github.com/rbright/waybar-shared/opener"
+	"github.com/rbright/waybar-shared/secrets"
+)
+
+func runDoctor(ctx context.Context, cfg config.Runtime, stdout io.Writer) error {
+	var report doctor.Report
+
+	report.ConfigFile("config", cfg.ConfigFile, config.ValidateEnvFile)
+	report.LogLevel(cfg.LogLevel)
+	checkOpener(&report, cfg, "open pull requests")
+	if cfg.Mode == config.ModeActions {
+		if len(cfg.Watches) == 0 {
+			report.Fail("watched branches", "none; set WAYBAR_GITHUB_WATCH")
+		} else {
+			report.OK("watched branches", watchNames(cfg.Watches))
+		}
+	}
+	ghAvailable := report.Command("gh", "preferred auth source; falls back to token", false)
+	for _, host := range cfg.Hosts {
+		checkHost(ctx, &report, cfg.ForHost(host), ghAvailable, len(cfg.Hosts) > 1)
+	}
+
+	report.WritableDir("state dir", cfg.StateDir)
+	report.WritableDir("menu dir", cfg.MenuDir)
+
+	if err := report.Write(stdout); err != nil {
+		return err
+	}
+	return report.Err()
+}
+
+// checkHost reports auth, searches and the saved rate limit for one host. Labels
+// name the host when several are configured.
+func checkHost(ctx context.Context, report *doctor.Report, cfg config.Runtime, ghAvailable, labelHost bool) {
+	label := func(name string) string {
+		if labelHost {
+			return name + " (" + cfg.Host + ")"
+		}
+		return name
+	}
+
+	report.OK(label("searches"), searchNames(cfg.Searches))
+
+	limits, err := state.LoadRateLimit(cfg.RateLimitPath)
+	switch {
+	case err != nil:
+		report.Warn(label("rate limit"), err.Error())
+	case time.Now().Before(limits.BlockedUntil):
+		report.Warn(label("rate limit"), rateLimitedReason(limits.BlockedUntil))
+	case limits.Limit > 0:
+		report.OK(label("rate limit"), quotaLine(limits))
+	}
+
+	ghAuthed := false
+	if ghAvailable {
+		cmd := exec.CommandContext(ctx, "gh", "auth", "status", "-h", cfg.Host)
+		if output, err := cmd.CombinedOutput(); err != nil {
+			report.Warn(label("gh auth"), fmt.Sprintf("not logged in to %s: %s", cfg.Host, firstLine(string(output))))
+		} else {
+			ghAuthed = true
+			report.OK(label("gh auth"), "logged in to "+cfg.Host)
+		}
+	}
+
+	tokenHint := "WAYBAR_GITHUB_TOKEN/GITHUB_TOKEN not set and none in keyring"
+	authHint := "no usable auth; run `gh auth login`, set WAYBAR_GITHUB_TOKEN or run `waybar-github store-token`"
+	if labelHost {
+		tokenHint = "no token configured for this host and none in keyring"
+		authHint = "no usable auth; run `gh auth login -h " + cfg.Host + "` or configure a token for this host"
+	}
+
+	resolved := withKeyringToken(ctx, cfg)
+	switch {
+	case cfg.Token != "":
+		report.OK(label("token"), "set in environment")
+	case resolved.Token != "":
+		report.OK(label("token"), "found in keyring ("+secrets.FormatAttributes(cfg.SecretAttributes)+")")
+	default:
+		report.Warn(label("token"), tokenHint)
+	}
+	if !ghAuthed && resolved.Token == "" {
+		report.Fail(label("auth"), authHint)
+	}
+}
+
+func checkOpener(report *doctor.Report, cfg config.Runtime, purpose string) {
+	if rules, err := opener.LoadRules(cfg.Opener.RulesFile); err != nil {
+		report.Fail("opener rules", err.Error())
+	} else if len(rules) > 0 {
+		report.OK("opener rules", fmt.Sprintf("%d rule(s) from %s", len(rules), cfg.Opener.RulesFile))
+	}
+	command, err := opener.Describe(cfg.Opener)
+	if err != nil {
+		report.Fail("url opener", err.Error()+" (needed to "+purpose+")")
+		return
+	}
+	report.OK("url opener", command)
+}
+
+func searchNames(searches []config.Search) string {
+	names := make([]string, 0, len(searches))
+	for _, search := range searches {
+		names = append(names, search.Name)
+	}
+	return strings.Join(names, ", ")
+}
+
+func watchNames(watches []config.Watch) string {
+	names := make([]string, 0, len(watches))
+	for _, watch := range watches {
+		names = append(names, watch.Repository+"@"+fallbackString(watch.Branch, "default"))
+	}
+	return strings.Join(names, ", ")
+}
+
+func firstLine(value string) string {
+	line, _, _ := strings.Cut(strings.TrimSpace(value), "\n")
+	return strings.TrimSpace(line)
+}
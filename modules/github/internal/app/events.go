@@ -0,0 +1,235 @@
+package app
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"os"
+	"os/exec"
+	"syscall"
+	"time"
+
+	"github.com/rbright/waybar-github/internal/config"
+	"github.com/rbright/waybar-github/internal/desktop"
+	"github.com/rbright/waybar-github/internal/github"
+	"github.com/rbright/waybar-github/internal/state"
+	"github.com/rbright/waybar-shared/itemref"
+)
+
+const (
+	desktopNotifyCommand = "desktop-notify"
+	seenMaxAge           = 30 * 24 * time.Hour
+	// maxMergeLookups bounds the follow-up query for pull requests that left the list.
+	maxMergeLookups = 20
+)
+
+type prEvent struct {
+	summary string
+	body    string
+	url     string
+	// action, when set, is an item action offered as a button, run on the item
+	// with key when clicked.
+	action string
+	key    string
+}
+
+// spawnNotification is replaced in tests, which must not start detached copies
+// of the test binary.
+var spawnNotification = spawnDesktopNotification
+
+// notifyChanges raises desktop notifications for what changed since the
+// previous fetch. It is best-effort: failures are logged and never fail the poll.
+// Hosts that failed this round are left out, so their state is compared next time.
+func notifyChanges(ctx context.Context, cfg config.Runtime, fetches []hostFetch, previous []github.PullRequest) {
+	seen, existed, err := state.LoadSeen(cfg.SeenPath)
+	if err != nil {
+		slog.Warn("load notification seen-set failed", "error", err)
+		return
+	}
+
+	var current, merged []github.PullRequest
+	for _, fetch := range fetches {
+		if fetch.err != nil {
+			continue
+		}
+		current = append(current, fetch.result.Items...)
+		gone := goneAuthoredIDs(hostItems(previous, fetch.cfg.Host), fetch.result.Items)
+		hostMerged, err := github.FetchMergedPullRequests(ctx, fetch.cfg, fetch.authMode, gone)
+		if err != nil {
+			slog.Warn("github merge lookup failed", "host", fetch.cfg.Host, "error", err)
+		}
+		merged = append(merged, hostMerged...)
+	}
+
+	now := time.Now().UTC()
+	events := prEvents(current, merged, seen, now)
+	seen.Prune(now, seenMaxAge)
+	if err := state.SaveSeen(cfg.SeenPath, seen); err != nil {
+		slog.Warn("save notification seen-set failed", "error", err)
+	}
+
+	// The first run only records the current state.
+	if !existed {
+		return
+	}
+	for _, event := range events {
+		if err := spawnNotification(event); err != nil {
+			slog.Warn("desktop notification failed", "summary", event.summary, "error", err)
+		}
+	}
+}
+
+// prEvents updates seen in place and returns the events it had not recorded yet.
+// Condition keys (review, ci) are dropped when the condition clears, so a second
+// review request or a second red build notifies again.
+func prEvents(current, merged []github.PullRequest, seen state.Seen, now time.Time) []prEvent {
+	var events []prEvent
+	mark := func(key, value string, event prEvent) {
+		if _, ok := seen[key]; !ok {
+			events = append(events, event)
+		}
+		seen[key] = state.SeenMark{Value: value, At: now}
+	}
+
+	visited := make(map[string]bool)
+	for _, item := range current {
+		if item.ID == "" || visited[item.ID] {
+			continue
+		}
+		visited[item.ID] = true
+		body := fmt.Sprintf("%s #%d: %s", item.Repository, item.Number, item.Title)
+
+		reviewKey := "review:" + item.ID
+		if item.ReviewRequested {
+			mark(reviewKey, "", prEvent{summary: "Review requested", body: body, url: item.URL})
+		} else {
+			delete(seen, reviewKey)
+		}
+
+		if !item.Authored {
+			continue
+		}
+
+		ciKey := "ci:" + item.ID
+		if item.CIFailing() {
+			mark(ciKey, "", prEvent{summary: "CI failed", body: body, url: item.URL})
+		} else {
+			delete(seen, ciKey)
+		}
+
+		// The mark holds the newest comment or review by someone else; marks
+		// from before that was tracked hold a count and only record the time.
+		commentsKey := "comments:" + item.ID
+		var latest time.Time
+		for _, at := range item.CommentedAt {
+			if at.After(latest) {
+				latest = at
+			}
+		}
+		if previous, ok := seen[commentsKey]; ok {
+			if since, err := time.Parse(time.RFC3339Nano, previous.Value); err == nil {
+				if added := countAfter(item.CommentedAt, since); added > 0 {
+					events = append(events, prEvent{
+						summary: fmt.Sprintf("%d new comment(s)", added),
+						body:    body,
+						url:     item.URL,
+					})
+				}
+			}
+		}
+		seen[commentsKey] = state.SeenMark{Value: latest.Format(time.RFC3339Nano), At: now}
+	}
+
+	for _, item := range merged {
+		body := fmt.Sprintf("%s #%d: %s", item.Repository, item.Number, item.Title)
+		mark("merged:"+item.ID, "", prEvent{summary: "Pull request merged", body: body, url: item.URL})
+	}
+	return events
+}
+
+func countAfter(times []time.Time, since time.Time) int {
+	count := 0
+	for _, at := range times {
+		if at.After(since) {
+			count++
+		}
+	}
+	return count
+}
+
+// hostItems keeps the items fetched from host; items saved before hosts were
+// recorded belong to every host.
+func hostItems(items []github.PullRequest, host string) []github.PullRequest {
+	var filtered []github.PullRequest
+	for _, item := range items {
+		if item.Host == "" || item.Host == host {
+			filtered = append(filtered, item)
+		}
+	}
+	return filtered
+}
+
+func goneAuthoredIDs(previous, current []github.PullRequest) []string {
+	present := make(map[string]bool, len(current))
+	for _, item := range current {
+		present[item.ID] = true
+	}
+
+	var gone []string
+	for _, item := range previous {
+		if item.Authored && item.IsPullRequest() && item.ID != "" && !present[item.ID] && len(gone) < maxMergeLookups {
+			present[item.ID] = true
+			gone = append(gone, item.ID)
+		}
+	}
+	return gone
+}
+
+// spawnDesktopNotification hands the notification to a detached copy of this
+// binary, which waits for a click long after a one-shot status poll has exited.
+func spawnDesktopNotification(event prEvent) error {
+	executable, err := os.Executable()
+	if err != nil {
+		return fmt.Errorf("resolve executable: %w", err)
+	}
+
+	args := []string{desktopNotifyCommand, event.summary, event.body, event.url}
+	if event.action != "" {
+		// Item actions only exist in pull request mode, whatever the environment selects.
+		args = append([]string{config.ModePullRequests}, append(args, event.action, "id:"+event.key)...)
+	}
+	cmd := exec.Command(executable, args...)
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setsid: true}
+	if err := cmd.Start(); err != nil {
+		return fmt.Errorf("start notifier: %w", err)
+	}
+	return cmd.Process.Release()
+}
+
+func runDesktopNotify(ctx context.Context, cfg config.Runtime, event prEvent) error {
+	actions := []string{desktop.DefaultAction, "Open"}
+	if event.action != "" {
+		actions = append(actions, event.action, actionConfirmations[event.action].button)
+	}
+	action, err := desktop.Show(ctx, desktop.Notification{
+		AppName: "Waybar GitHub",
+		Summary: event.summary,
+		Body:    event.body,
+		Icon:    "github",
+		Actions: actions,
+	})
+	if err != nil {
+		return err
+	}
+	if event.action != "" && action == event.action {
+		// The click can come long after the notification was shown, so the
+		// action gets the time it would have had when run directly.
+		actionCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), Timeout(cfg, []string{event.action}))
+		defer cancel()
+		return runItemAction(actionCtx, cfg, event.action, itemref.Ref{Key: event.key})
+	}
+	if action != desktop.DefaultAction || event.url == "" {
+		return nil
+	}
+	return openURL(ctx, cfg, event.url)
+}
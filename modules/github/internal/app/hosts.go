@@ -0,0 +1,159 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/rbright/waybar-github/internal/config"
+	"github.com/rbright/waybar-github/internal/github"
+	"github.com/rbright/waybar-github/internal/state"
+	"github.com/rbright/waybar-shared/network"
+)
+
+var errNoAuth = errors.New("no github auth available")
+
+// hostFetch is one host's share of a refresh.
+type hostFetch struct {
+	cfg      config.Runtime
+	authMode github.AuthMode
+	result   github.FetchResult
+	err      error
+	// limitedUntil is set when the host was skipped or answered with a rate limit.
+	limitedUntil time.Time
+}
+
+// fetchHosts fetches every configured host concurrently, in configuration order.
+func fetchHosts(ctx context.Context, cfg config.Runtime, now time.Time) []hostFetch {
+	fetches := make([]hostFetch, len(cfg.Hosts))
+	var wg sync.WaitGroup
+	for i, host := range cfg.Hosts {
+		wg.Go(func() {
+			fetches[i] = fetchHost(ctx, cfg.ForHost(host), now)
+		})
+	}
+	wg.Wait()
+	return fetches
+}
+
+func fetchHost(ctx context.Context, cfg config.Runtime, now time.Time) hostFetch {
+	limits, err := state.LoadRateLimit(cfg.RateLimitPath)
+	if err != nil {
+		return hostFetch{cfg: cfg, err: err}
+	}
+	if wait := limits.Wait(now, cfg.RateLimitReserve); wait > 0 {
+		slog.Info("github rate limit budget low; skipping fetch", "host", cfg.Host, "remaining", limits.Remaining, "wait", wait)
+		return hostFetch{cfg: cfg, err: errors.New("rate limited"), limitedUntil: now.Add(wait)}
+	}
+
+	cfg, authMode := resolveAuth(ctx, cfg)
+	if authMode == github.AuthNone {
+		slog.Warn("no github auth available", "host", cfg.Host)
+		return hostFetch{cfg: cfg, authMode: authMode, err: errNoAuth}
+	}
+
+	result, err := github.FetchPullRequests(ctx, cfg, authMode)
+	if err != nil {
+		slog.Warn("github fetch failed", "host", cfg.Host, "mode", authMode, "error", err)
+		fetch := hostFetch{cfg: cfg, authMode: authMode, err: err}
+		if limitErr, ok := github.AsRateLimit(err); ok {
+			limits.Block(now, limitErr.RetryAfter)
+			if saveErr := state.SaveRateLimit(cfg.RateLimitPath, limits); saveErr != nil {
+				slog.Warn("save rate limit failed", "host", cfg.Host, "error", saveErr)
+			}
+			fetch.limitedUntil = limits.BlockedUntil
+		}
+		return fetch
+	}
+
+	if result.RateLimit != nil {
+		limits = state.RateLimit{RateLimit: *result.RateLimit, CheckedAt: now}
+		if err := state.SaveRateLimit(cfg.RateLimitPath, limits); err != nil {
+			slog.Warn("save rate limit failed", "host", cfg.Host, "error", err)
+		}
+	}
+	return hostFetch{cfg: cfg, authMode: authMode, result: result}
+}
+
+// failureReason is the degraded-section note for a failed host.
+func (f hostFetch) failureReason() string {
+	switch {
+	case !f.limitedUntil.IsZero():
+		return rateLimitedReason(f.limitedUntil)
+	case errors.Is(f.err, errNoAuth):
+		return "Not signed in; run 'gh auth login -h " + f.cfg.Host + "'"
+	default:
+		return "Refresh failed: " + f.err.Error()
+	}
+}
+
+// mergeFetches combines the hosts into one result and the meta to save with it.
+// A failed host keeps its last good sections (within the stale window, or while
+// rate limited) or an empty section per search, each host's first section
+// carrying the failure reason.
+func mergeFetches(cfg config.Runtime, fetches []hostFetch, previous state.Meta, previousItems []github.PullRequest, now time.Time) (github.FetchResult, state.Meta, bool) {
+	merged := github.FetchResult{Items: []github.PullRequest{}}
+	meta := state.Meta{FetchedAt: now, HostFetchedAt: make(map[string]time.Time, len(fetches)), HostCount: make(map[string]int, len(fetches))}
+	degraded := false
+
+	for _, fetch := range fetches {
+		host := fetch.cfg.Host
+		if fetch.err == nil {
+			merged.Items = append(merged.Items, fetch.result.Items...)
+			merged.Searches = append(merged.Searches, fetch.result.Searches...)
+			merged.Count += fetch.result.Count
+			meta.HostFetchedAt[host] = now
+			meta.HostCount[host] = fetch.result.Count
+			continue
+		}
+
+		degraded = true
+		var searches []github.SearchCount
+		last := previous.HostFetchedAt[host]
+		if !fetch.limitedUntil.IsZero() || network.WithinStaleAge(last, cfg.StaleAfter) {
+			for _, search := range previous.Searches {
+				if search.Host == host {
+					searches = append(searches, search)
+				}
+			}
+			for _, item := range previousItems {
+				if item.Host == host {
+					merged.Items = append(merged.Items, item)
+				}
+			}
+			if len(searches) > 0 {
+				meta.HostFetchedAt[host] = last
+				meta.HostCount[host] = previousCount(previous, host, searches)
+			}
+		}
+		if len(searches) == 0 {
+			for _, search := range fetch.cfg.Searches {
+				searches = append(searches, github.SearchCount{Name: search.Name, Host: host})
+			}
+		}
+
+		for i := range searches {
+			searches[i].Error = ""
+		}
+		searches[0].Error = fetch.failureReason()
+		merged.Searches = append(merged.Searches, searches...)
+		merged.Count += meta.HostCount[host]
+	}
+	meta.Count, meta.Searches = merged.Count, merged.Searches
+	return merged, meta, degraded
+}
+
+// previousCount is the host's count from the last good fetch. Meta saved before
+// per-host counts has only the sections, whose largest count is a lower bound.
+func previousCount(previous state.Meta, host string, searches []github.SearchCount) int {
+	if count, ok := previous.HostCount[host]; ok {
+		return count
+	}
+	count := 0
+	for _, search := range searches {
+		count = max(count, search.Count)
+	}
+	return count
+}
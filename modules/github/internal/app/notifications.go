@@ -0,0 +1,266 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/rbright/waybar-github/internal/config"
+	"github.com/rbright/waybar-github/internal/github"
+	"github.com/rbright/waybar-github/internal/state"
+	"github.com/rbright/waybar-github/internal/waybar"
+	"github.com/rbright/waybar-shared/itemref"
+	"github.com/rbright/waybar-shared/network"
+)
+
+func buildNotificationsStatus(ctx context.Context, cfg config.Runtime) (waybar.Output, error) {
+	meta, err := state.LoadNotificationsMeta(cfg.MetaPath)
+	if err != nil {
+		return waybar.Output{}, err
+	}
+
+	if cfg.NetworkCheck && !network.Online(ctx, cfg.APIURL) {
+		slog.Info("offline; skipping github notifications fetch")
+		return renderNotifications(cfg, meta, "offline", "Offline")
+	}
+
+	// GitHub asks clients not to poll faster than the X-Poll-Interval it sent last.
+	if wait := time.Duration(meta.PollInterval) * time.Second; wait > 0 && time.Since(meta.CheckedAt) < wait {
+		slog.Debug("github notifications poll interval not elapsed", "interval", wait)
+		return renderNotifications(cfg, meta, "", "")
+	}
+
+	// REST has its own budget, shared with the actions mode.
+	limits, err := state.LoadRateLimit(cfg.RateLimitPath)
+	if err != nil {
+		return waybar.Output{}, err
+	}
+	if time.Now().Before(limits.BlockedUntil) {
+		slog.Info("github rate limited; skipping notifications fetch", "until", limits.BlockedUntil)
+		return renderNotifications(cfg, meta, "rate-limited", rateLimitedReason(limits.BlockedUntil))
+	}
+
+	cfg, authMode := resolveAuth(ctx, cfg)
+	if authMode == github.AuthNone {
+		slog.Warn("no github auth available", "host", cfg.Host)
+		statusLine := "Run 'gh auth login', set GITHUB_TOKEN or run waybar-github store-token"
+		if _, err := state.SaveNotifications(cfg.ItemsPath, []github.Notification{}); err != nil {
+			return waybar.Output{}, err
+		}
+		if err := state.WriteNotificationsMenu(cfg.MenuPath, state.NotificationsMenuData{StatusLine: statusLine}); err != nil {
+			return waybar.Output{}, err
+		}
+		return waybar.Output{
+			Text:    "?",
+			Tooltip: statusLine,
+			Class:   "unknown",
+		}, nil
+	}
+
+	lastModified := ""
+	if !meta.FetchedAt.IsZero() {
+		lastModified = meta.LastModified
+	}
+	result, err := github.FetchNotifications(ctx, cfg, authMode, lastModified)
+	if err != nil {
+		slog.Warn("github notifications fetch failed", "mode", authMode, "error", err)
+		if limitErr, ok := github.AsRateLimit(err); ok {
+			limits.Block(time.Now().UTC(), limitErr.RetryAfter)
+			if saveErr := state.SaveRateLimit(cfg.RateLimitPath, limits); saveErr != nil {
+				return waybar.Output{}, saveErr
+			}
+			return renderNotifications(cfg, meta, "rate-limited", rateLimitedReason(limits.BlockedUntil))
+		}
+		// Keep the last good items and menu through transient failures.
+		if network.WithinStaleAge(meta.FetchedAt, cfg.StaleAfter) {
+			return renderNotifications(cfg, meta, "stale", "Refresh failed: "+err.Error())
+		}
+		statusLine := "GitHub API request failed"
+		if _, saveErr := state.SaveNotifications(cfg.ItemsPath, []github.Notification{}); saveErr != nil {
+			return waybar.Output{}, saveErr
+		}
+		if metaErr := state.SaveNotificationsMeta(cfg.MetaPath, state.NotificationsMeta{}); metaErr != nil {
+			return waybar.Output{}, metaErr
+		}
+		if menuErr := state.WriteNotificationsMenu(cfg.MenuPath, state.NotificationsMenuData{StatusLine: statusLine}); menuErr != nil {
+			return waybar.Output{}, menuErr
+		}
+		return waybar.Output{
+			Text:    "!",
+			Tooltip: fmt.Sprintf("GitHub notifications: %s", err.Error()),
+			Class:   "error",
+		}, nil
+	}
+
+	now := time.Now().UTC()
+	meta.LastModified = result.LastModified
+	meta.PollInterval = int(result.PollInterval / time.Second)
+	meta.CheckedAt = now
+	meta.FetchedAt = now
+	if !result.NotModified {
+		meta.More = result.More
+		slots, err := state.SaveNotifications(cfg.ItemsPath, result.Items)
+		if err != nil {
+			return waybar.Output{}, err
+		}
+		if err := state.WriteNotificationsMenu(cfg.MenuPath, state.NotificationsMenuData{Items: result.Items, Limit: cfg.MaxItems, Slots: slots}); err != nil {
+			return waybar.Output{}, err
+		}
+	}
+	if err := state.SaveNotificationsMeta(cfg.MetaPath, meta); err != nil {
+		return waybar.Output{}, err
+	}
+
+	return renderNotifications(cfg, meta, "", "")
+}
+
+// renderNotifications renders the saved items. A className marks cached output
+// (offline, stale) and reason explains why in the tooltip.
+func renderNotifications(cfg config.Runtime, meta state.NotificationsMeta, className, reason string) (waybar.Output, error) {
+	if className != "" && meta.FetchedAt.IsZero() {
+		return waybar.Output{
+			Text:    "?",
+			Tooltip: fmt.Sprintf("GitHub notifications: %s; nothing cached yet", reason),
+			Class:   className,
+		}, nil
+	}
+
+	items, _, err := state.LoadNotifications(cfg.ItemsPath)
+	if err != nil {
+		return waybar.Output{}, err
+	}
+
+	count := strconv.Itoa(len(items))
+	if meta.More {
+		count += "+"
+	}
+
+	lines := []string{"GitHub notifications: " + count}
+	if summary := reasonSummary(items); summary != "" {
+		lines = append(lines, summary)
+	}
+	for idx, item := range items {
+		if idx == cfg.MaxItems {
+			lines = append(lines, fmt.Sprintf("… and %d more", len(items)-idx))
+			break
+		}
+		lines = append(lines, fmt.Sprintf("%s: %s", item.Repository, item.Title))
+	}
+
+	if className == "" {
+		lines = append(lines, "Click to open dropdown")
+		className = "clear"
+		if len(items) > 0 {
+			className = "normal"
+		}
+	} else {
+		lines = append(lines, "", network.CachedNote(reason, meta.FetchedAt))
+	}
+
+	return waybar.Output{
+		Text:    count,
+		Tooltip: strings.Join(lines, "\n"),
+		Class:   className,
+	}, nil
+}
+
+// reasonSummary counts items per notification reason, e.g. "Review requested: 2 · Mention: 1".
+func reasonSummary(items []github.Notification) string {
+	var order []string
+	counts := make(map[string]int)
+	for _, item := range items {
+		label := state.ReasonLabel(item.Reason)
+		if counts[label] == 0 {
+			order = append(order, label)
+		}
+		counts[label]++
+	}
+
+	parts := make([]string, 0, len(order))
+	for _, label := range order {
+		parts = append(parts, fmt.Sprintf("%s: %d", label, counts[label]))
+	}
+	return strings.Join(parts, " · ")
+}
+
+func openNotification(ctx context.Context, cfg config.Runtime, ref itemref.Ref) error {
+	item, ok, err := state.ResolveNotification(cfg.ItemsPath, ref)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return itemref.ErrStale
+	}
+
+	if url := strings.TrimSpace(item.URL); url != "" {
+		if err := openURL(ctx, cfg, url); err != nil {
+			return err
+		}
+	}
+
+	cfg, authMode := resolveAuth(ctx, cfg)
+	if authMode == github.AuthNone || item.ID == "" {
+		return nil
+	}
+	if err := github.MarkThreadRead(ctx, cfg, authMode, item.ID); err != nil {
+		slog.Warn("github mark thread read failed", "id", item.ID, "error", err)
+		return nil
+	}
+
+	// Drop the thread locally; the next conditional poll may well be a 304.
+	items, _, err := state.LoadNotifications(cfg.ItemsPath)
+	if err != nil {
+		return err
+	}
+	remaining := make([]github.Notification, 0, len(items))
+	for _, existing := range items {
+		if existing.ID != item.ID {
+			remaining = append(remaining, existing)
+		}
+	}
+	if err := saveNotificationState(cfg, remaining); err != nil {
+		return err
+	}
+	signalBar(cfg)
+	return nil
+}
+
+func markAllRead(ctx context.Context, cfg config.Runtime) error {
+	if cfg.Mode != config.ModeNotifications {
+		return errors.New("mark-all-read is only available in notifications mode (waybar-github notifications mark-all-read)")
+	}
+
+	cfg, authMode := resolveAuth(ctx, cfg)
+	if authMode == github.AuthNone {
+		return nil
+	}
+	if err := github.MarkAllNotificationsRead(ctx, cfg, authMode, time.Now()); err != nil {
+		return err
+	}
+
+	if err := saveNotificationState(cfg, []github.Notification{}); err != nil {
+		return err
+	}
+	signalBar(cfg)
+	return nil
+}
+
+func saveNotificationState(cfg config.Runtime, items []github.Notification) error {
+	slots, err := state.SaveNotifications(cfg.ItemsPath, items)
+	if err != nil {
+		return err
+	}
+	meta, err := state.LoadNotificationsMeta(cfg.MetaPath)
+	if err != nil {
+		return err
+	}
+	meta.More = meta.More && len(items) > 0
+	if err := state.SaveNotificationsMeta(cfg.MetaPath, meta); err != nil {
+		return err
+	}
+	return state.WriteNotificationsMenu(cfg.MenuPath, state.NotificationsMenuData{Items: items, Limit: cfg.MaxItems, Slots: slots})
+}
@@ -0,0 +1,395 @@
+package app
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"net/url"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/rbright/waybar-github/internal/config"
+	"github.com/rbright/waybar-github/internal/github"
+	"github.com/rbright/waybar-github/internal/state"
+	"github.com/rbright/waybar-github/internal/waybar"
+	"github.com/rbright/waybar-shared/itemref"
+	"github.com/rbright/waybar-shared/network"
+)
+
+func buildActionsStatus(ctx context.Context, cfg config.Runtime) (waybar.Output, error) {
+	meta, err := state.LoadActionsMeta(cfg.MetaPath)
+	if err != nil {
+		return waybar.Output{}, err
+	}
+
+	if len(cfg.Watches) == 0 {
+		return actionsUnavailable(cfg, "No watched branches; set WAYBAR_GITHUB_WATCH")
+	}
+
+	if cfg.NetworkCheck && !network.Online(ctx, cfg.APIURL) {
+		slog.Info("offline; skipping github actions fetch")
+		return renderWorkflowRuns(cfg, meta, "offline", "Offline")
+	}
+
+	limits, err := state.LoadRateLimit(cfg.RateLimitPath)
+	if err != nil {
+		return waybar.Output{}, err
+	}
+	if time.Now().Before(limits.BlockedUntil) {
+		slog.Info("github rate limited; skipping actions fetch", "until", limits.BlockedUntil)
+		return renderWorkflowRuns(cfg, meta, "rate-limited", rateLimitedReason(limits.BlockedUntil))
+	}
+
+	cfg, authMode := resolveAuth(ctx, cfg)
+	if authMode == github.AuthNone {
+		slog.Warn("no github auth available", "host", cfg.Host)
+		return actionsUnavailable(cfg, "Run 'gh auth login', set GITHUB_TOKEN or run waybar-github store-token")
+	}
+
+	branches, runs, defaults, err := fetchWatchedBranches(ctx, cfg, authMode, meta.DefaultBranches)
+	if err != nil {
+		slog.Warn("github actions fetch failed", "mode", authMode, "error", err)
+		if limitErr, ok := github.AsRateLimit(err); ok {
+			limits.Block(time.Now().UTC(), limitErr.RetryAfter)
+			if saveErr := state.SaveRateLimit(cfg.RateLimitPath, limits); saveErr != nil {
+				return waybar.Output{}, saveErr
+			}
+			return renderWorkflowRuns(cfg, meta, "rate-limited", rateLimitedReason(limits.BlockedUntil))
+		}
+		// Keep the last good runs and menu through transient failures.
+		if network.WithinStaleAge(meta.FetchedAt, cfg.StaleAfter) {
+			return renderWorkflowRuns(cfg, meta, "stale", "Refresh failed: "+err.Error())
+		}
+		if _, saveErr := state.SaveWorkflowRuns(cfg.ItemsPath, []github.WorkflowRun{}); saveErr != nil {
+			return waybar.Output{}, saveErr
+		}
+		if metaErr := state.SaveActionsMeta(cfg.MetaPath, state.ActionsMeta{DefaultBranches: defaults}); metaErr != nil {
+			return waybar.Output{}, metaErr
+		}
+		if menuErr := state.WriteActionsMenu(cfg.MenuPath, state.ActionsMenuData{StatusLine: "GitHub API request failed"}); menuErr != nil {
+			return waybar.Output{}, menuErr
+		}
+		return waybar.Output{
+			Text:    "!",
+			Tooltip: fmt.Sprintf("GitHub Actions: %s", err.Error()),
+			Class:   "error",
+		}, nil
+	}
+
+	if cfg.DesktopNotifications {
+		notifyRedBranches(cfg, branches, runs)
+	}
+
+	now := time.Now().UTC()
+	meta = state.ActionsMeta{Branches: branches, FetchedAt: now, DefaultBranches: defaults}
+	slots, err := state.SaveWorkflowRuns(cfg.ItemsPath, runs)
+	if err != nil {
+		return waybar.Output{}, err
+	}
+	if err := state.SaveActionsMeta(cfg.MetaPath, meta); err != nil {
+		return waybar.Output{}, err
+	}
+	if err := state.WriteActionsMenu(cfg.MenuPath, state.ActionsMenuData{Branches: branches, Runs: runs, Now: now, Slots: slots}); err != nil {
+		return waybar.Output{}, err
+	}
+	return renderWorkflowRuns(cfg, meta, "", "")
+}
+
+func actionsUnavailable(cfg config.Runtime, statusLine string) (waybar.Output, error) {
+	if _, err := state.SaveWorkflowRuns(cfg.ItemsPath, []github.WorkflowRun{}); err != nil {
+		return waybar.Output{}, err
+	}
+	if err := state.WriteActionsMenu(cfg.MenuPath, state.ActionsMenuData{StatusLine: statusLine}); err != nil {
+		return waybar.Output{}, err
+	}
+	return waybar.Output{
+		Text:    "?",
+		Tooltip: statusLine,
+		Class:   "unknown",
+	}, nil
+}
+
+// fetchWatchedBranches fetches every watched branch concurrently. A branch that
+// fails only marks its own section; the refresh fails when all of them do, or
+// on a rate limit, which applies to the rest as well.
+func fetchWatchedBranches(ctx context.Context, cfg config.Runtime, authMode github.AuthMode, cachedDefaults map[string]string) ([]github.WatchedBranch, []github.WorkflowRun, map[string]string, error) {
+	branches := make([]github.WatchedBranch, len(cfg.Watches))
+	branchRuns := make([][]github.WorkflowRun, len(cfg.Watches))
+	errs := make([]error, len(cfg.Watches))
+	resolved := make([]string, len(cfg.Watches))
+
+	var wg sync.WaitGroup
+	for i, watch := range cfg.Watches {
+		wg.Go(func() {
+			branch := watch.Branch
+			if branch == "" {
+				branch = cachedDefaults[watch.Repository]
+			}
+			if branch == "" {
+				branch, errs[i] = github.DefaultBranch(ctx, cfg, authMode, watch.Repository)
+				if errs[i] != nil {
+					branches[i] = github.WatchedBranch{Repository: watch.Repository, Branch: "default branch", Error: errs[i].Error()}
+					return
+				}
+				resolved[i] = branch
+			}
+			branches[i] = github.WatchedBranch{Repository: watch.Repository, Branch: branch}
+			branchRuns[i], errs[i] = github.FetchWorkflowRuns(ctx, cfg, authMode, watch.Repository, branch)
+			if errs[i] != nil {
+				branches[i].Error = errs[i].Error()
+			}
+		})
+	}
+	wg.Wait()
+
+	defaults := make(map[string]string)
+	for i, watch := range cfg.Watches {
+		if watch.Branch != "" {
+			continue
+		}
+		if branch := fallbackString(resolved[i], cachedDefaults[watch.Repository]); branch != "" {
+			defaults[watch.Repository] = branch
+		}
+	}
+
+	var runs []github.WorkflowRun
+	failed := 0
+	for i, err := range errs {
+		if _, ok := github.AsRateLimit(err); ok {
+			return nil, nil, defaults, err
+		}
+		if err != nil {
+			failed++
+			slog.Warn("github workflow runs fetch failed", "branch", branches[i].Label(), "error", err)
+			continue
+		}
+		runs = append(runs, sortRuns(branchRuns[i])...)
+	}
+	if failed == len(errs) {
+		return nil, nil, defaults, errs[0]
+	}
+	return branches, runs, defaults, nil
+}
+
+// sortRuns lists failing runs first, then running ones, then the rest, each in
+// workflow order.
+func sortRuns(runs []github.WorkflowRun) []github.WorkflowRun {
+	rank := func(run github.WorkflowRun) int {
+		switch {
+		case run.Failed():
+			return 0
+		case run.Running():
+			return 1
+		default:
+			return 2
+		}
+	}
+	slices.SortStableFunc(runs, func(a, b github.WorkflowRun) int { return rank(a) - rank(b) })
+	return runs
+}
+
+// renderWorkflowRuns renders the saved runs. A className marks cached output
+// (offline, stale, rate-limited) and reason explains why in the tooltip.
+func renderWorkflowRuns(cfg config.Runtime, meta state.ActionsMeta, className, reason string) (waybar.Output, error) {
+	if className != "" && meta.FetchedAt.IsZero() {
+		return waybar.Output{
+			Text:    "?",
+			Tooltip: fmt.Sprintf("GitHub Actions: %s; nothing cached yet", reason),
+			Class:   className,
+		}, nil
+	}
+
+	runs, _, err := state.LoadWorkflowRuns(cfg.ItemsPath)
+	if err != nil {
+		return waybar.Output{}, err
+	}
+
+	failing, running, degraded := 0, 0, false
+	for _, run := range runs {
+		switch {
+		case run.Failed():
+			failing++
+		case run.Running():
+			running++
+		}
+	}
+
+	lines := []string{"GitHub Actions: " + actionsSummary(failing, running, len(runs))}
+	for _, branch := range meta.Branches {
+		if branch.Error != "" {
+			degraded = true
+			lines = append(lines, fmt.Sprintf("⚠ %s: %s", branch.Label(), branch.Error))
+			continue
+		}
+		var parts []string
+		passing := 0
+		for _, run := range runs {
+			switch {
+			case !branch.Matches(run):
+			case run.Failed():
+				parts = append(parts, fmt.Sprintf("✗ %s #%d", run.Workflow, run.RunNumber))
+			case run.Running():
+				parts = append(parts, fmt.Sprintf("● %s #%d", run.Workflow, run.RunNumber))
+			default:
+				passing++
+			}
+		}
+		if passing > 0 {
+			parts = append(parts, fmt.Sprintf("✓ %d passing", passing))
+		}
+		if len(parts) == 0 {
+			parts = append(parts, "no workflow runs")
+		}
+		lines = append(lines, fmt.Sprintf("%s: %s", branch.Label(), strings.Join(parts, ", ")))
+	}
+
+	text, stateClass := "✓", "ci-passing"
+	switch {
+	case failing > 0:
+		text, stateClass = "✗", "ci-failing"
+	case running > 0:
+		text, stateClass = "●", "ci-pending"
+	case len(runs) == 0:
+		text, stateClass = "–", "clear"
+	}
+
+	if className == "" {
+		lines = append(lines, "Click to open dropdown")
+		className = stateClass
+		if degraded {
+			className += " degraded"
+		}
+	} else {
+		lines = append(lines, "", network.CachedNote(reason, meta.FetchedAt))
+		className += " " + stateClass
+	}
+
+	return waybar.Output{
+		Text:    text,
+		Tooltip: strings.Join(lines, "\n"),
+		Class:   className,
+	}, nil
+}
+
+// actionsSummary counts workflows by state, e.g. "1 failing, 2 running" or "all 5 passing".
+func actionsSummary(failing, running, total int) string {
+	var parts []string
+	if failing > 0 {
+		parts = append(parts, fmt.Sprintf("%d failing", failing))
+	}
+	if running > 0 {
+		parts = append(parts, fmt.Sprintf("%d running", running))
+	}
+	switch {
+	case len(parts) > 0:
+		return strings.Join(parts, ", ")
+	case total == 0:
+		return "no workflow runs"
+	default:
+		return fmt.Sprintf("all %d passing", total)
+	}
+}
+
+// notifyRedBranches raises a desktop notification when a watched branch turns
+// red. The mark is dropped once the branch is green again, so the next failure
+// notifies again. Branches that failed to fetch keep their mark.
+func notifyRedBranches(cfg config.Runtime, branches []github.WatchedBranch, runs []github.WorkflowRun) {
+	seen, existed, err := state.LoadSeen(cfg.SeenPath)
+	if err != nil {
+		slog.Warn("load notification seen-set failed", "error", err)
+		return
+	}
+
+	now := time.Now().UTC()
+	var events []prEvent
+	for _, branch := range branches {
+		if branch.Error != "" {
+			continue
+		}
+		key := "red:" + branch.Label()
+		var failed []github.WorkflowRun
+		for _, run := range runs {
+			if branch.Matches(run) && run.Failed() {
+				failed = append(failed, run)
+			}
+		}
+		if len(failed) == 0 {
+			delete(seen, key)
+			continue
+		}
+		if _, ok := seen[key]; !ok {
+			names := make([]string, 0, len(failed))
+			for _, run := range failed {
+				names = append(names, run.Workflow)
+			}
+			events = append(events, prEvent{
+				summary: branch.Label() + " is failing",
+				body:    strings.Join(names, ", ") + " failed: " + failed[0].Title,
+				url:     failed[0].URL,
+			})
+		}
+		seen[key] = state.SeenMark{At: now}
+	}
+
+	seen.Prune(now, seenMaxAge)
+	if err := state.SaveSeen(cfg.SeenPath, seen); err != nil {
+		slog.Warn("save notification seen-set failed", "error", err)
+	}
+
+	// The first run only records the current state.
+	if !existed {
+		return
+	}
+	for _, event := range events {
+		if err := spawnNotification(event); err != nil {
+			slog.Warn("desktop notification failed", "summary", event.summary, "error", err)
+		}
+	}
+}
+
+func openWorkflowRun(ctx context.Context, cfg config.Runtime, ref itemref.Ref) error {
+	run, ok, err := state.ResolveWorkflowRun(cfg.ItemsPath, ref)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return itemref.ErrStale
+	}
+	if link := strings.TrimSpace(run.URL); link != "" {
+		return openURL(ctx, cfg, link)
+	}
+	return nil
+}
+
+// actionsDashboardURL opens the Actions page of the first failing watched
+// branch, or of the first watched branch when none is failing.
+func actionsDashboardURL(cfg config.Runtime) string {
+	// Best-effort: without saved state the first watch is opened.
+	meta, _ := state.LoadActionsMeta(cfg.MetaPath)
+	runs, _, _ := state.LoadWorkflowRuns(cfg.ItemsPath)
+
+	target := github.WatchedBranch{}
+	if len(cfg.Watches) > 0 {
+		target = github.WatchedBranch{Repository: cfg.Watches[0].Repository, Branch: cfg.Watches[0].Branch}
+	}
+	if len(meta.Branches) > 0 {
+		target = meta.Branches[0]
+	}
+	for _, branch := range meta.Branches {
+		if slices.ContainsFunc(runs, func(run github.WorkflowRun) bool { return branch.Matches(run) && run.Failed() }) {
+			target = branch
+			break
+		}
+	}
+
+	if target.Repository == "" {
+		return fmt.Sprintf("https://%s", cfg.Host)
+	}
+	link := fmt.Sprintf("https://%s/%s/actions", cfg.Host, target.Repository)
+	if target.Branch != "" && target.Error == "" {
+		link += "?query=" + url.QueryEscape("branch:"+target.Branch)
+	}
+	return link
+}
@@ -4,16 +4,26 @@ import (
 	"bufio"
 	"fmt"
 	"os"
+	"path"
 	"path/filepath"
+	"slices"
 	"strings"
 	"time"
 
+	"github.com/rbright/waybar-shared/daemon"
+	"github.com/rbright/waybar-shared/opener"
+	"github.com/rbright/waybar-shared/secrets"
 	"github.com/spf13/viper"
 )
 
-const maxActionItems = 12
+const (
+	maxActionItems   = 12
+	maxSearchResults = 1000
+)
 
 type Runtime struct {
+	// Mode selects what the bar tracks: ModePullRequests, ModeNotifications or ModeActions.
+	Mode       string
 	ConfigFile string
 
 	Host       string
@@ -21,16 +31,100 @@ type Runtime struct {
 	GraphQLURL string
 	Token      string
 	PRQuery    string
+	// MaxItems is how many rows the dropdown lists; FetchLimit is how many results
+	// each search pages through for counts and filters.
 	MaxItems   int
+	FetchLimit int
 	Timeout    time.Duration
 
+	// Searches are the named queries fetched together; each becomes a dropdown section.
+	Searches []Search
+	// Hosts are fetched concurrently and merged; the first-class Host above is
+	// always among them and keeps the unprefixed settings.
+	Hosts []Host
+
 	StateDir  string
 	MenuDir   string
 	MenuPath  string
 	ItemsPath string
+	MetaPath  string
+	SeenPath  string
+	// RateLimitPath holds the budget this mode spends on Host. GitHub counts
+	// GraphQL and REST requests separately, so pull requests keep their own file
+	// while notifications and actions share the REST one.
+	RateLimitPath string
+
+	SocketPath     string
+	DaemonInterval time.Duration
+	Signal         int
+
+	// NetworkCheck skips remote fetches while offline and renders cached state instead.
+	NetworkCheck bool
+	// StaleAfter is how long the last good fetch is shown after refresh errors; 0 disables it.
+	StaleAfter time.Duration
+
+	// RateLimitReserve is the GraphQL budget left untouched for other tools sharing the token.
+	RateLimitReserve int
+
+	// Watches are the branches actions mode reports workflow runs for.
+	Watches []Watch
+
+	// Filter hides fetched items by repository, draft state and per-repository cap.
+	Filter Filter
+	// GroupByRepository groups the dropdown by repository instead of by search.
+	GroupByRepository bool
+	// RepositorySubmenus nests each repository's rows in a submenu instead of a section.
+	RepositorySubmenus bool
+	// SortByReviewWait lists pull requests by how long their review requests have
+	// waited, oldest first, instead of in search order.
+	SortByReviewWait bool
+
+	// ReviewSLA is how much work-week time a review request may wait before the
+	// bar raises stale-reviews; 0 disables it.
+	ReviewSLA time.Duration
+	WorkWeek  WorkWeek
+
+	// ItemActions nests copy, checkout, approve and re-run actions under each menu row.
+	ItemActions bool
+	// Clones maps "owner/repo" to a local clone for checkout; repositories missing
+	// from it are looked up by name under CloneRoot.
+	Clones    map[string]string
+	CloneRoot string
+	// CheckoutWorktree checks pull requests out into a new worktree next to the clone.
+	CheckoutWorktree bool
+
+	// DesktopNotifications announces review requests, red CI, new comments and merges.
+	DesktopNotifications bool
+
+	LogLevel string
+	LogPath  string
+
+	Opener opener.Config
+
+	// SecretAttributes locate the token in the Secret Service when none is set in the environment.
+	SecretAttributes map[string]string
 }
 
-func Load() (Runtime, error) {
+const (
+	ModePullRequests  = "pull-requests"
+	ModeNotifications = "notifications"
+	ModeActions       = "actions"
+)
+
+// IsMode reports whether value names a module mode, which may be given as the
+// first argument (`waybar-github notifications status`).
+func IsMode(value string) bool {
+	switch value {
+	case ModePullRequests, ModeNotifications, ModeActions:
+		return true
+	default:
+		return false
+	}
+}
+
+// Load reads the runtime config for mode. An empty mode falls back to
+// WAYBAR_GITHUB_MODE and then to pull requests.
+func Load(mode string) (Runtime, error) {
 	home, err := os.UserHomeDir()
 	if err != nil {
 		return Runtime{}, fmt.Errorf("resolve home dir: %w", err)
@@ -64,19 +158,70 @@ func Load() (Runtime, error) {
 	_ = v.BindEnv("graphql_url", "WAYBAR_GITHUB_GRAPHQL_URL", "GITHUB_GRAPHQL_URL")
 	_ = v.BindEnv("token", "WAYBAR_GITHUB_TOKEN", "GITHUB_TOKEN")
 	_ = v.BindEnv("pr_query", "WAYBAR_GITHUB_PR_QUERY", "PR_QUERY")
+	_ = v.BindEnv("hosts", "WAYBAR_GITHUB_HOSTS")
+	_ = v.BindEnv("searches", "WAYBAR_GITHUB_SEARCHES")
+	_ = v.BindEnv("search_qualifiers", "WAYBAR_GITHUB_SEARCH_QUALIFIERS")
 	_ = v.BindEnv("max_items", "WAYBAR_GITHUB_MAX_ITEMS", "MAX_ITEMS")
+	_ = v.BindEnv("fetch_limit", "WAYBAR_GITHUB_FETCH_LIMIT")
 	_ = v.BindEnv("timeout_seconds", "WAYBAR_GITHUB_TIMEOUT_SECONDS")
 	_ = v.BindEnv("state_dir", "WAYBAR_GITHUB_STATE_DIR")
 	_ = v.BindEnv("menu_dir", "WAYBAR_GITHUB_MENU_DIR")
+	_ = v.BindEnv("socket_path", "WAYBAR_GITHUB_SOCKET_PATH")
+	_ = v.BindEnv("daemon_interval_seconds", "WAYBAR_GITHUB_DAEMON_INTERVAL_SECONDS")
+	_ = v.BindEnv("signal", "WAYBAR_GITHUB_SIGNAL")
+	_ = v.BindEnv("notifications_signal", "WAYBAR_GITHUB_NOTIFICATIONS_SIGNAL")
+	_ = v.BindEnv("actions_signal", "WAYBAR_GITHUB_ACTIONS_SIGNAL")
+	_ = v.BindEnv("watch", "WAYBAR_GITHUB_WATCH")
+	_ = v.BindEnv("mode", "WAYBAR_GITHUB_MODE")
+	_ = v.BindEnv("desktop_notifications", "WAYBAR_GITHUB_DESKTOP_NOTIFICATIONS")
+	_ = v.BindEnv("network_check", "WAYBAR_GITHUB_NETWORK_CHECK")
+	_ = v.BindEnv("stale_after_seconds", "WAYBAR_GITHUB_STALE_AFTER_SECONDS")
+	_ = v.BindEnv("include_repos", "WAYBAR_GITHUB_INCLUDE_REPOS")
+	_ = v.BindEnv("exclude_repos", "WAYBAR_GITHUB_EXCLUDE_REPOS")
+	_ = v.BindEnv("drafts", "WAYBAR_GITHUB_DRAFTS")
+	_ = v.BindEnv("max_per_repo", "WAYBAR_GITHUB_MAX_PER_REPO")
+	_ = v.BindEnv("group_by", "WAYBAR_GITHUB_GROUP_BY")
+	_ = v.BindEnv("repository_submenus", "WAYBAR_GITHUB_REPOSITORY_SUBMENUS")
+	_ = v.BindEnv("sort", "WAYBAR_GITHUB_SORT")
+	_ = v.BindEnv("review_sla_days", "WAYBAR_GITHUB_REVIEW_SLA_DAYS")
+	_ = v.BindEnv("work_week", "WAYBAR_GITHUB_WORK_WEEK")
+	_ = v.BindEnv("item_actions", "WAYBAR_GITHUB_ITEM_ACTIONS")
+	_ = v.BindEnv("clones", "WAYBAR_GITHUB_CLONES")
+	_ = v.BindEnv("clone_root", "WAYBAR_GITHUB_CLONE_ROOT")
+	_ = v.BindEnv("checkout_worktree", "WAYBAR_GITHUB_CHECKOUT_WORKTREE")
+	_ = v.BindEnv("rate_limit_reserve", "WAYBAR_GITHUB_RATE_LIMIT_RESERVE")
+	_ = v.BindEnv("log_level", "WAYBAR_GITHUB_LOG_LEVEL")
+	_ = v.BindEnv("opener_command", "WAYBAR_GITHUB_OPENER_COMMAND", "WAYBAR_OPENER_COMMAND")
+	_ = v.BindEnv("opener_rules_file", "WAYBAR_GITHUB_OPENER_RULES_FILE", "WAYBAR_OPENER_RULES_FILE")
 
 	v.SetDefault("host", "github.com")
-	v.SetDefault("api_url", "https://api.github.com")
 	v.SetDefault("graphql_url", "")
 	v.SetDefault("pr_query", "is:open is:pr involves:@me archived:false sort:updated-desc")
+	v.SetDefault("search_qualifiers", "is:open is:pr archived:false sort:updated-desc")
 	v.SetDefault("max_items", 8)
+	v.SetDefault("fetch_limit", 100)
 	v.SetDefault("timeout_seconds", 15)
 	v.SetDefault("state_dir", filepath.Join(xdgState, "waybar", "github-pull-requests"))
 	v.SetDefault("menu_dir", filepath.Join(xdgState, "waybar", "menus"))
+	v.SetDefault("daemon_interval_seconds", 60)
+	v.SetDefault("signal", 0)
+	v.SetDefault("notifications_signal", 0)
+	v.SetDefault("actions_signal", 0)
+	v.SetDefault("desktop_notifications", true)
+	v.SetDefault("network_check", true)
+	v.SetDefault("stale_after_seconds", 900)
+	v.SetDefault("drafts", DraftsShow)
+	v.SetDefault("max_per_repo", 0)
+	v.SetDefault("group_by", "search")
+	v.SetDefault("repository_submenus", false)
+	v.SetDefault("sort", "search")
+	v.SetDefault("review_sla_days", 0)
+	v.SetDefault("work_week", "mon-fri")
+	v.SetDefault("item_actions", true)
+	v.SetDefault("checkout_worktree", false)
+	v.SetDefault("rate_limit_reserve", 100)
+	v.SetDefault("log_level", "warn")
+	v.SetDefault("opener_rules_file", filepath.Join(xdgConfig, "waybar", "url-opener.rules"))
 
 	maxItems := v.GetInt("max_items")
 	if maxItems < 1 {
@@ -86,6 +231,9 @@ func Load() (Runtime, error) {
 		maxItems = maxActionItems
 	}
 
+	// GitHub search never returns more than 1000 results.
+	fetchLimit := min(max(v.GetInt("fetch_limit"), maxItems), maxSearchResults)
+
 	timeoutSeconds := v.GetInt("timeout_seconds")
 	if timeoutSeconds <= 0 {
 		timeoutSeconds = 15
@@ -97,15 +245,37 @@ func Load() (Runtime, error) {
 	}
 
 	apiURL := strings.TrimSpace(v.GetString("api_url"))
+	graphqlURL := strings.TrimSpace(v.GetString("graphql_url"))
 	if apiURL == "" {
-		apiURL = "https://api.github.com"
+		var defaultGraphQL string
+		apiURL, defaultGraphQL = defaultURLs(host)
+		if graphqlURL == "" {
+			graphqlURL = defaultGraphQL
+		}
 	}
-
-	graphqlURL := strings.TrimSpace(v.GetString("graphql_url"))
 	if graphqlURL == "" {
 		graphqlURL = strings.TrimRight(apiURL, "/") + "/graphql"
 	}
 
+	prQuery := strings.TrimSpace(v.GetString("pr_query"))
+	searches := []Search{newSearch("Pull requests", prQuery, "")}
+	if raw := strings.TrimSpace(v.GetString("searches")); raw != "" {
+		searches, err = ParseSearches(raw, strings.TrimSpace(v.GetString("search_qualifiers")))
+		if err != nil {
+			return Runtime{}, fmt.Errorf("parse WAYBAR_GITHUB_SEARCHES: %w", err)
+		}
+	}
+
+	if mode == "" {
+		mode = strings.TrimSpace(v.GetString("mode"))
+	}
+	if mode == "" {
+		mode = ModePullRequests
+	}
+	if !IsMode(mode) {
+		return Runtime{}, fmt.Errorf("unsupported mode %q (want %s, %s or %s)", mode, ModePullRequests, ModeNotifications, ModeActions)
+	}
+
 	stateDir := strings.TrimSpace(v.GetString("state_dir"))
 	if stateDir == "" {
 		stateDir = filepath.Join(xdgState, "waybar", "github-pull-requests")
@@ -116,22 +286,482 @@ func Load() (Runtime, error) {
 		menuDir = filepath.Join(xdgState, "waybar", "menus")
 	}
 
+	socketPath := strings.TrimSpace(v.GetString("socket_path"))
+	if socketPath == "" {
+		socketPath = daemon.SocketPath("github")
+	}
+	if mode != ModePullRequests {
+		// Every mode needs its own socket so one daemon process can host them all.
+		socketPath = filepath.Join(filepath.Dir(socketPath), "github-"+mode+".sock")
+	}
+
+	daemonIntervalSeconds := v.GetInt("daemon_interval_seconds")
+	if daemonIntervalSeconds <= 0 {
+		daemonIntervalSeconds = 60
+	}
+
+	staleAfterSeconds := v.GetInt("stale_after_seconds")
+	if staleAfterSeconds < 0 {
+		staleAfterSeconds = 0
+	}
+
+	filter, err := parseFilter(v)
+	if err != nil {
+		return Runtime{}, err
+	}
+
+	groupBy := strings.ToLower(strings.TrimSpace(v.GetString("group_by")))
+	if groupBy != "search" && groupBy != "repository" {
+		return Runtime{}, fmt.Errorf("unsupported WAYBAR_GITHUB_GROUP_BY %q (want search or repository)", groupBy)
+	}
+
+	sortBy := strings.ToLower(strings.TrimSpace(v.GetString("sort")))
+	if sortBy != "search" && sortBy != "review-wait" {
+		return Runtime{}, fmt.Errorf("unsupported WAYBAR_GITHUB_SORT %q (want search or review-wait)", sortBy)
+	}
+
+	workWeek, err := ParseWorkWeek(v.GetString("work_week"))
+	if err != nil {
+		return Runtime{}, fmt.Errorf("parse WAYBAR_GITHUB_WORK_WEEK: %w", err)
+	}
+	reviewSLA := time.Duration(max(0, v.GetFloat64("review_sla_days")) * float64(24*time.Hour))
+
+	watches, err := parseWatches(v.GetString("watch"))
+	if err != nil {
+		return Runtime{}, fmt.Errorf("parse WAYBAR_GITHUB_WATCH: %w", err)
+	}
+
+	clones, err := parseClones(v.GetString("clones"), home)
+	if err != nil {
+		return Runtime{}, fmt.Errorf("parse WAYBAR_GITHUB_CLONES: %w", err)
+	}
+
+	rateLimitReserve := v.GetInt("rate_limit_reserve")
+	if rateLimitReserve < 0 {
+		rateLimitReserve = 0
+	}
+
+	signal := v.GetInt("signal")
+	if mode != ModePullRequests {
+		signal = v.GetInt(mode + "_signal")
+	}
+	if signal < 0 || signal > 30 {
+		signal = 0
+	}
+
+	secretAttributes := "service=waybar-github host=" + host
+	if raw, ok := os.LookupEnv("WAYBAR_GITHUB_SECRET_ATTRIBUTES"); ok {
+		secretAttributes = raw
+	}
+	parsedSecretAttributes, err := secrets.ParseAttributes(secretAttributes)
+	if err != nil {
+		return Runtime{}, fmt.Errorf("parse WAYBAR_GITHUB_SECRET_ATTRIBUTES: %w", err)
+	}
+
+	hosts := []Host{{
+		Host:             host,
+		APIURL:           apiURL,
+		GraphQLURL:       graphqlURL,
+		Token:            strings.TrimSpace(v.GetString("token")),
+		Searches:         searches,
+		SecretAttributes: parsedSecretAttributes,
+		RateLimitPath:    rateLimitPath(stateDir, APIGraphQL, host),
+	}}
+	if raw := strings.TrimSpace(v.GetString("hosts")); raw != "" {
+		hosts, err = loadHosts(v, raw, hosts[0], stateDir)
+		if err != nil {
+			return Runtime{}, err
+		}
+	}
+
 	return Runtime{
+		Mode:       mode,
 		ConfigFile: configFile,
 		Host:       host,
 		APIURL:     apiURL,
 		GraphQLURL: graphqlURL,
 		Token:      strings.TrimSpace(v.GetString("token")),
-		PRQuery:    strings.TrimSpace(v.GetString("pr_query")),
+		PRQuery:    prQuery,
+		Searches:   searches,
+		Hosts:      hosts,
 		MaxItems:   maxItems,
+		FetchLimit: fetchLimit,
 		Timeout:    time.Duration(timeoutSeconds) * time.Second,
 		StateDir:   stateDir,
 		MenuDir:    menuDir,
-		MenuPath:   filepath.Join(menuDir, "github-pull-requests.xml"),
-		ItemsPath:  filepath.Join(stateDir, "items.json"),
+		MenuPath:   filepath.Join(menuDir, "github-"+mode+".xml"),
+		ItemsPath:  filepath.Join(stateDir, statePrefix(mode)+"items.json"),
+		MetaPath:   filepath.Join(stateDir, statePrefix(mode)+"meta.json"),
+		SeenPath:   filepath.Join(stateDir, statePrefix(mode)+"notified.json"),
+
+		RateLimitPath:    rateLimitPath(stateDir, modeAPI(mode), host),
+		RateLimitReserve: rateLimitReserve,
+
+		SocketPath:     socketPath,
+		DaemonInterval: time.Duration(daemonIntervalSeconds) * time.Second,
+		Signal:         signal,
+		NetworkCheck:   v.GetBool("network_check"),
+		StaleAfter:     time.Duration(staleAfterSeconds) * time.Second,
+
+		Watches: watches,
+
+		Filter:             filter,
+		GroupByRepository:  groupBy == "repository",
+		RepositorySubmenus: v.GetBool("repository_submenus"),
+		SortByReviewWait:   sortBy == "review-wait",
+
+		ReviewSLA: reviewSLA,
+		WorkWeek:  workWeek,
+
+		ItemActions:      v.GetBool("item_actions"),
+		Clones:           clones,
+		CloneRoot:        expandHome(strings.TrimSpace(v.GetString("clone_root")), home),
+		CheckoutWorktree: v.GetBool("checkout_worktree"),
+
+		DesktopNotifications: v.GetBool("desktop_notifications"),
+
+		LogLevel: strings.TrimSpace(v.GetString("log_level")),
+		LogPath:  filepath.Join(stateDir, "github.log"),
+
+		Opener: opener.Config{
+			Command:   strings.TrimSpace(v.GetString("opener_command")),
+			RulesFile: strings.TrimSpace(v.GetString("opener_rules_file")),
+		},
+
+		SecretAttributes: parsedSecretAttributes,
 	}, nil
 }
 
+// Host is one GitHub instance with its own credentials and searches.
+type Host struct {
+	Host       string
+	APIURL     string
+	GraphQLURL string
+	Token      string
+	Searches   []Search
+
+	SecretAttributes map[string]string
+	// RateLimitPath holds the host's GraphQL budget.
+	RateLimitPath string
+}
+
+// loadHosts reads the WAYBAR_GITHUB_HOSTS list. primary keeps the unprefixed
+// settings; every other host reads WAYBAR_GITHUB_<HOST>_API_URL, _GRAPHQL_URL,
+// _TOKEN, _SEARCHES and _SECRET_ATTRIBUTES, where <HOST> is the host name in
+// upper case with other characters replaced by "_" (ghe.example.com → GHE_EXAMPLE_COM).
+func loadHosts(v *viper.Viper, raw string, primary Host, stateDir string) ([]Host, error) {
+	var hosts []Host
+	seen := make(map[string]bool)
+	for _, name := range strings.FieldsFunc(raw, func(r rune) bool { return r == ',' || r == ';' || r == ' ' }) {
+		name = strings.ToLower(name)
+		if seen[name] {
+			continue
+		}
+		seen[name] = true
+		if strings.EqualFold(name, primary.Host) {
+			hosts = append(hosts, primary)
+			continue
+		}
+
+		key := hostKey(name)
+		apiURL, graphqlURL := defaultURLs(name)
+		if value := strings.TrimSpace(v.GetString(key + "_api_url")); value != "" {
+			apiURL = value
+		}
+		if value := strings.TrimSpace(v.GetString(key + "_graphql_url")); value != "" {
+			graphqlURL = value
+		}
+
+		searches := primary.Searches
+		if value := strings.TrimSpace(v.GetString(key + "_searches")); value != "" {
+			parsed, err := ParseSearches(value, strings.TrimSpace(v.GetString("search_qualifiers")))
+			if err != nil {
+				return nil, fmt.Errorf("parse WAYBAR_GITHUB_%s_SEARCHES: %w", strings.ToUpper(key), err)
+			}
+			searches = parsed
+		}
+
+		secretAttributes := "service=waybar-github host=" + name
+		if value, ok := os.LookupEnv("WAYBAR_GITHUB_" + strings.ToUpper(key) + "_SECRET_ATTRIBUTES"); ok {
+			secretAttributes = value
+		}
+		parsedSecretAttributes, err := secrets.ParseAttributes(secretAttributes)
+		if err != nil {
+			return nil, fmt.Errorf("parse WAYBAR_GITHUB_%s_SECRET_ATTRIBUTES: %w", strings.ToUpper(key), err)
+		}
+
+		hosts = append(hosts, Host{
+			Host:             name,
+			APIURL:           apiURL,
+			GraphQLURL:       graphqlURL,
+			Token:            strings.TrimSpace(v.GetString(key + "_token")),
+			Searches:         searches,
+			SecretAttributes: parsedSecretAttributes,
+			RateLimitPath:    rateLimitPath(stateDir, APIGraphQL, name),
+		})
+	}
+	if len(hosts) == 0 {
+		return nil, fmt.Errorf("WAYBAR_GITHUB_HOSTS lists no hosts")
+	}
+	return hosts, nil
+}
+
+// hostKey turns a host name into the viper key segment for its settings.
+func hostKey(host string) string {
+	return strings.Map(func(r rune) rune {
+		if (r >= 'a' && r <= 'z') || (r >= '0' && r <= '9') {
+			return r
+		}
+		return '_'
+	}, strings.ToLower(host))
+}
+
+// defaultURLs are the REST and GraphQL endpoints of github.com or a GitHub
+// Enterprise Server host.
+func defaultURLs(host string) (string, string) {
+	if host == "github.com" {
+		return "https://api.github.com", "https://api.github.com/graphql"
+	}
+	return "https://" + host + "/api/v3", "https://" + host + "/api/graphql"
+}
+
+// ForHost returns a copy of r that talks to host. MaxItems is shared between
+// the hosts so the merged dropdown stays within the menu's action slots.
+func (r Runtime) ForHost(host Host) Runtime {
+	r.Host = host.Host
+	r.APIURL = host.APIURL
+	r.GraphQLURL = host.GraphQLURL
+	r.Token = host.Token
+	r.Searches = host.Searches
+	r.SecretAttributes = host.SecretAttributes
+	r.RateLimitPath = host.RateLimitPath
+	r.MaxItems = max(1, r.MaxItems/max(1, len(r.Hosts)))
+	return r
+}
+
+// The APIs GitHub keeps separate rate limit budgets for.
+const (
+	APIGraphQL = "graphql"
+	APIREST    = "rest"
+)
+
+// modeAPI is the API a mode's fetches spend: pull requests are searched over
+// GraphQL, notifications and workflow runs are listed over REST.
+func modeAPI(mode string) string {
+	if mode == ModePullRequests {
+		return APIGraphQL
+	}
+	return APIREST
+}
+
+// rateLimitPath is the file holding the budget of api on host.
+func rateLimitPath(stateDir, api, host string) string {
+	return filepath.Join(stateDir, "ratelimit-"+api+"-"+host+".json")
+}
+
+// statePrefix keeps each mode's items and meta apart inside the shared state dir;
+// pull requests keep the original unprefixed names.
+func statePrefix(mode string) string {
+	if mode == ModePullRequests {
+		return ""
+	}
+	return mode + "-"
+}
+
+type Search struct {
+	Name  string
+	Query string
+	// Type is the GraphQL search type: SearchIssues covers pull requests and issues.
+	Type string
+}
+
+const (
+	SearchIssues      = "ISSUE"
+	SearchDiscussions = "DISCUSSION"
+)
+
+// kindQualifiers pick what a search returns. When a query names one itself, the
+// same qualifiers are dropped from the shared WAYBAR_GITHUB_SEARCH_QUALIFIERS.
+var kindQualifiers = []string{"is:pr", "is:issue", "type:pr", "type:issue", "is:discussion"}
+
+// newSearch appends qualifiers to query. "is:discussion" is not a search
+// qualifier; it switches the search to discussions and is removed from the query.
+func newSearch(name, query, qualifiers string) Search {
+	fields := strings.Fields(query)
+	ownKind := slices.ContainsFunc(fields, func(field string) bool { return slices.Contains(kindQualifiers, field) })
+	for _, qualifier := range strings.Fields(qualifiers) {
+		if !ownKind || !slices.Contains(kindQualifiers, qualifier) {
+			fields = append(fields, qualifier)
+		}
+	}
+
+	search := Search{Name: name, Type: SearchIssues}
+	if slices.Contains(fields, "is:discussion") {
+		search.Type = SearchDiscussions
+		fields = slices.DeleteFunc(fields, func(field string) bool { return field == "is:discussion" })
+	}
+	search.Query = strings.Join(fields, " ")
+	return search
+}
+
+// ParseSearches reads "Name=query;Name=query" lists. qualifiers are appended to
+// every query so entries can stay short, e.g. "Review requested=review-requested:@me".
+func ParseSearches(raw, qualifiers string) ([]Search, error) {
+	var searches []Search
+	for _, entry := range strings.Split(raw, ";") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		name, query, ok := strings.Cut(entry, "=")
+		name, query = strings.TrimSpace(name), strings.TrimSpace(query)
+		if !ok || name == "" || query == "" {
+			return nil, fmt.Errorf("invalid search %q (want NAME=QUERY)", entry)
+		}
+		searches = append(searches, newSearch(name, query, qualifiers))
+	}
+	if len(searches) == 0 {
+		return nil, fmt.Errorf("no searches defined")
+	}
+	if len(searches) > maxActionItems {
+		return nil, fmt.Errorf("at most %d searches are supported", maxActionItems)
+	}
+	return searches, nil
+}
+
+// Filter hides search results after the search, so counts can say how many
+// were hidden rather than silently shrinking.
+type Filter struct {
+	// Include and Exclude are repository globs such as "acme/*" or "*/renovate-*";
+	// a bare owner means all of its repositories. An empty Include allows all.
+	Include []string
+	Exclude []string
+	// Drafts is DraftsShow, DraftsHide or DraftsSeparate.
+	Drafts string
+	// MaxPerRepo caps the items kept per repository; 0 disables the cap.
+	MaxPerRepo int
+}
+
+const (
+	DraftsShow     = "show"
+	DraftsHide     = "hide"
+	DraftsSeparate = "separate"
+)
+
+// Active reports whether the filter can hide anything.
+func (f Filter) Active() bool {
+	return len(f.Include) > 0 || len(f.Exclude) > 0 || f.Drafts == DraftsHide || f.MaxPerRepo > 0
+}
+
+// Allows reports whether repository passes the include and exclude globs.
+func (f Filter) Allows(repository string) bool {
+	repository = strings.ToLower(repository)
+	if len(f.Include) > 0 && !matchesAny(f.Include, repository) {
+		return false
+	}
+	return !matchesAny(f.Exclude, repository)
+}
+
+func matchesAny(patterns []string, repository string) bool {
+	for _, pattern := range patterns {
+		if ok, _ := path.Match(pattern, repository); ok {
+			return true
+		}
+	}
+	return false
+}
+
+func parseFilter(v *viper.Viper) (Filter, error) {
+	include, err := parseRepoGlobs(v.GetString("include_repos"))
+	if err != nil {
+		return Filter{}, fmt.Errorf("parse WAYBAR_GITHUB_INCLUDE_REPOS: %w", err)
+	}
+	exclude, err := parseRepoGlobs(v.GetString("exclude_repos"))
+	if err != nil {
+		return Filter{}, fmt.Errorf("parse WAYBAR_GITHUB_EXCLUDE_REPOS: %w", err)
+	}
+
+	drafts := strings.ToLower(strings.TrimSpace(v.GetString("drafts")))
+	switch drafts {
+	case "":
+		drafts = DraftsShow
+	case DraftsShow, DraftsHide, DraftsSeparate:
+	default:
+		return Filter{}, fmt.Errorf("unsupported WAYBAR_GITHUB_DRAFTS %q (want %s, %s or %s)", drafts, DraftsShow, DraftsHide, DraftsSeparate)
+	}
+
+	return Filter{
+		Include:    include,
+		Exclude:    exclude,
+		Drafts:     drafts,
+		MaxPerRepo: max(0, v.GetInt("max_per_repo")),
+	}, nil
+}
+
+// parseRepoGlobs reads comma- or space-separated repository globs.
+func parseRepoGlobs(raw string) ([]string, error) {
+	var globs []string
+	for _, glob := range strings.FieldsFunc(strings.ToLower(raw), func(r rune) bool { return r == ',' || r == ';' || r == ' ' }) {
+		if !strings.Contains(glob, "/") {
+			glob += "/*"
+		}
+		if _, err := path.Match(glob, ""); err != nil {
+			return nil, fmt.Errorf("invalid glob %q: %w", glob, err)
+		}
+		globs = append(globs, glob)
+	}
+	return globs, nil
+}
+
+// Watch is a branch whose workflow runs actions mode reports. An empty Branch
+// means the repository's default branch.
+type Watch struct {
+	Repository string
+	Branch     string
+}
+
+// parseWatches reads "owner/repo[@branch]" entries separated by commas or spaces.
+func parseWatches(raw string) ([]Watch, error) {
+	var watches []Watch
+	for _, entry := range strings.FieldsFunc(raw, func(r rune) bool { return r == ',' || r == ';' || r == ' ' }) {
+		repository, branch, _ := strings.Cut(entry, "@")
+		owner, name, ok := strings.Cut(repository, "/")
+		if !ok || owner == "" || name == "" || strings.Contains(name, "/") {
+			return nil, fmt.Errorf("invalid watch %q (want OWNER/REPO or OWNER/REPO@BRANCH)", entry)
+		}
+		watches = append(watches, Watch{Repository: repository, Branch: branch})
+	}
+	return watches, nil
+}
+
+// parseClones reads "owner/repo=path;owner/repo=path" lists.
+func parseClones(raw, home string) (map[string]string, error) {
+	clones := make(map[string]string)
+	for _, entry := range strings.Split(raw, ";") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		repository, path, ok := strings.Cut(entry, "=")
+		repository, path = strings.TrimSpace(repository), strings.TrimSpace(path)
+		if !ok || !strings.Contains(repository, "/") || path == "" {
+			return nil, fmt.Errorf("invalid clone %q (want OWNER/REPO=PATH)", entry)
+		}
+		clones[strings.ToLower(repository)] = expandHome(path, home)
+	}
+	return clones, nil
+}
+
+func expandHome(path, home string) string {
+	if path == "~" {
+		return home
+	}
+	if rest, ok := strings.CutPrefix(path, "~/"); ok {
+		return filepath.Join(home, rest)
+	}
+	return path
+}
+
 func loadEnvFile(path string) error {
 	file, err := os.Open(path)
 	if err != nil {
@@ -184,3 +814,37 @@ func loadEnvFile(path string) error {
 	}
 	return nil
 }
+
+// ValidateEnvFile reports lines that the env-file loader would silently skip.
+func ValidateEnvFile(path string) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("open env file %s: %w", path, err)
+	}
+	defer func() {
+		_ = file.Close()
+	}()
+
+	var problems []string
+	scanner := bufio.NewScanner(file)
+	for lineNumber := 1; scanner.Scan(); lineNumber++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		line = strings.TrimSpace(strings.TrimPrefix(line, "export "))
+
+		key, _, ok := strings.Cut(line, "=")
+		if !ok || strings.TrimSpace(key) == "" {
+			problems = append(problems, fmt.Sprintf("line %d", lineNumber))
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return fmt.Errorf("scan env file %s: %w", path, err)
+	}
+
+	if len(problems) > 0 {
+		return fmt.Errorf("%s: malformed KEY=VALUE on %s", path, strings.Join(problems, ", "))
+	}
+	return nil
+}
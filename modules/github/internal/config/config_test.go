@@ -0,0 +1,330 @@
+package config
+
+import (
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+// isolateEnv points config loading at an empty home, so only the variables a
+// test sets apply, and returns that home.
+func isolateEnv(t *testing.T, env map[string]string) string {
+	t.Helper()
+	dir := t.TempDir()
+	defaults := map[string]string{
+		"HOME":                      dir,
+		"XDG_CONFIG_HOME":           filepath.Join(dir, "config"),
+		"XDG_STATE_HOME":            filepath.Join(dir, "state"),
+		"XDG_RUNTIME_DIR":           filepath.Join(dir, "run"),
+		"WAYBAR_GITHUB_CONFIG_FILE": filepath.Join(dir, "missing.env"),
+		"WAYBAR_GITHUB_SOCKET_PATH": "",
+	}
+	for name, value := range env {
+		defaults[name] = value
+	}
+	for name, value := range defaults {
+		t.Setenv(name, value)
+	}
+	return dir
+}
+
+func TestLoadGivesEachModeItsOwnSocket(t *testing.T) {
+	tests := []struct {
+		name   string
+		socket string
+		want   map[string]string
+	}{
+		{
+			name: "default",
+			want: map[string]string{
+				ModePullRequests:  "run/waybar-modules/github.sock",
+				ModeNotifications: "run/waybar-modules/github-notifications.sock",
+				ModeActions:       "run/waybar-modules/github-actions.sock",
+			},
+		},
+		{
+			name:   "configured",
+			socket: "sockets/gh.sock",
+			want: map[string]string{
+				ModePullRequests:  "sockets/gh.sock",
+				ModeNotifications: "sockets/github-notifications.sock",
+				ModeActions:       "sockets/github-actions.sock",
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			home := isolateEnv(t, nil)
+			if tt.socket != "" {
+				t.Setenv("WAYBAR_GITHUB_SOCKET_PATH", filepath.Join(home, tt.socket))
+			}
+
+			for mode, want := range tt.want {
+				cfg, err := Load(mode)
+				if err != nil {
+					t.Fatalf("load %s: %v", mode, err)
+				}
+				if cfg.SocketPath != filepath.Join(home, want) {
+					t.Fatalf("expected %s socket %s, got %s", mode, filepath.Join(home, want), cfg.SocketPath)
+				}
+			}
+		})
+	}
+}
+
+// GitHub budgets GraphQL and REST separately, so pacing one must not read the other's file.
+func TestLoadKeysRateLimitByAPIAndHost(t *testing.T) {
+	home := isolateEnv(t, map[string]string{"WAYBAR_GITHUB_HOSTS": "github.com,ghe.example.com"})
+	stateDir := filepath.Join(home, "state", "waybar", "github-pull-requests")
+
+	want := map[string]string{
+		ModePullRequests:  "ratelimit-graphql-github.com.json",
+		ModeNotifications: "ratelimit-rest-github.com.json",
+		ModeActions:       "ratelimit-rest-github.com.json",
+	}
+	for mode, file := range want {
+		cfg, err := Load(mode)
+		if err != nil {
+			t.Fatalf("load %s: %v", mode, err)
+		}
+		if cfg.RateLimitPath != filepath.Join(stateDir, file) {
+			t.Fatalf("expected %s budget in %s, got %s", mode, file, cfg.RateLimitPath)
+		}
+	}
+
+	cfg, err := Load(ModePullRequests)
+	if err != nil {
+		t.Fatalf("load: %v", err)
+	}
+	if got := cfg.ForHost(cfg.Hosts[1]).RateLimitPath; got != filepath.Join(stateDir, "ratelimit-graphql-ghe.example.com.json") {
+		t.Fatalf("expected the second host's own GraphQL budget, got %s", got)
+	}
+}
+
+func TestLoadHosts(t *testing.T) {
+	isolateEnv(t, map[string]string{
+		"WAYBAR_GITHUB_HOSTS":                       "ghe.example.com, github.com;GHE.example.com",
+		"WAYBAR_GITHUB_TOKEN":                       "primary-token",
+		"WAYBAR_GITHUB_SEARCHES":                    "Review requested=review-requested:@me",
+		"WAYBAR_GITHUB_SEARCH_QUALIFIERS":           "is:open",
+		"WAYBAR_GITHUB_GHE_EXAMPLE_COM_TOKEN":       "ghe-token",
+		"WAYBAR_GITHUB_GHE_EXAMPLE_COM_SEARCHES":    "Mine=author:@me",
+		"WAYBAR_GITHUB_GHE_EXAMPLE_COM_API_URL":     "https://ghe.example.com/custom/v3",
+		"WAYBAR_GITHUB_GHE_EXAMPLE_COM_GRAPHQL_URL": "",
+	})
+
+	cfg, err := Load(ModePullRequests)
+	if err != nil {
+		t.Fatalf("load: %v", err)
+	}
+	if len(cfg.Hosts) != 2 {
+		t.Fatalf("expected the repeated host once, got %+v", cfg.Hosts)
+	}
+
+	ghe, primary := cfg.Hosts[0], cfg.Hosts[1]
+	if ghe.Host != "ghe.example.com" || ghe.Token != "ghe-token" || ghe.APIURL != "https://ghe.example.com/custom/v3" || ghe.GraphQLURL != "https://ghe.example.com/api/graphql" {
+		t.Fatalf("unexpected enterprise host %+v", ghe)
+	}
+	if len(ghe.Searches) != 1 || ghe.Searches[0].Name != "Mine" || ghe.Searches[0].Query != "author:@me is:open" {
+		t.Fatalf("expected the host's own searches with the shared qualifiers, got %+v", ghe.Searches)
+	}
+	if ghe.SecretAttributes["host"] != "ghe.example.com" {
+		t.Fatalf("expected the keyring entry of the host, got %v", ghe.SecretAttributes)
+	}
+	if primary.Host != "github.com" || primary.Token != "primary-token" || primary.GraphQLURL != "https://api.github.com/graphql" || primary.Searches[0].Name != "Review requested" {
+		t.Fatalf("expected github.com to keep the unprefixed settings, got %+v", primary)
+	}
+
+	cfg.MaxItems = 9
+	if got := cfg.ForHost(ghe); got.Host != ghe.Host || got.Token != ghe.Token || got.Searches[0].Name != "Mine" || got.MaxItems != 4 {
+		t.Fatalf("expected the enterprise host with half the rows, got %+v", got)
+	}
+}
+
+func TestLoadHostsErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		env  map[string]string
+		want string
+	}{
+		{name: "no hosts", env: map[string]string{"WAYBAR_GITHUB_HOSTS": ", ;"}, want: "WAYBAR_GITHUB_HOSTS lists no hosts"},
+		{name: "bad searches", env: map[string]string{"WAYBAR_GITHUB_HOSTS": "ghe.example.com", "WAYBAR_GITHUB_GHE_EXAMPLE_COM_SEARCHES": "no query"}, want: "parse WAYBAR_GITHUB_GHE_EXAMPLE_COM_SEARCHES"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			isolateEnv(t, tt.env)
+			if _, err := Load(ModePullRequests); err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Fatalf("expected error containing %q, got %v", tt.want, err)
+			}
+		})
+	}
+}
+
+func TestParseSearchesKinds(t *testing.T) {
+	qualifiers := "is:open is:pr archived:false"
+	searches, err := ParseSearches("Review requested=review-requested:@me; Bugs=is:issue label:bug ;Q&A=is:discussion involves:@me", qualifiers)
+	if err != nil {
+		t.Fatalf("parse: %v", err)
+	}
+	want := []Search{
+		{Name: "Review requested", Query: "review-requested:@me is:open is:pr archived:false", Type: SearchIssues},
+		{Name: "Bugs", Query: "is:issue label:bug is:open archived:false", Type: SearchIssues},
+		{Name: "Q&A", Query: "involves:@me is:open archived:false", Type: SearchDiscussions},
+	}
+	if !slices.Equal(searches, want) {
+		t.Fatalf("expected %+v, got %+v", want, searches)
+	}
+
+	if _, err := ParseSearches("Bugs", qualifiers); err == nil || !strings.Contains(err.Error(), `invalid search "Bugs"`) {
+		t.Fatalf("expected an entry without a query to be refused, got %v", err)
+	}
+}
+
+func TestFilterAllows(t *testing.T) {
+	tests := []struct {
+		name       string
+		include    string
+		exclude    string
+		repository string
+		want       bool
+	}{
+		{name: "no globs", repository: "acme/app", want: true},
+		{name: "bare owner", include: "acme", repository: "acme/app", want: true},
+		{name: "other owner", include: "acme", repository: "other/app", want: false},
+		{name: "name glob", include: "rbright/waybar-*", repository: "rbright/waybar-github", want: true},
+		{name: "name glob miss", include: "rbright/waybar-*", repository: "rbright/dotfiles", want: false},
+		{name: "case-insensitive", include: "ACME/App", repository: "Acme/APP", want: true},
+		{name: "any owner", exclude: "*/renovate-config", repository: "acme/renovate-config", want: false},
+		{name: "exclude wins", include: "acme", exclude: "acme/legacy", repository: "acme/legacy", want: false},
+		{name: "star stays in one segment", include: "acme*", repository: "acme-bots/app", want: true},
+		{name: "separators", include: "acme/app;  acme/api,acme/web", repository: "acme/web", want: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			include, err := parseRepoGlobs(tt.include)
+			if err != nil {
+				t.Fatalf("parse include: %v", err)
+			}
+			exclude, err := parseRepoGlobs(tt.exclude)
+			if err != nil {
+				t.Fatalf("parse exclude: %v", err)
+			}
+			filter := Filter{Include: include, Exclude: exclude}
+			if got := filter.Allows(tt.repository); got != tt.want {
+				t.Fatalf("expected Allows(%q) = %v with include %v and exclude %v", tt.repository, tt.want, include, exclude)
+			}
+		})
+	}
+
+	if _, err := parseRepoGlobs("acme/[app"); err == nil {
+		t.Fatal("expected a malformed glob to be rejected")
+	}
+}
+
+func TestLoadDrafts(t *testing.T) {
+	tests := map[string]string{
+		"":         DraftsShow,
+		"show":     DraftsShow,
+		" HIDE ":   DraftsHide,
+		"separate": DraftsSeparate,
+	}
+	for raw, want := range tests {
+		isolateEnv(t, map[string]string{"WAYBAR_GITHUB_DRAFTS": raw})
+		cfg, err := Load(ModePullRequests)
+		if err != nil {
+			t.Fatalf("%q: load: %v", raw, err)
+		}
+		if cfg.Filter.Drafts != want {
+			t.Fatalf("%q: expected drafts %q, got %q", raw, want, cfg.Filter.Drafts)
+		}
+	}
+
+	isolateEnv(t, map[string]string{"WAYBAR_GITHUB_DRAFTS": "last"})
+	if _, err := Load(ModePullRequests); err == nil || !strings.Contains(err.Error(), "WAYBAR_GITHUB_DRAFTS") {
+		t.Fatalf("expected an unknown drafts mode to be rejected, got %v", err)
+	}
+}
+
+func TestParseWorkWeek(t *testing.T) {
+	days := func(weekdays ...time.Weekday) WorkWeek {
+		var week WorkWeek
+		for _, day := range weekdays {
+			week[day] = true
+		}
+		return week
+	}
+	weekdays := days(time.Monday, time.Tuesday, time.Wednesday, time.Thursday, time.Friday)
+
+	tests := []struct {
+		raw  string
+		want WorkWeek
+	}{
+		{raw: "", want: WorkWeek{true, true, true, true, true, true, true}},
+		{raw: "mon-fri", want: weekdays},
+		{raw: "Monday-Friday", want: weekdays},
+		{raw: "sun-thu", want: days(time.Sunday, time.Monday, time.Tuesday, time.Wednesday, time.Thursday)},
+		{raw: "mon,wed, fri", want: days(time.Monday, time.Wednesday, time.Friday)},
+		{raw: "sat-mon", want: days(time.Saturday, time.Sunday, time.Monday)},
+		{raw: "tue-tue", want: days(time.Tuesday)},
+	}
+	for _, tt := range tests {
+		got, err := ParseWorkWeek(tt.raw)
+		if err != nil {
+			t.Fatalf("%q: %v", tt.raw, err)
+		}
+		if got != tt.want {
+			t.Fatalf("%q: expected %v, got %v", tt.raw, tt.want, got)
+		}
+	}
+
+	for _, raw := range []string{"funday", "mon-xyz", "-fri"} {
+		if _, err := ParseWorkWeek(raw); err == nil {
+			t.Fatalf("expected %q to be rejected", raw)
+		}
+	}
+}
+
+func TestWorkWeekElapsed(t *testing.T) {
+	weekdays, err := ParseWorkWeek("mon-fri")
+	if err != nil {
+		t.Fatalf("parse: %v", err)
+	}
+	sunThu, err := ParseWorkWeek("sun-thu")
+	if err != nil {
+		t.Fatalf("parse: %v", err)
+	}
+	everyDay, _ := ParseWorkWeek("")
+	sydney := time.FixedZone("UTC+10", 10*60*60)
+	// 2026-03-06 is a Friday.
+	at := func(day, hour int, zone *time.Location) time.Time {
+		return time.Date(2026, 3, day, hour, 0, 0, 0, zone)
+	}
+
+	tests := []struct {
+		name     string
+		week     WorkWeek
+		from, to time.Time
+		want     time.Duration
+	}{
+		{name: "same day", week: weekdays, from: at(5, 9, time.UTC), to: at(5, 17, time.UTC), want: 8 * time.Hour},
+		{name: "across the weekend", week: weekdays, from: at(6, 17, time.UTC), to: at(9, 9, time.UTC), want: 16 * time.Hour},
+		{name: "made on saturday", week: weekdays, from: at(7, 10, time.UTC), to: at(9, 10, time.UTC), want: 10 * time.Hour},
+		{name: "still the weekend", week: weekdays, from: at(6, 23, time.UTC), to: at(8, 23, time.UTC), want: time.Hour},
+		{name: "every day", week: everyDay, from: at(6, 17, time.UTC), to: at(9, 9, time.UTC), want: 64 * time.Hour},
+		{name: "sun-thu", week: sunThu, from: at(5, 20, time.UTC), to: at(8, 2, time.UTC), want: 6 * time.Hour},
+		{name: "to before from", week: weekdays, from: at(9, 9, time.UTC), to: at(6, 9, time.UTC), want: 0},
+		// Friday 20:00 UTC is Saturday morning in UTC+10, so only Monday counts
+		// there; counted in UTC it would be 4h of Friday and 1h of Monday.
+		{name: "days of to's zone", week: weekdays, from: at(6, 20, time.UTC), to: at(9, 11, sydney), want: 11 * time.Hour},
+		{name: "same instants in UTC", week: weekdays, from: at(6, 20, time.UTC), to: at(9, 11, sydney).UTC(), want: 5 * time.Hour},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.week.Elapsed(tt.from, tt.to); got != tt.want {
+				t.Fatalf("expected %s, got %s", tt.want, got)
+			}
+		})
+	}
+}
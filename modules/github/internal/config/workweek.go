@@ -0,0 +1,92 @@
+package config
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// WorkWeek marks the days that count towards review SLAs, indexed by time.Weekday.
+type WorkWeek [7]bool
+
+var weekdays = map[string]time.Weekday{
+	"sun": time.Sunday,
+	"mon": time.Monday,
+	"tue": time.Tuesday,
+	"wed": time.Wednesday,
+	"thu": time.Thursday,
+	"fri": time.Friday,
+	"sat": time.Saturday,
+}
+
+// ParseWorkWeek reads days and day ranges such as "mon-fri" or "sun-thu,sat".
+// An empty value counts every day.
+func ParseWorkWeek(raw string) (WorkWeek, error) {
+	var week WorkWeek
+	fields := strings.FieldsFunc(strings.ToLower(raw), func(r rune) bool { return r == ',' || r == ' ' })
+	if len(fields) == 0 {
+		return WorkWeek{true, true, true, true, true, true, true}, nil
+	}
+
+	for _, field := range fields {
+		first, last, isRange := strings.Cut(field, "-")
+		if !isRange {
+			last = first
+		}
+		from, ok := weekdays[prefix(first, 3)]
+		if !ok {
+			return WorkWeek{}, fmt.Errorf("unknown day %q", first)
+		}
+		to, ok := weekdays[prefix(last, 3)]
+		if !ok {
+			return WorkWeek{}, fmt.Errorf("unknown day %q", last)
+		}
+		// Ranges may wrap around the weekend, e.g. "sat-wed".
+		for day := from; ; day = (day + 1) % 7 {
+			week[day] = true
+			if day == to {
+				break
+			}
+		}
+	}
+	return week, nil
+}
+
+// Elapsed is the time between from and to that falls on work days, in to's
+// location. A request made on a Friday evening has waited only from Monday on.
+func (w WorkWeek) Elapsed(from, to time.Time) time.Duration {
+	if !to.After(from) {
+		return 0
+	}
+	from = from.In(to.Location())
+
+	var elapsed time.Duration
+	for day := startOfDay(from); day.Before(to); day = day.AddDate(0, 0, 1) {
+		if !w[day.Weekday()] {
+			continue
+		}
+		start, end := day, day.AddDate(0, 0, 1)
+		if start.Before(from) {
+			start = from
+		}
+		if end.After(to) {
+			end = to
+		}
+		if end.After(start) {
+			elapsed += end.Sub(start)
+		}
+	}
+	return elapsed
+}
+
+func startOfDay(t time.Time) time.Time {
+	year, month, day := t.Date()
+	return time.Date(year, month, day, 0, 0, 0, 0, t.Location())
+}
+
+func prefix(value string, n int) string {
+	if len(value) > n {
+		return value[:n]
+	}
+	return value
+}
@@ -0,0 +1,95 @@
+package desktop
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/godbus/dbus/v5"
+)
+
+const (
+	serviceName      = "org.freedesktop.Notifications"
+	servicePath      = dbus.ObjectPath("/org/freedesktop/Notifications")
+	serviceInterface = "org.freedesktop.Notifications"
+
+	// DefaultAction is invoked when the notification body is clicked.
+	DefaultAction = "default"
+
+	// MaxWait bounds how long Show waits for a click before giving up.
+	MaxWait = 30 * time.Minute
+)
+
+type Notification struct {
+	AppName string
+	Summary string
+	Body    string
+	Icon    string
+	// Actions are key/label pairs; use DefaultAction for a click on the body.
+	Actions []string
+}
+
+// Show posts n on the session bus and waits until one of its actions is invoked
+// or the notification is closed. It returns the invoked action key, or "" when
+// the notification was dismissed.
+func Show(ctx context.Context, n Notification) (string, error) {
+	conn, err := dbus.ConnectSessionBus(dbus.WithContext(ctx))
+	if err != nil {
+		return "", fmt.Errorf("connect session bus: %w", err)
+	}
+	defer func() {
+		_ = conn.Close()
+	}()
+
+	// Subscribe before posting so a fast click cannot be missed.
+	if err := conn.AddMatchSignal(
+		dbus.WithMatchObjectPath(servicePath),
+		dbus.WithMatchInterface(serviceInterface),
+	); err != nil {
+		return "", fmt.Errorf("subscribe to notification signals: %w", err)
+	}
+	signals := make(chan *dbus.Signal, 16)
+	conn.Signal(signals)
+
+	var id uint32
+	err = conn.Object(serviceName, servicePath).CallWithContext(
+		ctx,
+		serviceInterface+".Notify",
+		0,
+		n.AppName,
+		uint32(0),
+		n.Icon,
+		n.Summary,
+		n.Body,
+		n.Actions,
+		map[string]dbus.Variant{},
+		int32(-1),
+	).Store(&id)
+	if err != nil {
+		return "", fmt.Errorf("post notification: %w", err)
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			return "", ctx.Err()
+		case signal, ok := <-signals:
+			if !ok {
+				return "", fmt.Errorf("session bus closed")
+			}
+			if len(signal.Body) < 2 {
+				continue
+			}
+			if signalID, _ := signal.Body[0].(uint32); signalID != id {
+				continue
+			}
+			switch signal.Name {
+			case serviceInterface + ".ActionInvoked":
+				action, _ := signal.Body[1].(string)
+				return action, nil
+			case serviceInterface + ".NotificationClosed":
+				return "", nil
+			}
+		}
+	}
+}
@@ -0,0 +1,137 @@
+package desktop
+
+import (
+	"bufio"
+	"context"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/godbus/dbus/v5"
+)
+
+// fakeServer is a minimal org.freedesktop.Notifications that answers every
+// notification with a scripted signal.
+type fakeServer struct {
+	conn *dbus.Conn
+
+	mu       sync.Mutex
+	summary  string
+	actions  []string
+	respond  string
+	response string
+}
+
+func (s *fakeServer) Notify(_ string, _ uint32, _ string, summary, _ string, actions []string, _ map[string]dbus.Variant, _ int32) (uint32, *dbus.Error) {
+	s.mu.Lock()
+	s.summary = summary
+	s.actions = actions
+	respond, response := s.respond, s.response
+	s.mu.Unlock()
+
+	const id = uint32(7)
+	go func() {
+		time.Sleep(20 * time.Millisecond)
+		switch respond {
+		case "action":
+			_ = s.conn.Emit(servicePath, serviceInterface+".ActionInvoked", id, response)
+		case "close":
+			_ = s.conn.Emit(servicePath, serviceInterface+".NotificationClosed", id, uint32(2))
+		}
+	}()
+	return id, nil
+}
+
+func startPrivateNotificationServer(t *testing.T) *fakeServer {
+	t.Helper()
+
+	daemonPath, err := exec.LookPath("dbus-daemon")
+	if err != nil {
+		t.Skip("dbus-daemon not available")
+	}
+
+	address := "unix:path=" + filepath.Join(t.TempDir(), "bus")
+	cmd := exec.Command(daemonPath, "--session", "--nofork", "--nopidfile", "--print-address", "--address="+address)
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		t.Fatalf("dbus-daemon stdout: %v", err)
+	}
+	if err := cmd.Start(); err != nil {
+		t.Fatalf("start dbus-daemon: %v", err)
+	}
+	t.Cleanup(func() {
+		_ = cmd.Process.Kill()
+		_ = cmd.Wait()
+	})
+
+	line, err := bufio.NewReader(stdout).ReadString('\n')
+	if err != nil {
+		t.Fatalf("read bus address: %v", err)
+	}
+	t.Setenv("DBUS_SESSION_BUS_ADDRESS", strings.TrimSpace(line))
+
+	conn, err := dbus.ConnectSessionBus()
+	if err != nil {
+		t.Fatalf("connect private bus: %v", err)
+	}
+	t.Cleanup(func() {
+		_ = conn.Close()
+	})
+
+	server := &fakeServer{conn: conn}
+	if err := conn.Export(server, servicePath, serviceInterface); err != nil {
+		t.Fatalf("export server: %v", err)
+	}
+	reply, err := conn.RequestName(serviceName, dbus.NameFlagDoNotQueue)
+	if err != nil || reply != dbus.RequestNameReplyPrimaryOwner {
+		t.Fatalf("request name: %v %v", reply, err)
+	}
+	return server
+}
+
+func TestShowReturnsInvokedAction(t *testing.T) {
+	server := startPrivateNotificationServer(t)
+	server.mu.Lock()
+	server.respond, server.response = "action", DefaultAction
+	server.mu.Unlock()
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	action, err := Show(ctx, Notification{
+		AppName: "waybar-test",
+		Summary: "Review requested",
+		Actions: []string{DefaultAction, "Open"},
+	})
+	if err != nil {
+		t.Fatalf("show: %v", err)
+	}
+	if action != DefaultAction {
+		t.Fatalf("expected default action, got %q", action)
+	}
+
+	server.mu.Lock()
+	defer server.mu.Unlock()
+	if server.summary != "Review requested" || len(server.actions) != 2 {
+		t.Fatalf("unexpected notification %q %v", server.summary, server.actions)
+	}
+}
+
+func TestShowReturnsEmptyWhenClosed(t *testing.T) {
+	server := startPrivateNotificationServer(t)
+	server.mu.Lock()
+	server.respond = "close"
+	server.mu.Unlock()
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	action, err := Show(ctx, Notification{AppName: "waybar-test", Summary: "CI failed"})
+	if err != nil {
+		t.Fatalf("show: %v", err)
+	}
+	if action != "" {
+		t.Fatalf("expected no action, got %q", action)
+	}
+}
@@ -0,0 +1,82 @@
+package github
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/rbright/waybar-github/internal/config"
+)
+
+// ApprovePullRequest submits an approving review.
+func ApprovePullRequest(ctx context.Context, cfg config.Runtime, mode AuthMode, repository string, number int) error {
+	body, err := json.Marshal(map[string]string{"event": "APPROVE"})
+	if err != nil {
+		return fmt.Errorf("marshal review: %w", err)
+	}
+	path := fmt.Sprintf("/repos/%s/pulls/%d/reviews", repository, number)
+	response, err := restRequest(ctx, cfg, mode, http.MethodPost, path, nil, body)
+	if err != nil {
+		return err
+	}
+	if response.Status < 200 || response.Status >= 300 {
+		return response.err("approve pull request")
+	}
+	return nil
+}
+
+// RerunFailedJobs re-runs the failed jobs of every failed workflow run on the
+// pull request's head commit and returns how many runs were restarted.
+func RerunFailedJobs(ctx context.Context, cfg config.Runtime, mode AuthMode, repository string, number int) (int, error) {
+	response, err := restRequest(ctx, cfg, mode, http.MethodGet, fmt.Sprintf("/repos/%s/pulls/%d", repository, number), nil, nil)
+	if err != nil {
+		return 0, err
+	}
+	if response.Status < 200 || response.Status >= 300 {
+		return 0, response.err("get pull request")
+	}
+	var pull struct {
+		Head struct {
+			SHA string `json:"sha"`
+		} `json:"head"`
+	}
+	if err := json.Unmarshal(response.Body, &pull); err != nil {
+		return 0, fmt.Errorf("decode pull request: %w", err)
+	}
+	if strings.TrimSpace(pull.Head.SHA) == "" {
+		return 0, fmt.Errorf("pull request has no head commit")
+	}
+
+	query := url.Values{"head_sha": {pull.Head.SHA}, "status": {"failure"}, "per_page": {"50"}}
+	response, err = restRequest(ctx, cfg, mode, http.MethodGet, fmt.Sprintf("/repos/%s/actions/runs?%s", repository, query.Encode()), nil, nil)
+	if err != nil {
+		return 0, err
+	}
+	if response.Status < 200 || response.Status >= 300 {
+		return 0, response.err("list workflow runs")
+	}
+	var runs struct {
+		WorkflowRuns []struct {
+			ID int64 `json:"id"`
+		} `json:"workflow_runs"`
+	}
+	if err := json.Unmarshal(response.Body, &runs); err != nil {
+		return 0, fmt.Errorf("decode workflow runs: %w", err)
+	}
+
+	rerun := 0
+	for _, run := range runs.WorkflowRuns {
+		response, err := restRequest(ctx, cfg, mode, http.MethodPost, fmt.Sprintf("/repos/%s/actions/runs/%d/rerun-failed-jobs", repository, run.ID), nil, nil)
+		if err != nil {
+			return rerun, err
+		}
+		if response.Status < 200 || response.Status >= 300 {
+			return rerun, response.err("re-run failed jobs")
+		}
+		rerun++
+	}
+	return rerun, nil
+}
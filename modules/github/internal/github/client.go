@@ -6,8 +6,11 @@ import (
 	"encoding/json"
 	"fmt"
 	"io"
+	"log/slog"
+	"maps"
 	"net/http"
 	"os/exec"
+	"slices"
 	"strconv"
 	"strings"
 	"time"
@@ -23,6 +26,8 @@ const (
 	AuthToken AuthMode = "token"
 )
 
+// PullRequest is one search result. Kind tells pull requests apart from the
+// issues and discussions a search may also return.
 type PullRequest struct {
 	ID         string `json:"id"`
 	Number     int    `json:"number"`
@@ -31,55 +36,472 @@ type PullRequest struct {
 	Repository string `json:"repository"`
 	IsDraft    bool   `json:"isDraft"`
 	UpdatedAt  string `json:"updatedAt"`
+	// Search is the name of the configured search that returned this item.
+	Search string `json:"search,omitempty"`
+	// Host is the GitHub instance the item was fetched from.
+	Host string `json:"host,omitempty"`
+	// Kind is KindPullRequest, KindIssue or KindDiscussion; empty in items saved
+	// before issues were supported, which were all pull requests.
+	Kind string `json:"kind,omitempty"`
+	// Labels are the first few labels on issues and discussions.
+	Labels []string `json:"labels,omitempty"`
+	// Category and Answered describe discussions.
+	Category string `json:"category,omitempty"`
+	Answered bool   `json:"answered,omitempty"`
+	// CIState is the head commit's status check rollup (SUCCESS, FAILURE, ERROR, PENDING, EXPECTED).
+	CIState string `json:"ciState,omitempty"`
+	// Authored is true when the authenticated user opened the pull request.
+	Authored bool `json:"authored,omitempty"`
+
+	ReviewDecision     string   `json:"reviewDecision,omitempty"`
+	Mergeable          string   `json:"mergeable,omitempty"`
+	Reviews            []Review `json:"reviews,omitempty"`
+	RequestedReviewers []string `json:"requestedReviewers,omitempty"`
+	// ReviewRequested is true when the authenticated user is among the requested reviewers.
+	ReviewRequested bool `json:"reviewRequested,omitempty"`
+	// ReviewRequestedAt is when the user's review was requested or, on pull requests
+	// that do not wait on the user, when the oldest pending request was made.
+	ReviewRequestedAt time.Time `json:"reviewRequestedAt,omitzero"`
+	// CommentedAt is when other people commented or submitted a review, among
+	// the latest ten of each; the user's own activity is left out.
+	CommentedAt []time.Time `json:"commentedAt,omitempty"`
+}
+
+const (
+	KindPullRequest = "pull-request"
+	KindIssue       = "issue"
+	KindDiscussion  = "discussion"
+)
+
+func (pr PullRequest) IsPullRequest() bool {
+	return pr.Kind == "" || pr.Kind == KindPullRequest
+}
+
+// LabelSummary lists an issue's labels, or a discussion's category and whether
+// it was answered, e.g. "bug, p1" or "Q&A, answered".
+func (pr PullRequest) LabelSummary() string {
+	parts := slices.Clone(pr.Labels)
+	if pr.Kind == KindDiscussion {
+		parts = slices.Insert(parts, 0, pr.Category)
+		if pr.Answered {
+			parts = append(parts, "answered")
+		}
+	}
+	parts = slices.DeleteFunc(parts, func(part string) bool { return part == "" })
+	return strings.Join(parts, ", ")
+}
+
+// Updated parses UpdatedAt; the zero time when it is missing.
+func (pr PullRequest) Updated() time.Time {
+	updated, _ := time.Parse(time.RFC3339, pr.UpdatedAt)
+	return updated
+}
+
+// Review is the latest review per reviewer.
+type Review struct {
+	Author string `json:"author"`
+	State  string `json:"state"`
+}
+
+const (
+	ReviewApproved         = "APPROVED"
+	ReviewChangesRequested = "CHANGES_REQUESTED"
+	ReviewRequired         = "REVIEW_REQUIRED"
+)
+
+// Ready reports whether the pull request is approved and can be merged as is.
+func (pr PullRequest) Ready() bool {
+	return !pr.IsDraft && pr.ReviewDecision == ReviewApproved && pr.Mergeable == "MERGEABLE"
+}
+
+// ReviewSummary describes where the pull request stands in review, or "" when unknown.
+func (pr PullRequest) ReviewSummary() string {
+	switch {
+	case pr.Ready():
+		return "ready to merge"
+	case pr.ReviewDecision == ReviewApproved:
+		return "approved"
+	case pr.ReviewDecision == ReviewChangesRequested:
+		return "changes requested"
+	case len(pr.RequestedReviewers) == 1:
+		return "awaiting 1 reviewer"
+	case len(pr.RequestedReviewers) > 1:
+		return fmt.Sprintf("awaiting %d reviewers", len(pr.RequestedReviewers))
+	case pr.ReviewDecision == ReviewRequired:
+		return "review required"
+	default:
+		return ""
+	}
+}
+
+const (
+	CIStateSuccess  = "SUCCESS"
+	CIStateFailure  = "FAILURE"
+	CIStateError    = "ERROR"
+	CIStatePending  = "PENDING"
+	CIStateExpected = "EXPECTED"
+)
+
+// CIFailing reports whether the head commit's checks are red.
+func (pr PullRequest) CIFailing() bool {
+	return pr.CIState == CIStateFailure || pr.CIState == CIStateError
+}
+
+// CIPending reports whether checks on the head commit are still running.
+func (pr PullRequest) CIPending() bool {
+	return pr.CIState == CIStatePending || pr.CIState == CIStateExpected
+}
+
+type SearchCount struct {
+	Name  string `json:"name"`
+	Host  string `json:"host,omitempty"`
+	Count int    `json:"count"`
+	// Hidden counts the fetched results removed by the repository and draft filters.
+	Hidden int `json:"hidden,omitempty"`
+	// Capped counts the fetched results left out by the per-repository cap.
+	Capped int `json:"capped,omitempty"`
+	// More is set when results remain beyond the fetch limit, so Count is a lower bound.
+	More bool `json:"more,omitempty"`
+	// Error explains why the section shows cached or no results when its host failed.
+	Error string `json:"error,omitempty"`
+}
+
+// TotalHidden sums the results hidden by filters across searches.
+func TotalHidden(searches []SearchCount) int {
+	hidden := 0
+	for _, search := range searches {
+		hidden += search.Hidden
+	}
+	return hidden
+}
+
+// TotalCapped sums the results left out by the per-repository cap across searches.
+func TotalCapped(searches []SearchCount) int {
+	capped := 0
+	for _, search := range searches {
+		capped += search.Capped
+	}
+	return capped
+}
+
+// CountLabel renders count, with a "+" when a search stopped at the fetch limit.
+func CountLabel(count int, searches ...SearchCount) string {
+	label := strconv.Itoa(count)
+	if slices.ContainsFunc(searches, func(search SearchCount) bool { return search.More }) {
+		label += "+"
+	}
+	return label
+}
+
+// Label names the section, prefixed with its host when several hosts are merged.
+func (s SearchCount) Label(withHost bool) string {
+	if withHost && s.Host != "" {
+		return s.Host + " · " + s.Name
+	}
+	return s.Name
+}
+
+// Matches reports whether item belongs to this section.
+func (s SearchCount) Matches(item PullRequest) bool {
+	return item.Search == s.Name && item.Host == s.Host
+}
+
+// SpansHosts reports whether searches come from more than one host.
+func SpansHosts(searches []SearchCount) bool {
+	for _, search := range searches {
+		if search.Host != searches[0].Host {
+			return true
+		}
+	}
+	return false
 }
 
 type FetchResult struct {
-	Count int
-	Items []PullRequest
+	// Count is the number of distinct items across the searches.
+	Count    int
+	Items    []PullRequest
+	Searches []SearchCount
+	// RateLimit is nil when the response did not include the budget.
+	RateLimit *RateLimit
+}
+
+type searchResult struct {
+	PageInfo struct {
+		HasNextPage bool   `json:"hasNextPage"`
+		EndCursor   string `json:"endCursor"`
+	} `json:"pageInfo"`
+	Nodes []searchNode `json:"nodes"`
+}
+
+type searchNode struct {
+	Typename   string `json:"__typename"`
+	ID         string `json:"id"`
+	Number     int    `json:"number"`
+	Title      string `json:"title"`
+	URL        string `json:"url"`
+	IsDraft    bool   `json:"isDraft"`
+	UpdatedAt  string `json:"updatedAt"`
+	Repository struct {
+		NameWithOwner string `json:"nameWithOwner"`
+	} `json:"repository"`
+	Author struct {
+		Login string `json:"login"`
+	} `json:"author"`
+	Commits struct {
+		Nodes []commitNode `json:"nodes"`
+	} `json:"commits"`
+	ReviewDecision string `json:"reviewDecision"`
+	Mergeable      string `json:"mergeable"`
+	LatestReviews  struct {
+		Nodes []struct {
+			Author struct {
+				Login string `json:"login"`
+			} `json:"author"`
+			State string `json:"state"`
+		} `json:"nodes"`
+	} `json:"latestReviews"`
+	Comments struct {
+		Nodes []activityNode `json:"nodes"`
+	} `json:"comments"`
+	Reviews struct {
+		Nodes []activityNode `json:"nodes"`
+	} `json:"reviews"`
+	ReviewRequests struct {
+		Nodes []struct {
+			RequestedReviewer struct {
+				Login string `json:"login"`
+				Name  string `json:"name"`
+			} `json:"requestedReviewer"`
+		} `json:"nodes"`
+	} `json:"reviewRequests"`
+	TimelineItems struct {
+		Nodes []struct {
+			CreatedAt         time.Time `json:"createdAt"`
+			RequestedReviewer struct {
+				Login string `json:"login"`
+				Name  string `json:"name"`
+			} `json:"requestedReviewer"`
+		} `json:"nodes"`
+	} `json:"timelineItems"`
+	Labels struct {
+		Nodes []struct {
+			Name string `json:"name"`
+		} `json:"nodes"`
+	} `json:"labels"`
+	Category struct {
+		Name string `json:"name"`
+	} `json:"category"`
+	IsAnswered bool `json:"isAnswered"`
+}
+
+// activityNode is a comment or a submitted review; the query aliases a review's
+// submittedAt to createdAt.
+type activityNode struct {
+	Author struct {
+		Login string `json:"login"`
+	} `json:"author"`
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+type commitNode struct {
+	Commit struct {
+		StatusCheckRollup *struct {
+			State string `json:"state"`
+		} `json:"statusCheckRollup"`
+	} `json:"commit"`
 }
 
 type graphQLResponse struct {
-	Data struct {
-		Search struct {
-			IssueCount int `json:"issueCount"`
-			Nodes      []struct {
-				ID         string `json:"id"`
-				Number     int    `json:"number"`
-				Title      string `json:"title"`
-				URL        string `json:"url"`
-				IsDraft    bool   `json:"isDraft"`
-				UpdatedAt  string `json:"updatedAt"`
-				Repository struct {
-					NameWithOwner string `json:"nameWithOwner"`
-				} `json:"repository"`
-			} `json:"nodes"`
-		} `json:"search"`
-	} `json:"data"`
+	// Data holds the aliased searches (s0, s1, …) plus "viewer" and "rateLimit",
+	// which are decoded separately.
+	Data   map[string]json.RawMessage `json:"data"`
 	Errors []struct {
+		Type    string `json:"type"`
 		Message string `json:"message"`
 	} `json:"errors"`
 }
 
-const searchQuery = `query WaybarGitHubPullRequests($searchQuery: String!, $limit: Int!) {
-  search(type: ISSUE, query: $searchQuery, first: $limit) {
-    issueCount
-    nodes {
-      ... on PullRequest {
-        id
-        number
-        title
-        url
-        isDraft
-        updatedAt
-        repository {
-          nameWithOwner
+const searchFields = `fragment searchFields on SearchResultItemConnection {
+  pageInfo {
+    hasNextPage
+    endCursor
+  }
+  nodes {
+    __typename
+    ... on PullRequest {
+      id
+      number
+      title
+      url
+      isDraft
+      updatedAt
+      repository {
+        nameWithOwner
+      }
+      author {
+        login
+      }
+      commits(last: 1) {
+        nodes {
+          commit {
+            statusCheckRollup {
+              state
+            }
+          }
+        }
+      }
+      reviewDecision
+      mergeable
+      latestReviews(first: 10) {
+        nodes {
+          author {
+            login
+          }
+          state
+        }
+      }
+      comments(last: 10) {
+        nodes {
+          author {
+            login
+          }
+          createdAt
+        }
+      }
+      reviews(last: 10, states: [APPROVED, CHANGES_REQUESTED, COMMENTED, DISMISSED]) {
+        nodes {
+          author {
+            login
+          }
+          createdAt: submittedAt
+        }
+      }
+      reviewRequests(first: 10) {
+        nodes {
+          requestedReviewer {
+            ... on User {
+              login
+            }
+            ... on Team {
+              name
+            }
+            ... on Mannequin {
+              login
+            }
+          }
+        }
+      }
+      timelineItems(itemTypes: [REVIEW_REQUESTED_EVENT], last: 10) {
+        nodes {
+          ... on ReviewRequestedEvent {
+            createdAt
+            requestedReviewer {
+              ... on User {
+                login
+              }
+              ... on Team {
+                name
+              }
+              ... on Mannequin {
+                login
+              }
+            }
+          }
+        }
+      }
+    }
+    ... on Issue {
+      id
+      number
+      title
+      url
+      updatedAt
+      repository {
+        nameWithOwner
+      }
+      author {
+        login
+      }
+      comments(last: 10) {
+        nodes {
+          author {
+            login
+          }
+          createdAt
+        }
+      }
+      labels(first: 5) {
+        nodes {
+          name
         }
       }
     }
+    ... on Discussion {
+      id
+      number
+      title
+      url
+      updatedAt
+      repository {
+        nameWithOwner
+      }
+      author {
+        login
+      }
+      comments(last: 10) {
+        nodes {
+          author {
+            login
+          }
+          createdAt
+        }
+      }
+      labels(first: 5) {
+        nodes {
+          name
+        }
+      }
+      category {
+        name
+      }
+      isAnswered
+    }
   }
 }`
 
+// buildSearchQuery aliases one search per configured query (s0, s1, …) so all
+// sections are fetched in a single request. Only the searches in pending are
+// included; each resumes after its own cursor ($a0, $a1, …).
+func buildSearchQuery(searches []config.Search, pending []int) string {
+	var params, fields strings.Builder
+	params.WriteString("$limit: Int!")
+	for _, i := range pending {
+		fmt.Fprintf(&params, ", $q%d: String!, $a%d: String", i, i)
+		fmt.Fprintf(&fields, "  s%d: search(type: %s, query: $q%d, first: $limit, after: $a%d) {\n    ...searchFields\n  }\n", i, searchType(searches[i].Type), i, i)
+	}
+	return fmt.Sprintf("query WaybarGitHubPullRequests(%s) {\n  viewer {\n    login\n  }\n  rateLimit {\n    limit\n    remaining\n    cost\n    resetAt\n  }\n%s}\n\n%s", params.String(), fields.String(), searchFields)
+}
+
+func searchType(searchType string) string {
+	if searchType == config.SearchDiscussions {
+		return config.SearchDiscussions
+	}
+	return config.SearchIssues
+}
+
+// maxPageSize is the most results GitHub returns per search page.
+const maxPageSize = 100
+
 func DetectAuth(ctx context.Context, cfg config.Runtime) AuthMode {
+	mode := detectAuth(ctx, cfg)
+	slog.Debug("github auth mode detected", "mode", mode, "host", cfg.Host)
+	return mode
+}
+
+func detectAuth(ctx context.Context, cfg config.Runtime) AuthMode {
+	if _, ok := withGHToken(ctx, cfg); ok {
+		return AuthGH
+	}
 	if _, err := exec.LookPath("gh"); err == nil {
 		cmd := exec.CommandContext(ctx, "gh", "auth", "status", "-h", cfg.Host)
 		if err := cmd.Run(); err == nil {
@@ -94,64 +516,168 @@ func DetectAuth(ctx context.Context, cfg config.Runtime) AuthMode {
 	return AuthNone
 }
 
-func FetchPullRequests(ctx context.Context, cfg config.Runtime, mode AuthMode) (FetchResult, error) {
-	var raw []byte
-	var err error
+// searchPages accumulates one search's nodes across pages.
+type searchPages struct {
+	nodes  []searchNode
+	cursor string
+	// more is set when results remain beyond FetchLimit.
+	more bool
+}
 
-	switch mode {
-	case AuthGH:
-		raw, err = fetchWithGH(ctx, cfg)
-	case AuthToken:
-		raw, err = fetchWithToken(ctx, cfg)
-	default:
-		return FetchResult{}, fmt.Errorf("no supported auth mode")
+// FetchPullRequests pages through every search up to FetchLimit results, so
+// counts and filters cover all of them while only the top rows are kept.
+// Searches that have more pages are fetched together in follow-up requests.
+func FetchPullRequests(ctx context.Context, cfg config.Runtime, mode AuthMode) (FetchResult, error) {
+	pageSize := min(maxPageSize, max(1, cfg.FetchLimit))
+	pages := make([]searchPages, len(cfg.Searches))
+	pending := make([]int, 0, len(cfg.Searches))
+	for i := range cfg.Searches {
+		pending = append(pending, i)
 	}
-	if err != nil {
-		return FetchResult{}, err
+
+	start := time.Now()
+	var (
+		viewer    string
+		rateLimit *RateLimit
+		requests  int
+	)
+	for len(pending) > 0 {
+		variables := map[string]any{"limit": pageSize}
+		for _, i := range pending {
+			variables[fmt.Sprintf("q%d", i)] = cfg.Searches[i].Query
+			if pages[i].cursor != "" {
+				variables[fmt.Sprintf("a%d", i)] = pages[i].cursor
+			}
+		}
+
+		raw, err := doGraphQL(ctx, cfg, mode, buildSearchQuery(cfg.Searches, pending), variables)
+		if err != nil {
+			return FetchResult{}, err
+		}
+		page, err := decodeSearchPage(raw, cfg.Searches, pending)
+		if err != nil {
+			return FetchResult{}, err
+		}
+		requests++
+		viewer = fallbackLogin(viewer, page.viewer)
+		if page.rateLimit != nil {
+			// Pacing needs what a whole refresh costs, not its last page.
+			if rateLimit != nil {
+				page.rateLimit.Cost += rateLimit.Cost
+			}
+			rateLimit = page.rateLimit
+		}
+
+		var next []int
+		for _, i := range pending {
+			section := page.sections[i]
+			pages[i].nodes = append(pages[i].nodes, section.Nodes...)
+			pages[i].cursor = section.PageInfo.EndCursor
+			hasNext := section.PageInfo.HasNextPage && pages[i].cursor != ""
+			switch {
+			case len(pages[i].nodes) > cfg.FetchLimit:
+				pages[i].nodes = pages[i].nodes[:cfg.FetchLimit]
+				pages[i].more = true
+			case hasNext && len(pages[i].nodes) == cfg.FetchLimit:
+				pages[i].more = true
+			case hasNext:
+				next = append(next, i)
+			}
+		}
+		pending = next
 	}
 
-	parsed, err := parseResponse(raw)
-	if err != nil {
-		return FetchResult{}, err
+	parsed := buildResult(pages, cfg.Searches, viewer, cfg.MaxItems, cfg.Filter)
+	parsed.RateLimit = rateLimit
+	for i := range parsed.Items {
+		parsed.Items[i].Host = cfg.Host
 	}
+	for i := range parsed.Searches {
+		parsed.Searches[i].Host = cfg.Host
+	}
+
+	slog.Debug("github pull requests fetched", "host", cfg.Host, "mode", mode, "duration", time.Since(start), "requests", requests, "count", parsed.Count, "items", len(parsed.Items))
 	return parsed, nil
 }
 
-func fetchWithGH(ctx context.Context, cfg config.Runtime) ([]byte, error) {
-	cmd := exec.CommandContext(
-		ctx,
-		"gh",
-		"api",
-		"graphql",
-		"-f", "query="+searchQuery,
-		"-F", "searchQuery="+cfg.PRQuery,
-		"-F", "limit="+strconv.Itoa(cfg.MaxItems),
-	)
+func fallbackLogin(current, next string) string {
+	if current != "" {
+		return current
+	}
+	return next
+}
 
-	out, err := cmd.Output()
-	if err != nil {
-		return nil, fmt.Errorf("gh graphql request failed: %w", err)
+func doGraphQL(ctx context.Context, cfg config.Runtime, mode AuthMode, query string, variables map[string]any) ([]byte, error) {
+	switch mode {
+	case AuthGH:
+		ghCfg, ok := withGHToken(ctx, cfg)
+		if !ok {
+			return fetchWithGH(ctx, cfg, query, variables)
+		}
+		response, err := postGraphQL(ctx, ghCfg, query, variables)
+		if err != nil {
+			return nil, err
+		}
+		if response.Status == http.StatusUnauthorized {
+			// The token read may be older than gh's own, e.g. after `gh auth
+			// refresh`; forget it and let gh retry the request once.
+			forgetGHToken(cfg.Host)
+			return fetchWithGH(ctx, cfg, query, variables)
+		}
+		return graphQLBody(response)
+	case AuthToken:
+		response, err := postGraphQL(ctx, cfg, query, variables)
+		if err != nil {
+			return nil, err
+		}
+		return graphQLBody(response)
+	default:
+		return nil, fmt.Errorf("no supported auth mode")
 	}
+}
 
-	return out, nil
+func fetchWithGH(ctx context.Context, cfg config.Runtime, query string, variables map[string]any) ([]byte, error) {
+	args := []string{"api", "graphql", "--hostname", cfg.Host, "-f", "query=" + query}
+	for _, name := range slices.Sorted(maps.Keys(variables)) {
+		// -f keeps strings raw; -F would read "@file" values and coerce numbers.
+		switch value := variables[name].(type) {
+		case string:
+			args = append(args, "-f", name+"="+value)
+		case []string:
+			for _, item := range value {
+				args = append(args, "-f", name+"[]="+item)
+			}
+		default:
+			args = append(args, "-F", fmt.Sprintf("%s=%v", name, value))
+		}
+	}
+	// --include keeps the status and headers so rate limits are recognized under gh too.
+	args = append(args, "--include")
+	out, runErr := exec.CommandContext(ctx, "gh", args...).Output()
+
+	response, parseErr := parseIncludedResponse(out)
+	if parseErr != nil {
+		if runErr != nil {
+			return nil, fmt.Errorf("gh graphql request failed: %w", runErr)
+		}
+		return nil, parseErr
+	}
+	return graphQLBody(response)
 }
 
-func fetchWithToken(ctx context.Context, cfg config.Runtime) ([]byte, error) {
+func postGraphQL(ctx context.Context, cfg config.Runtime, query string, variables map[string]any) (restResponse, error) {
 	payload := map[string]any{
-		"query": searchQuery,
-		"variables": map[string]any{
-			"searchQuery": cfg.PRQuery,
-			"limit":       cfg.MaxItems,
-		},
+		"query":     query,
+		"variables": variables,
 	}
 	body, err := json.Marshal(payload)
 	if err != nil {
-		return nil, fmt.Errorf("marshal graphql payload: %w", err)
+		return restResponse{}, fmt.Errorf("marshal graphql payload: %w", err)
 	}
 
 	req, err := http.NewRequestWithContext(ctx, http.MethodPost, cfg.GraphQLURL, bytes.NewReader(body))
 	if err != nil {
-		return nil, fmt.Errorf("create graphql request: %w", err)
+		return restResponse{}, fmt.Errorf("create graphql request: %w", err)
 	}
 	req.Header.Set("Accept", "application/vnd.github+json")
 	req.Header.Set("Content-Type", "application/json")
@@ -161,7 +687,7 @@ func fetchWithToken(ctx context.Context, cfg config.Runtime) ([]byte, error) {
 	client := &http.Client{Timeout: maxDuration(cfg.Timeout, 10*time.Second)}
 	resp, err := client.Do(req)
 	if err != nil {
-		return nil, fmt.Errorf("perform graphql request: %w", err)
+		return restResponse{}, fmt.Errorf("perform graphql request: %w", err)
 	}
 	defer func() {
 		_ = resp.Body.Close()
@@ -169,49 +695,327 @@ func fetchWithToken(ctx context.Context, cfg config.Runtime) ([]byte, error) {
 
 	responseBody, err := io.ReadAll(resp.Body)
 	if err != nil {
-		return nil, fmt.Errorf("read graphql response: %w", err)
+		return restResponse{}, fmt.Errorf("read graphql response: %w", err)
 	}
+	slog.Debug("github graphql response", "status", resp.StatusCode, "bytes", len(responseBody))
 
-	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
-		return nil, fmt.Errorf("github graphql status %d: %s", resp.StatusCode, strings.TrimSpace(string(responseBody)))
-	}
+	return restResponse{Status: resp.StatusCode, Header: resp.Header, Body: responseBody}, nil
+}
 
-	return responseBody, nil
+func graphQLBody(response restResponse) ([]byte, error) {
+	if response.Status >= 200 && response.Status < 300 {
+		return response.Body, nil
+	}
+	if limitErr := rateLimitError(response, time.Now()); limitErr != nil {
+		return nil, limitErr
+	}
+	return nil, fmt.Errorf("github graphql status %d: %s", response.Status, strings.TrimSpace(string(response.Body)))
 }
 
-func parseResponse(raw []byte) (FetchResult, error) {
-	var response graphQLResponse
-	if err := json.Unmarshal(raw, &response); err != nil {
-		return FetchResult{}, fmt.Errorf("decode graphql response: %w", err)
+const mergedQuery = `query WaybarGitHubMergedPullRequests($ids: [ID!]!) {
+  nodes(ids: $ids) {
+    ... on PullRequest {
+      id
+      number
+      title
+      url
+      merged
+      repository {
+        nameWithOwner
+      }
+    }
+  }
+}`
+
+// FetchMergedPullRequests returns the pull requests among ids that have been
+// merged, used to tell merges apart from closes once they leave the search.
+func FetchMergedPullRequests(ctx context.Context, cfg config.Runtime, mode AuthMode, ids []string) ([]PullRequest, error) {
+	if len(ids) == 0 {
+		return nil, nil
+	}
+	raw, err := doGraphQL(ctx, cfg, mode, mergedQuery, map[string]any{"ids": ids})
+	if err != nil {
+		return nil, err
 	}
 
-	if len(response.Errors) > 0 {
-		return FetchResult{}, fmt.Errorf("graphql error: %s", strings.TrimSpace(response.Errors[0].Message))
+	var response struct {
+		Data struct {
+			Nodes []*struct {
+				ID         string `json:"id"`
+				Number     int    `json:"number"`
+				Title      string `json:"title"`
+				URL        string `json:"url"`
+				Merged     bool   `json:"merged"`
+				Repository struct {
+					NameWithOwner string `json:"nameWithOwner"`
+				} `json:"repository"`
+			} `json:"nodes"`
+		} `json:"data"`
+		Errors []struct {
+			Message string `json:"message"`
+		} `json:"errors"`
+	}
+	if err := json.Unmarshal(raw, &response); err != nil {
+		return nil, fmt.Errorf("decode graphql response: %w", err)
+	}
+	if len(response.Errors) > 0 && len(response.Data.Nodes) == 0 {
+		return nil, fmt.Errorf("graphql error: %s", strings.TrimSpace(response.Errors[0].Message))
 	}
 
-	items := make([]PullRequest, 0, len(response.Data.Search.Nodes))
-	for _, node := range response.Data.Search.Nodes {
-		if strings.TrimSpace(node.URL) == "" {
+	var merged []PullRequest
+	for _, node := range response.Data.Nodes {
+		// Deleted or inaccessible nodes come back as null.
+		if node == nil || !node.Merged {
 			continue
 		}
-
-		items = append(items, PullRequest{
+		merged = append(merged, PullRequest{
 			ID:         strings.TrimSpace(node.ID),
 			Number:     node.Number,
 			Title:      sanitize(node.Title),
 			URL:        strings.TrimSpace(node.URL),
 			Repository: sanitize(node.Repository.NameWithOwner),
-			IsDraft:    node.IsDraft,
-			UpdatedAt:  strings.TrimSpace(node.UpdatedAt),
 		})
 	}
+	return merged, nil
+}
+
+type searchPage struct {
+	viewer    string
+	rateLimit *RateLimit
+	sections  map[int]searchResult
+}
+
+func decodeSearchPage(raw []byte, searches []config.Search, pending []int) (searchPage, error) {
+	var response graphQLResponse
+	if err := json.Unmarshal(raw, &response); err != nil {
+		return searchPage{}, fmt.Errorf("decode graphql response: %w", err)
+	}
+
+	if len(response.Errors) > 0 {
+		// The primary GraphQL budget running out is reported with a 200 status.
+		if response.Errors[0].Type == "RATE_LIMITED" {
+			return searchPage{}, &RateLimitError{Message: sanitize(response.Errors[0].Message)}
+		}
+		// Errors on single nodes, such as repositories behind SAML enforcement,
+		// come with the rest of the results, which are still worth showing.
+		if !hasSearches(response.Data, pending) {
+			return searchPage{}, fmt.Errorf("graphql error: %s", strings.TrimSpace(response.Errors[0].Message))
+		}
+		slog.Warn("github graphql returned partial data", "errors", len(response.Errors), "error", sanitize(response.Errors[0].Message))
+	}
+
+	page := searchPage{sections: make(map[int]searchResult, len(pending))}
+	var viewer struct {
+		Login string `json:"login"`
+	}
+	if rawViewer, ok := response.Data["viewer"]; ok {
+		if err := json.Unmarshal(rawViewer, &viewer); err != nil {
+			return searchPage{}, fmt.Errorf("decode graphql viewer: %w", err)
+		}
+	}
+	page.viewer = viewer.Login
+
+	if rawLimit, ok := response.Data["rateLimit"]; ok && string(rawLimit) != "null" {
+		var limit RateLimit
+		if err := json.Unmarshal(rawLimit, &limit); err != nil {
+			return searchPage{}, fmt.Errorf("decode graphql rate limit: %w", err)
+		}
+		page.rateLimit = &limit
+	}
+
+	for _, i := range pending {
+		search := searches[i]
+		rawSection, ok := response.Data[fmt.Sprintf("s%d", i)]
+		if !ok || string(rawSection) == "null" {
+			return searchPage{}, fmt.Errorf("graphql response missing search %q", search.Name)
+		}
+		var section searchResult
+		if err := json.Unmarshal(rawSection, &section); err != nil {
+			return searchPage{}, fmt.Errorf("decode graphql search %q: %w", search.Name, err)
+		}
+		page.sections[i] = section
+	}
+	return page, nil
+}
+
+// hasSearches reports whether data holds every pending search.
+func hasSearches(data map[string]json.RawMessage, pending []int) bool {
+	for _, i := range pending {
+		if raw, ok := data[fmt.Sprintf("s%d", i)]; !ok || string(raw) == "null" {
+			return false
+		}
+	}
+	return true
+}
+
+// buildResult filters the fetched nodes and keeps the top rows, at most maxItems
+// shared between the searches. Section counts cover every fetched node that
+// passed the filter; the total counts each item once however many searches
+// returned it.
+func buildResult(pages []searchPages, searches []config.Search, viewer string, maxItems int, filter config.Filter) FetchResult {
+	result := FetchResult{Items: []PullRequest{}}
+	perRepo := make(map[string]int)
+	kept := make(map[string]bool)
+	listed := make([][]searchNode, len(searches))
+	for i, search := range searches {
+		added, hidden, capped := 0, 0, 0
+		for _, node := range pages[i].nodes {
+			if strings.TrimSpace(node.URL) == "" {
+				continue
+			}
+			added++
+			repoKey := strings.ToLower(sanitize(node.Repository.NameWithOwner))
+			if !filter.Allows(repoKey) || (node.IsDraft && filter.Drafts == config.DraftsHide) {
+				hidden++
+				continue
+			}
+			// An item returned by several searches counts once towards the per-repository cap.
+			if filter.MaxPerRepo > 0 && !kept[node.ID] && perRepo[repoKey] >= filter.MaxPerRepo {
+				capped++
+				continue
+			}
+			if !kept[node.ID] {
+				kept[node.ID] = true
+				perRepo[repoKey]++
+			}
+			listed[i] = append(listed[i], node)
+		}
+
+		result.Searches = append(result.Searches, SearchCount{Name: search.Name, Count: added - hidden - capped, Hidden: hidden, Capped: capped, More: pages[i].more})
+	}
+	result.Count = len(kept)
+
+	available := make([]int, len(listed))
+	for i := range listed {
+		available[i] = len(listed[i])
+	}
+	for i, rows := range shareRows(available, maxItems) {
+		for _, node := range listed[i][:rows] {
+			result.Items = append(result.Items, pullRequest(node, searches[i].Name, viewer))
+		}
+	}
+	return result
+}
+
+// shareRows splits maxItems rows between sections one row at a time, so each
+// gets an even share and the rows a short section cannot fill go to the others.
+func shareRows(available []int, maxItems int) []int {
+	rows := make([]int, len(available))
+	for total := 0; total < maxItems; {
+		added := false
+		for i := range available {
+			if total < maxItems && rows[i] < available[i] {
+				rows[i]++
+				total++
+				added = true
+			}
+		}
+		if !added {
+			break
+		}
+	}
+	return rows
+}
+
+// pullRequest converts a search node returned by the named search.
+func pullRequest(node searchNode, search, viewer string) PullRequest {
+	reviews := make([]Review, 0, len(node.LatestReviews.Nodes))
+	for _, review := range node.LatestReviews.Nodes {
+		reviews = append(reviews, Review{Author: sanitize(review.Author.Login), State: strings.TrimSpace(review.State)})
+	}
+	var reviewers []string
+	reviewRequested := false
+	for _, request := range node.ReviewRequests.Nodes {
+		if name := sanitize(request.RequestedReviewer.Login + request.RequestedReviewer.Name); name != "" {
+			reviewers = append(reviewers, name)
+		}
+		if viewer != "" && strings.EqualFold(request.RequestedReviewer.Login, viewer) {
+			reviewRequested = true
+		}
+	}
+
+	// The latest request per reviewer counts; earlier ones were re-requested.
+	requestedAt := make(map[string]time.Time)
+	for _, event := range node.TimelineItems.Nodes {
+		name := strings.ToLower(sanitize(event.RequestedReviewer.Login + event.RequestedReviewer.Name))
+		if name != "" && event.CreatedAt.After(requestedAt[name]) {
+			requestedAt[name] = event.CreatedAt
+		}
+	}
+	var reviewRequestedAt time.Time
+	if reviewRequested {
+		reviewRequestedAt = requestedAt[strings.ToLower(viewer)]
+	} else {
+		for _, name := range reviewers {
+			if at := requestedAt[strings.ToLower(name)]; !at.IsZero() && (reviewRequestedAt.IsZero() || at.Before(reviewRequestedAt)) {
+				reviewRequestedAt = at
+			}
+		}
+	}
+
+	var labels []string
+	for _, label := range node.Labels.Nodes {
+		if name := sanitize(label.Name); name != "" {
+			labels = append(labels, name)
+		}
+	}
 
-	count := response.Data.Search.IssueCount
-	if count <= 0 {
-		count = len(items)
+	return PullRequest{
+		ID:         strings.TrimSpace(node.ID),
+		Number:     node.Number,
+		Title:      sanitize(node.Title),
+		URL:        strings.TrimSpace(node.URL),
+		Repository: sanitize(node.Repository.NameWithOwner),
+		IsDraft:    node.IsDraft,
+		UpdatedAt:  strings.TrimSpace(node.UpdatedAt),
+		Search:     search,
+		Kind:       nodeKind(node.Typename),
+		Labels:     labels,
+		Category:   sanitize(node.Category.Name),
+		Answered:   node.IsAnswered,
+		CIState:    ciState(node.Commits.Nodes),
+		Authored:   viewer != "" && strings.EqualFold(node.Author.Login, viewer),
+
+		ReviewDecision:     strings.TrimSpace(node.ReviewDecision),
+		Mergeable:          strings.TrimSpace(node.Mergeable),
+		Reviews:            reviews,
+		RequestedReviewers: reviewers,
+		ReviewRequested:    reviewRequested,
+		ReviewRequestedAt:  reviewRequestedAt,
+		CommentedAt:        commentedAt(viewer, node.Comments.Nodes, node.Reviews.Nodes),
 	}
+}
 
-	return FetchResult{Count: count, Items: items}, nil
+// commentedAt lists when someone other than viewer commented or reviewed.
+func commentedAt(viewer string, activity ...[]activityNode) []time.Time {
+	var times []time.Time
+	for _, nodes := range activity {
+		for _, node := range nodes {
+			if node.CreatedAt.IsZero() || (viewer != "" && strings.EqualFold(node.Author.Login, viewer)) {
+				continue
+			}
+			times = append(times, node.CreatedAt)
+		}
+	}
+	return times
+}
+
+func nodeKind(typename string) string {
+	switch typename {
+	case "Issue":
+		return KindIssue
+	case "Discussion":
+		return KindDiscussion
+	default:
+		return KindPullRequest
+	}
+}
+
+func ciState(commits []commitNode) string {
+	if len(commits) == 0 || commits[0].Commit.StatusCheckRollup == nil {
+		return ""
+	}
+	return strings.ToUpper(strings.TrimSpace(commits[0].Commit.StatusCheckRollup.State))
 }
 
 func sanitize(value string) string {
@@ -0,0 +1,840 @@
+package github
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/rbright/waybar-github/internal/config"
+	"github.com/rbright/waybar-github/internal/githubtest"
+)
+
+func testConfig(graphQLURL string) config.Runtime {
+	return config.Runtime{
+		Host:       "github.com",
+		GraphQLURL: graphQLURL,
+		Token:      "test-token",
+		MaxItems:   8,
+		FetchLimit: 100,
+		Timeout:    5 * time.Second,
+		Searches:   []config.Search{{Name: "Pull requests", Query: "is:open is:pr involves:@me", Type: config.SearchIssues}},
+	}
+}
+
+// resetGHTokens forgets tokens cached by earlier tests.
+func resetGHTokens(t *testing.T) {
+	t.Helper()
+	ResetGHTokens()
+	t.Cleanup(ResetGHTokens)
+}
+
+func TestFetchPullRequestsDecodesRecordedSearch(t *testing.T) {
+	server := githubtest.NewServer(t, githubtest.Reply(t, http.StatusOK, "search_pull_requests"))
+
+	result, err := FetchPullRequests(context.Background(), testConfig(server.URL), AuthToken)
+	if err != nil {
+		t.Fatalf("fetch: %v", err)
+	}
+
+	if result.Count != 3 || len(result.Items) != 3 {
+		t.Fatalf("expected 3 results, got count %d and %d items", result.Count, len(result.Items))
+	}
+	if len(result.Searches) != 1 || result.Searches[0].Count != 3 || result.Searches[0].More || result.Searches[0].Host != "github.com" {
+		t.Fatalf("unexpected searches %+v", result.Searches)
+	}
+	if result.RateLimit == nil || result.RateLimit.Remaining != 4987 {
+		t.Fatalf("unexpected rate limit %+v", result.RateLimit)
+	}
+
+	fix := result.Items[0]
+	if fix.ID != "PR_kwDOAbc0042" || fix.Repository != "acme/app" || fix.Number != 42 || fix.Kind != KindPullRequest {
+		t.Fatalf("unexpected first item %+v", fix)
+	}
+	if !fix.Authored || !fix.CIFailing() || !fix.Ready() {
+		t.Fatalf("expected authored, failing and ready, got %+v", fix)
+	}
+	// octocat's own comment is not activity to be told about.
+	wantActivity := []time.Time{time.Date(2024, 4, 29, 11, 0, 0, 0, time.UTC), time.Date(2024, 4, 29, 12, 0, 0, 0, time.UTC)}
+	if !slices.EqualFunc(fix.CommentedAt, wantActivity, time.Time.Equal) {
+		t.Fatalf("expected hubot's comment and review, got %v", fix.CommentedAt)
+	}
+	if len(fix.Reviews) != 1 || fix.Reviews[0] != (Review{Author: "hubot", State: ReviewApproved}) {
+		t.Fatalf("unexpected reviews %+v", fix.Reviews)
+	}
+
+	requested := result.Items[1]
+	if requested.Title != `Escape <menu> & "labels"` {
+		t.Fatalf("unexpected title %q", requested.Title)
+	}
+	if !requested.ReviewRequested || !slices.Equal(requested.RequestedReviewers, []string{"octocat", "platform"}) {
+		t.Fatalf("expected review requested from octocat and platform, got %+v", requested)
+	}
+	if want := time.Date(2024, 4, 29, 8, 0, 0, 0, time.UTC); !requested.ReviewRequestedAt.Equal(want) {
+		t.Fatalf("expected review requested at %s, got %s", want, requested.ReviewRequestedAt)
+	}
+
+	draft := result.Items[2]
+	if !draft.IsDraft || draft.Title != "WIP: collapse whitespace" || !draft.CIPending() {
+		t.Fatalf("unexpected draft %+v", draft)
+	}
+
+	requests := server.Requests()
+	if len(requests) != 1 {
+		t.Fatalf("expected one request, got %d", len(requests))
+	}
+	if requests[0].Authorization != "Bearer test-token" {
+		t.Fatalf("unexpected authorization %q", requests[0].Authorization)
+	}
+	if requests[0].Variables["q0"] != "is:open is:pr involves:@me" || requests[0].Variables["limit"] != float64(100) {
+		t.Fatalf("unexpected variables %v", requests[0].Variables)
+	}
+	if _, ok := requests[0].Variables["a0"]; ok {
+		t.Fatalf("first page must not send a cursor, got %v", requests[0].Variables)
+	}
+}
+
+// TestSearchFixtureMatchesQuery keeps the recorded search in step with the
+// query: every field in the fixture has to be one the query asks for.
+func TestSearchFixtureMatchesQuery(t *testing.T) {
+	var response struct {
+		Data map[string]json.RawMessage `json:"data"`
+	}
+	if err := json.Unmarshal(githubtest.Fixture(t, "search_pull_requests"), &response); err != nil {
+		t.Fatalf("decode fixture: %v", err)
+	}
+	var section any
+	if err := json.Unmarshal(response.Data["s0"], &section); err != nil {
+		t.Fatalf("decode section: %v", err)
+	}
+
+	query := buildSearchQuery(testConfig("").Searches, []int{0})
+	var walk func(value any)
+	walk = func(value any) {
+		switch value := value.(type) {
+		case map[string]any:
+			for field, child := range value {
+				if !regexp.MustCompile(`\b` + regexp.QuoteMeta(field) + `\b`).MatchString(query) {
+					t.Errorf("fixture field %q is not in the search query", field)
+				}
+				walk(child)
+			}
+		case []any:
+			for _, child := range value {
+				walk(child)
+			}
+		}
+	}
+	walk(section)
+}
+
+func TestBuildSearchQuery(t *testing.T) {
+	searches := []config.Search{
+		{Name: "Review requested", Query: "review-requested:@me", Type: config.SearchIssues},
+		{Name: "Mine", Query: "author:@me", Type: config.SearchIssues},
+		{Name: "Discussions", Query: "involves:@me", Type: config.SearchDiscussions},
+	}
+
+	query := buildSearchQuery(searches, []int{0, 2})
+	for _, want := range []string{
+		"query WaybarGitHubPullRequests($limit: Int!, $q0: String!, $a0: String, $q2: String!, $a2: String)",
+		"s0: search(type: ISSUE, query: $q0, first: $limit, after: $a0)",
+		"s2: search(type: DISCUSSION, query: $q2, first: $limit, after: $a2)",
+		"fragment searchFields on SearchResultItemConnection",
+	} {
+		if !strings.Contains(query, want) {
+			t.Fatalf("expected query to contain %q, got\n%s", want, query)
+		}
+	}
+	if strings.Contains(query, "s1:") || strings.Contains(query, "$q1") {
+		t.Fatalf("expected only pending searches, got\n%s", query)
+	}
+}
+
+// A pull request that several searches return is one pull request.
+func TestFetchPullRequestsCountsEachItemOnce(t *testing.T) {
+	server := githubtest.NewServer(t, githubtest.Reply(t, http.StatusOK, "search_sections"))
+	cfg := testConfig(server.URL)
+	cfg.Searches = []config.Search{
+		{Name: "Review requested", Query: "is:open is:pr review-requested:@me", Type: config.SearchIssues},
+		{Name: "Involved", Query: "is:open is:pr involves:@me", Type: config.SearchIssues},
+	}
+
+	result, err := FetchPullRequests(context.Background(), cfg, AuthToken)
+	if err != nil {
+		t.Fatalf("fetch: %v", err)
+	}
+	if result.Count != 3 {
+		t.Fatalf("expected 3 distinct pull requests, got %d", result.Count)
+	}
+	if result.Searches[0].Count != 1 || result.Searches[1].Count != 3 {
+		t.Fatalf("expected sections of 1 and 3, got %+v", result.Searches)
+	}
+	if len(result.Items) != 4 || result.Items[0].Search != "Review requested" || result.Items[1].Search != "Involved" {
+		t.Fatalf("expected every row of both sections, got %+v", result.Items)
+	}
+}
+
+func TestShareRows(t *testing.T) {
+	tests := []struct {
+		name      string
+		available []int
+		maxItems  int
+		want      []int
+	}{
+		{name: "even share", available: []int{5, 5}, maxItems: 4, want: []int{2, 2}},
+		{name: "short section gives its rows away", available: []int{1, 10}, maxItems: 6, want: []int{1, 5}},
+		{name: "odd row goes first", available: []int{3, 3, 3}, maxItems: 4, want: []int{2, 1, 1}},
+		{name: "fewer rows than sections", available: []int{2, 2, 2}, maxItems: 2, want: []int{1, 1, 0}},
+		{name: "everything fits", available: []int{1, 0, 2}, maxItems: 8, want: []int{1, 0, 2}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := shareRows(tt.available, tt.maxItems); !slices.Equal(got, tt.want) {
+				t.Fatalf("expected %v, got %v", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestFetchPullRequestsFollowsCursor(t *testing.T) {
+	server := githubtest.NewServer(t,
+		githubtest.Reply(t, http.StatusOK, "search_page_1"),
+		githubtest.Reply(t, http.StatusOK, "search_page_2"),
+	)
+	cfg := testConfig(server.URL)
+	cfg.MaxItems = 1
+
+	result, err := FetchPullRequests(context.Background(), cfg, AuthToken)
+	if err != nil {
+		t.Fatalf("fetch: %v", err)
+	}
+	if result.Count != 3 || len(result.Items) != 1 || result.Searches[0].More {
+		t.Fatalf("expected 3 counted, 1 listed and no more, got count %d, %d items, %+v", result.Count, len(result.Items), result.Searches)
+	}
+	if result.RateLimit == nil || result.RateLimit.Cost != 2 || result.RateLimit.Remaining != 4986 {
+		t.Fatalf("expected the cost of both pages and the last budget, got %+v", result.RateLimit)
+	}
+
+	requests := server.Requests()
+	if len(requests) != 2 {
+		t.Fatalf("expected two requests, got %d", len(requests))
+	}
+	if requests[1].Variables["a0"] != "Y3Vyc29yOjI=" {
+		t.Fatalf("expected the second page to resume after the cursor, got %v", requests[1].Variables)
+	}
+	if !strings.Contains(requests[1].Query, "after: $a0") {
+		t.Fatalf("expected the cursor in the query, got %s", requests[1].Query)
+	}
+}
+
+func TestFetchPullRequestsStopsAtFetchLimit(t *testing.T) {
+	server := githubtest.NewServer(t, githubtest.Reply(t, http.StatusOK, "search_page_1"))
+	cfg := testConfig(server.URL)
+	cfg.FetchLimit = 2
+
+	result, err := FetchPullRequests(context.Background(), cfg, AuthToken)
+	if err != nil {
+		t.Fatalf("fetch: %v", err)
+	}
+	if len(server.Requests()) != 1 {
+		t.Fatalf("expected one request, got %d", len(server.Requests()))
+	}
+	if result.Count != 2 || !result.Searches[0].More {
+		t.Fatalf("expected 2 counted with more beyond the limit, got %+v", result.Searches)
+	}
+	if got := CountLabel(result.Count, result.Searches...); got != "2+" {
+		t.Fatalf("expected label 2+, got %q", got)
+	}
+}
+
+// editedReply answers with the named fixture after edit has changed its data.
+func editedReply(t *testing.T, name string, edit func(data map[string]any)) githubtest.Response {
+	t.Helper()
+	var payload map[string]any
+	if err := json.Unmarshal(githubtest.Fixture(t, name), &payload); err != nil {
+		t.Fatalf("decode fixture %s: %v", name, err)
+	}
+	edit(payload["data"].(map[string]any))
+	body, err := json.Marshal(payload)
+	if err != nil {
+		t.Fatalf("encode fixture %s: %v", name, err)
+	}
+	reply := githubtest.Reply(t, http.StatusOK, name)
+	reply.Body = body
+	return reply
+}
+
+func setPageInfo(data map[string]any, section string, hasNext bool, cursor any) {
+	data[section].(map[string]any)["pageInfo"] = map[string]any{"hasNextPage": hasNext, "endCursor": cursor}
+}
+
+func TestFetchPullRequestsPageStops(t *testing.T) {
+	tests := []struct {
+		name       string
+		reply      githubtest.Response
+		fetchLimit int
+		count      int
+		more       bool
+	}{
+		// page_1 holds two results and has a next page.
+		{name: "truncated above the limit", reply: githubtest.Reply(t, http.StatusOK, "search_page_1"), fetchLimit: 1, count: 1, more: true},
+		{name: "limit reached with a next page", reply: githubtest.Reply(t, http.StatusOK, "search_page_1"), fetchLimit: 2, count: 2, more: true},
+		{name: "limit reached on the last page", reply: githubtest.Reply(t, http.StatusOK, "search_pull_requests"), fetchLimit: 3, count: 3},
+		{name: "next page without a cursor", reply: editedReply(t, "search_page_1", func(data map[string]any) {
+			setPageInfo(data, "s0", true, "")
+		}), fetchLimit: 100, count: 2},
+		{name: "next page with a null cursor", reply: editedReply(t, "search_page_1", func(data map[string]any) {
+			setPageInfo(data, "s0", true, nil)
+		}), fetchLimit: 100, count: 2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// A second request would get a server error and fail the fetch.
+			server := githubtest.NewServer(t, tt.reply, githubtest.Reply(t, http.StatusBadGateway, "server_error"))
+			cfg := testConfig(server.URL)
+			cfg.FetchLimit = tt.fetchLimit
+
+			result, err := FetchPullRequests(context.Background(), cfg, AuthToken)
+			if err != nil {
+				t.Fatalf("fetch: %v", err)
+			}
+			if requests := server.Requests(); len(requests) != 1 {
+				t.Fatalf("expected one request, got %d", len(requests))
+			}
+			if result.Count != tt.count || result.Searches[0].More != tt.more {
+				t.Fatalf("expected count %d and more %v, got %d and %+v", tt.count, tt.more, result.Count, result.Searches)
+			}
+		})
+	}
+}
+
+func TestFetchPullRequestsRequeriesOnlyPendingSearches(t *testing.T) {
+	// The first search is complete after one page; the second has another.
+	first := editedReply(t, "search_sections", func(data map[string]any) {
+		setPageInfo(data, "s1", true, "Y3Vyc29yOjM=")
+	})
+	second := editedReply(t, "search_page_2", func(data map[string]any) {
+		data["s1"] = data["s0"]
+		delete(data, "s0")
+		// page_2 repeats #43, which the first page of the search already has.
+		node := data["s1"].(map[string]any)["nodes"].([]any)[0].(map[string]any)
+		node["id"], node["number"], node["url"] = "PR_kwDOAbc0044", 44, "https://github.com/acme/app/pull/44"
+	})
+	server := githubtest.NewServer(t, first, second, githubtest.Reply(t, http.StatusBadGateway, "server_error"))
+	cfg := testConfig(server.URL)
+	cfg.Searches = []config.Search{
+		{Name: "Review requested", Query: "is:open is:pr review-requested:@me", Type: config.SearchIssues},
+		{Name: "Involved", Query: "is:open is:pr involves:@me", Type: config.SearchIssues},
+	}
+
+	result, err := FetchPullRequests(context.Background(), cfg, AuthToken)
+	if err != nil {
+		t.Fatalf("fetch: %v", err)
+	}
+	requests := server.Requests()
+	if len(requests) != 2 {
+		t.Fatalf("expected two requests, got %d", len(requests))
+	}
+	followUp := requests[1]
+	if _, ok := followUp.Variables["q0"]; ok || strings.Contains(followUp.Query, "$q0") {
+		t.Fatalf("expected the complete search to be left out, got %v in %s", followUp.Variables, followUp.Query)
+	}
+	if followUp.Variables["q1"] != "is:open is:pr involves:@me" || followUp.Variables["a1"] != "Y3Vyc29yOjM=" {
+		t.Fatalf("expected the pending search after its cursor, got %v", followUp.Variables)
+	}
+	if result.Count != 4 || result.Searches[0].Count != 1 || result.Searches[1].Count != 4 {
+		t.Fatalf("expected #44 added to the pending search only, got count %d and %+v", result.Count, result.Searches)
+	}
+}
+
+func TestFetchPullRequestsPartialData(t *testing.T) {
+	server := githubtest.NewServer(t, githubtest.Reply(t, http.StatusOK, "partial_data"))
+
+	result, err := FetchPullRequests(context.Background(), testConfig(server.URL), AuthToken)
+	if err != nil {
+		t.Fatalf("expected the readable results, got %v", err)
+	}
+	if result.Count != 1 || len(result.Items) != 1 || result.Items[0].Number != 42 {
+		t.Fatalf("expected only the accessible pull request, got %+v", result.Items)
+	}
+}
+
+func TestFetchPullRequestsFailures(t *testing.T) {
+	tests := []struct {
+		name      string
+		reply     githubtest.Response
+		want      string
+		rateLimit bool
+	}{
+		{name: "graphql errors", reply: githubtest.Reply(t, http.StatusOK, "graphql_errors"), want: "graphql error: Field 'reviewRequested' doesn't exist"},
+		{name: "rate limited", reply: githubtest.Reply(t, http.StatusOK, "rate_limited"), want: "rate limit", rateLimit: true},
+		{name: "unauthorized", reply: githubtest.Reply(t, http.StatusUnauthorized, "unauthorized"), want: "github graphql status 401: {"},
+		{name: "server error", reply: githubtest.Reply(t, http.StatusBadGateway, "server_error"), want: "github graphql status 502"},
+		{name: "missing search", reply: githubtest.Response{Status: http.StatusOK, Body: []byte(`{"data":{"viewer":{"login":"octocat"},"s0":null}}`)}, want: `missing search "Pull requests"`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := githubtest.NewServer(t, tt.reply)
+
+			_, err := FetchPullRequests(context.Background(), testConfig(server.URL), AuthToken)
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Fatalf("expected error containing %q, got %v", tt.want, err)
+			}
+			if _, ok := AsRateLimit(err); ok != tt.rateLimit {
+				t.Fatalf("expected rate limit %v, got %v", tt.rateLimit, err)
+			}
+		})
+	}
+}
+
+func TestFetchPullRequestsThroughGH(t *testing.T) {
+	resetGHTokens(t)
+	gh := githubtest.InstallGH(t)
+	gh.Respond(t, "api graphql", githubtest.Reply(t, http.StatusOK, "search_pull_requests").Included(), 0)
+
+	cfg := testConfig("http://127.0.0.1:1/unused")
+	cfg.Token = ""
+	result, err := FetchPullRequests(context.Background(), cfg, AuthGH)
+	if err != nil {
+		t.Fatalf("fetch: %v", err)
+	}
+	if result.Count != 3 || result.Items[0].Number != 42 || result.RateLimit == nil {
+		t.Fatalf("unexpected result %+v", result)
+	}
+
+	var api []string
+	for _, call := range gh.Calls(t) {
+		if slices.Equal(call[:2], []string{"api", "graphql"}) {
+			api = call
+		}
+	}
+	for _, want := range []string{"--hostname", "github.com", "q0=is:open is:pr involves:@me", "limit=100", "--include"} {
+		if !slices.Contains(api, want) {
+			t.Fatalf("expected gh api argument %q, got %q", want, api)
+		}
+	}
+}
+
+func TestFetchPullRequestsThroughGHFailures(t *testing.T) {
+	tests := []struct {
+		name      string
+		reply     githubtest.Response
+		exit      int
+		want      string
+		rateLimit bool
+	}{
+		{name: "unauthorized", reply: githubtest.Reply(t, http.StatusUnauthorized, "unauthorized"), exit: 1, want: "github graphql status 401"},
+		{name: "secondary rate limit", reply: githubtest.Response{Status: http.StatusForbidden, Header: http.Header{"Retry-After": {"60"}}, Body: []byte(`{"message":"You have exceeded a secondary rate limit."}`)}, exit: 1, want: "retry in 1m0s", rateLimit: true},
+		{name: "no output", exit: 4, want: "gh graphql request failed"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resetGHTokens(t)
+			gh := githubtest.InstallGH(t)
+			output := ""
+			if tt.reply.Status != 0 {
+				output = tt.reply.Included()
+			}
+			gh.Respond(t, "api graphql", output, tt.exit)
+
+			_, err := FetchPullRequests(context.Background(), testConfig(""), AuthGH)
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Fatalf("expected error containing %q, got %v", tt.want, err)
+			}
+			if _, ok := AsRateLimit(err); ok != tt.rateLimit {
+				t.Fatalf("expected rate limit %v, got %v", tt.rateLimit, err)
+			}
+		})
+	}
+}
+
+func TestFetchPullRequestsRetriesRejectedGHTokenThroughGH(t *testing.T) {
+	resetGHTokens(t)
+	gh := githubtest.InstallGH(t)
+	gh.Respond(t, "auth token", "gho_revoked\n", 0)
+	gh.Respond(t, "api graphql", githubtest.Reply(t, http.StatusOK, "search_pull_requests").Included(), 0)
+	server := githubtest.NewServer(t, githubtest.Reply(t, http.StatusUnauthorized, "unauthorized"))
+
+	result, err := FetchPullRequests(context.Background(), testConfig(server.URL), AuthGH)
+	if err != nil {
+		t.Fatalf("expected gh to retry the rejected request, got %v", err)
+	}
+	if result.Count != 3 {
+		t.Fatalf("expected the retried search, got %+v", result)
+	}
+	if requests := server.Requests(); len(requests) != 1 || requests[0].Authorization != "Bearer gho_revoked" {
+		t.Fatalf("expected one request with gh's token, got %+v", requests)
+	}
+	if _, ok := ghTokens.Load("github.com"); ok {
+		t.Fatal("expected the rejected token to be forgotten")
+	}
+
+	// gh's own token is rejected too: the 401 is reported, not retried again.
+	gh.Respond(t, "api graphql", githubtest.Reply(t, http.StatusUnauthorized, "unauthorized").Included(), 1)
+	if _, err := FetchPullRequests(context.Background(), testConfig(server.URL), AuthGH); err == nil || !strings.Contains(err.Error(), "github graphql status 401") {
+		t.Fatalf("expected the 401 from gh, got %v", err)
+	}
+	var api int
+	for _, call := range gh.Calls(t) {
+		if slices.Equal(call[:2], []string{"api", "graphql"}) {
+			api++
+		}
+	}
+	if api != 2 {
+		t.Fatalf("expected one gh retry per fetch, got %d", api)
+	}
+}
+
+func TestApprovePullRequestRetriesRejectedGHTokenThroughGH(t *testing.T) {
+	resetGHTokens(t)
+	gh := githubtest.InstallGH(t)
+	gh.Respond(t, "auth token", "gho_revoked\n", 0)
+	gh.Respond(t, "api --hostname", githubtest.Response{Status: http.StatusOK, Body: []byte(`{"state":"APPROVED"}`)}.Included(), 0)
+	server := githubtest.NewServer(t, githubtest.Reply(t, http.StatusUnauthorized, "unauthorized"))
+	cfg := testConfig(server.URL)
+	cfg.APIURL = strings.TrimSuffix(server.URL, "/graphql")
+
+	if err := ApprovePullRequest(context.Background(), cfg, AuthGH, "acme/api", 7); err != nil {
+		t.Fatalf("expected gh to retry the rejected approval, got %v", err)
+	}
+	var retried []string
+	for _, call := range gh.Calls(t) {
+		if call[0] == "api" {
+			retried = call
+		}
+	}
+	if !slices.Contains(retried, "/repos/acme/api/pulls/7/reviews") || !slices.Contains(retried, "POST") {
+		t.Fatalf("expected gh to post the review, got %q", retried)
+	}
+}
+
+func TestDetectAuth(t *testing.T) {
+	tests := []struct {
+		name  string
+		setup func(t *testing.T, gh *githubtest.GH)
+		token string
+		want  AuthMode
+		// ghToken is the token requests in gh mode carry; empty when they run gh.
+		ghToken string
+	}{
+		{
+			name:    "gh token variable",
+			setup:   func(t *testing.T, _ *githubtest.GH) { t.Setenv("GH_TOKEN", "env-token") },
+			want:    AuthGH,
+			ghToken: "env-token",
+		},
+		{
+			name: "hosts file",
+			setup: func(t *testing.T, gh *githubtest.GH) {
+				hosts := "github.com:\n    oauth_token: file-token\n    user: octocat\n    users:\n        octocat:\n            oauth_token: user-token\n"
+				if err := os.WriteFile(filepath.Join(gh.Dir(), "hosts.yml"), []byte(hosts), 0o600); err != nil {
+					t.Fatal(err)
+				}
+			},
+			want:    AuthGH,
+			ghToken: "file-token",
+		},
+		{
+			name:    "gh auth token",
+			setup:   func(t *testing.T, gh *githubtest.GH) { gh.Respond(t, "auth token", "keyring-token\n", 0) },
+			want:    AuthGH,
+			ghToken: "keyring-token",
+		},
+		{
+			name:  "gh signed in without a readable token",
+			setup: func(t *testing.T, gh *githubtest.GH) { gh.Respond(t, "auth status", "", 0) },
+			want:  AuthGH,
+		},
+		{
+			name:  "configured token",
+			token: "configured-token",
+			want:  AuthToken,
+		},
+		{
+			name: "nothing",
+			want: AuthNone,
+		},
+		{
+			name:  "gh missing",
+			setup: func(t *testing.T, _ *githubtest.GH) { t.Setenv("PATH", t.TempDir()) },
+			token: "configured-token",
+			want:  AuthToken,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resetGHTokens(t)
+			gh := githubtest.InstallGH(t)
+			if tt.setup != nil {
+				tt.setup(t, gh)
+			}
+			cfg := testConfig("")
+			cfg.Token = tt.token
+
+			if got := DetectAuth(context.Background(), cfg); got != tt.want {
+				t.Fatalf("expected %s, got %s", tt.want, got)
+			}
+			ghCfg, ok := withGHToken(context.Background(), cfg)
+			if ok != (tt.ghToken != "") || (ok && ghCfg.Token != tt.ghToken) {
+				t.Fatalf("expected gh token %q, got %q (%v)", tt.ghToken, ghCfg.Token, ok)
+			}
+		})
+	}
+}
+
+// A failed `gh auth token` is remembered only briefly, so signing in to gh
+// later is picked up without a restart.
+func TestWithGHTokenRetriesAfterFailedLookup(t *testing.T) {
+	resetGHTokens(t)
+	gh := githubtest.InstallGH(t)
+	gh.Respond(t, "auth token", "", 1)
+	cfg := testConfig("")
+
+	if _, ok := withGHToken(context.Background(), cfg); ok {
+		t.Fatal("expected no token while gh fails")
+	}
+	gh.Respond(t, "auth token", "gho_fresh\n", 0)
+	if _, ok := withGHToken(context.Background(), cfg); ok {
+		t.Fatal("expected the failed lookup to be cached")
+	}
+	if calls := len(gh.Calls(t)); calls != 1 {
+		t.Fatalf("expected one gh call within the retry window, got %d", calls)
+	}
+
+	ttl := ghTokenMissTTL
+	ghTokenMissTTL = 0
+	t.Cleanup(func() { ghTokenMissTTL = ttl })
+	ghCfg, ok := withGHToken(context.Background(), cfg)
+	if !ok || ghCfg.Token != "gho_fresh" {
+		t.Fatalf("expected gh's new token after the retry window, got %q (%v)", ghCfg.Token, ok)
+	}
+	// A token read is kept, even past the retry window.
+	if _, ok := withGHToken(context.Background(), cfg); !ok || len(gh.Calls(t)) != 2 {
+		t.Fatalf("expected the token to be cached, got %v after %d gh calls", ok, len(gh.Calls(t)))
+	}
+}
+
+func TestCIState(t *testing.T) {
+	tests := []struct {
+		name    string
+		commits string
+		want    string
+		failing bool
+		pending bool
+	}{
+		{name: "success", commits: `[{"commit":{"statusCheckRollup":{"state":"SUCCESS"}}}]`, want: CIStateSuccess},
+		{name: "failure", commits: `[{"commit":{"statusCheckRollup":{"state":"FAILURE"}}}]`, want: CIStateFailure, failing: true},
+		{name: "error", commits: `[{"commit":{"statusCheckRollup":{"state":" error "}}}]`, want: CIStateError, failing: true},
+		{name: "pending", commits: `[{"commit":{"statusCheckRollup":{"state":"PENDING"}}}]`, want: CIStatePending, pending: true},
+		{name: "expected", commits: `[{"commit":{"statusCheckRollup":{"state":"EXPECTED"}}}]`, want: CIStateExpected, pending: true},
+		{name: "no checks", commits: `[{"commit":{"statusCheckRollup":null}}]`},
+		{name: "no commits", commits: `[]`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var commits []commitNode
+			if err := json.Unmarshal([]byte(tt.commits), &commits); err != nil {
+				t.Fatalf("decode commits: %v", err)
+			}
+			pr := PullRequest{CIState: ciState(commits)}
+			if pr.CIState != tt.want || pr.CIFailing() != tt.failing || pr.CIPending() != tt.pending {
+				t.Fatalf("expected %q failing=%v pending=%v, got %q failing=%v pending=%v", tt.want, tt.failing, tt.pending, pr.CIState, pr.CIFailing(), pr.CIPending())
+			}
+		})
+	}
+}
+
+func TestReviewSummary(t *testing.T) {
+	tests := []struct {
+		name  string
+		pr    PullRequest
+		want  string
+		ready bool
+	}{
+		{name: "ready", pr: PullRequest{ReviewDecision: ReviewApproved, Mergeable: "MERGEABLE"}, want: "ready to merge", ready: true},
+		{name: "approved with conflicts", pr: PullRequest{ReviewDecision: ReviewApproved, Mergeable: "CONFLICTING"}, want: "approved"},
+		{name: "approved draft", pr: PullRequest{IsDraft: true, ReviewDecision: ReviewApproved, Mergeable: "MERGEABLE"}, want: "approved"},
+		{name: "changes requested", pr: PullRequest{ReviewDecision: ReviewChangesRequested, RequestedReviewers: []string{"hubot"}}, want: "changes requested"},
+		{name: "one reviewer", pr: PullRequest{ReviewDecision: ReviewRequired, RequestedReviewers: []string{"hubot"}}, want: "awaiting 1 reviewer"},
+		{name: "several reviewers", pr: PullRequest{RequestedReviewers: []string{"hubot", "platform"}}, want: "awaiting 2 reviewers"},
+		{name: "review required", pr: PullRequest{ReviewDecision: ReviewRequired}, want: "review required"},
+		{name: "unknown", pr: PullRequest{}, want: ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.pr.ReviewSummary(); got != tt.want {
+				t.Fatalf("expected %q, got %q", tt.want, got)
+			}
+			if got := tt.pr.Ready(); got != tt.ready {
+				t.Fatalf("expected ready=%v, got %v", tt.ready, got)
+			}
+		})
+	}
+}
+
+func TestFetchNotificationsConditionalRequest(t *testing.T) {
+	page := githubtest.Reply(t, http.StatusOK, "notifications")
+	page.Header.Set("Last-Modified", "Tue, 30 Apr 2024 09:00:00 GMT")
+	page.Header.Set("X-Poll-Interval", "60")
+	page.Header.Set("Link", `<https://api.github.com/notifications?page=2>; rel="next"`)
+	server := githubtest.NewServer(t, page, githubtest.Response{Status: http.StatusNotModified, Header: http.Header{"X-Poll-Interval": {"120"}}})
+	cfg := testConfig(server.URL)
+	cfg.APIURL = strings.TrimSuffix(server.URL, "/graphql")
+
+	result, err := FetchNotifications(context.Background(), cfg, AuthToken, "")
+	if err != nil {
+		t.Fatalf("fetch: %v", err)
+	}
+	if result.NotModified || !result.More || result.PollInterval != time.Minute || result.LastModified != "Tue, 30 Apr 2024 09:00:00 GMT" {
+		t.Fatalf("unexpected result %+v", result)
+	}
+	want := []Notification{
+		{ID: "1001", Reason: "review_requested", Type: "PullRequest", Title: "Add retry budget", Repository: "acme/api", URL: "https://github.com/acme/api/pull/7", UpdatedAt: "2024-04-30T09:00:00Z"},
+		{ID: "1002", Reason: "mention", Type: "Discussion", Title: "Roadmap for v2", Repository: "acme/app", URL: "https://github.com/acme/app/discussions", UpdatedAt: "2024-04-29T16:30:00Z"},
+	}
+	if !slices.Equal(result.Items, want) {
+		t.Fatalf("expected %+v, got %+v", want, result.Items)
+	}
+
+	result, err = FetchNotifications(context.Background(), cfg, AuthToken, result.LastModified)
+	if err != nil {
+		t.Fatalf("fetch unchanged: %v", err)
+	}
+	if !result.NotModified || len(result.Items) != 0 || result.PollInterval != 2*time.Minute {
+		t.Fatalf("expected not modified with the new poll interval, got %+v", result)
+	}
+	if result.LastModified != "Tue, 30 Apr 2024 09:00:00 GMT" {
+		t.Fatalf("expected a 304 to keep the last Last-Modified, got %q", result.LastModified)
+	}
+
+	requests := server.Requests()
+	if requests[0].Path != "/notifications?per_page=50" || requests[0].Header.Get("If-Modified-Since") != "" {
+		t.Fatalf("expected an unconditional first request, got %s %v", requests[0].Path, requests[0].Header)
+	}
+	if got := requests[1].Header.Get("If-Modified-Since"); got != "Tue, 30 Apr 2024 09:00:00 GMT" {
+		t.Fatalf("expected a conditional second request, got If-Modified-Since %q", got)
+	}
+}
+
+func TestPollInterval(t *testing.T) {
+	cases := map[string]time.Duration{
+		"60":   time.Minute,
+		" 5 ":  5 * time.Second,
+		"0":    0,
+		"-30":  0,
+		"soon": 0,
+		"":     0,
+	}
+	for raw, want := range cases {
+		if got := pollInterval(http.Header{"X-Poll-Interval": {raw}}); got != want {
+			t.Fatalf("pollInterval(%q) = %s, want %s", raw, got, want)
+		}
+	}
+}
+
+func TestFetchPullRequestsDecodesIssuesAndDiscussions(t *testing.T) {
+	server := githubtest.NewServer(t, githubtest.Reply(t, http.StatusOK, "search_issues_discussions"))
+	cfg := testConfig(server.URL)
+	cfg.Searches = []config.Search{
+		{Name: "Issues", Query: "is:open is:issue involves:@me", Type: config.SearchIssues},
+		{Name: "Discussions", Query: "involves:@me", Type: config.SearchDiscussions},
+	}
+
+	result, err := FetchPullRequests(context.Background(), cfg, AuthToken)
+	if err != nil {
+		t.Fatalf("fetch: %v", err)
+	}
+	if len(result.Items) != 2 {
+		t.Fatalf("expected an issue and a discussion, got %+v", result.Items)
+	}
+
+	issue, discussion := result.Items[0], result.Items[1]
+	if issue.Kind != KindIssue || issue.IsPullRequest() || issue.Title != "Login loops on Safari" || !issue.Authored || len(issue.CommentedAt) != 1 {
+		t.Fatalf("unexpected issue %+v", issue)
+	}
+	if got := issue.LabelSummary(); got != "bug, p1" {
+		t.Fatalf("expected the issue's labels, got %q", got)
+	}
+	if discussion.Kind != KindDiscussion || discussion.IsPullRequest() || discussion.Category != "Q&A" || !discussion.Answered || discussion.Authored {
+		t.Fatalf("unexpected discussion %+v", discussion)
+	}
+	if got := discussion.LabelSummary(); got != "Q&A, answered" {
+		t.Fatalf("expected the discussion's category, got %q", got)
+	}
+
+	query := server.Requests()[0].Query
+	if !strings.Contains(query, "s1: search(type: DISCUSSION") || !strings.Contains(query, "... on Issue {") || !strings.Contains(query, "... on Discussion {") {
+		t.Fatalf("expected issue and discussion fields in the query, got\n%s", query)
+	}
+}
+
+func TestLabelSummary(t *testing.T) {
+	tests := []struct {
+		name string
+		item PullRequest
+		want string
+	}{
+		{name: "issue", item: PullRequest{Kind: KindIssue, Labels: []string{"bug", "p1"}}, want: "bug, p1"},
+		{name: "unlabelled issue", item: PullRequest{Kind: KindIssue}, want: ""},
+		{name: "open discussion", item: PullRequest{Kind: KindDiscussion, Category: "Ideas", Labels: []string{"v2"}}, want: "Ideas, v2"},
+		{name: "answered without category", item: PullRequest{Kind: KindDiscussion, Answered: true}, want: "answered"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.item.LabelSummary(); got != tt.want {
+				t.Fatalf("expected %q, got %q", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestFetchPullRequestsFilters(t *testing.T) {
+	// The fixture lists acme/app #42, acme/api #7 and the acme/app draft #43.
+	tests := []struct {
+		name    string
+		filter  config.Filter
+		numbers []int
+		hidden  int
+		capped  int
+	}{
+		{name: "none", filter: config.Filter{Drafts: config.DraftsShow}, numbers: []int{42, 7, 43}},
+		{name: "exclude", filter: config.Filter{Exclude: []string{"acme/api"}}, numbers: []int{42, 43}, hidden: 1},
+		{name: "include", filter: config.Filter{Include: []string{"acme/a*"}}, numbers: []int{42, 7, 43}},
+		{name: "hide drafts", filter: config.Filter{Drafts: config.DraftsHide}, numbers: []int{42, 7}, hidden: 1},
+		{name: "separate drafts", filter: config.Filter{Drafts: config.DraftsSeparate}, numbers: []int{42, 7, 43}},
+		{name: "cap", filter: config.Filter{MaxPerRepo: 1}, numbers: []int{42, 7}, capped: 1},
+		{name: "exclude and cap", filter: config.Filter{Exclude: []string{"acme/api"}, MaxPerRepo: 1}, numbers: []int{42}, hidden: 1, capped: 1},
+		// A hidden draft leaves room under the cap rather than being counted twice.
+		{name: "hide drafts and cap", filter: config.Filter{Drafts: config.DraftsHide, MaxPerRepo: 1}, numbers: []int{42, 7}, hidden: 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := githubtest.NewServer(t, githubtest.Reply(t, http.StatusOK, "search_pull_requests"))
+			cfg := testConfig(server.URL)
+			cfg.Filter = tt.filter
+
+			result, err := FetchPullRequests(context.Background(), cfg, AuthToken)
+			if err != nil {
+				t.Fatalf("fetch: %v", err)
+			}
+			var numbers []int
+			for _, item := range result.Items {
+				numbers = append(numbers, item.Number)
+			}
+			if !slices.Equal(numbers, tt.numbers) {
+				t.Fatalf("expected items %v, got %v", tt.numbers, numbers)
+			}
+			search := result.Searches[0]
+			if result.Count != len(tt.numbers) || search.Count != len(tt.numbers) || search.Hidden != tt.hidden || search.Capped != tt.capped {
+				t.Fatalf("expected count %d, %d hidden and %d capped, got %d and %+v", len(tt.numbers), tt.hidden, tt.capped, result.Count, search)
+			}
+		})
+	}
+}
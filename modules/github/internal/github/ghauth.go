@@ -0,0 +1,143 @@
+package github
+
+import (
+	"context"
+	"log/slog"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/rbright/waybar-github/internal/config"
+)
+
+// ghTokens caches gh's token per host for the life of the process, so polls in
+// gh mode use the native client instead of running gh for every request. A
+// failed read is cached too, but only for ghTokenMissTTL, which keeps the
+// fallback from asking gh on every request yet picks up a later `gh auth login`.
+var ghTokens sync.Map
+
+var ghTokenMissTTL = time.Minute
+
+type ghToken struct {
+	value  string
+	readAt time.Time
+}
+
+// withGHToken returns cfg carrying gh's token for cfg.Host, or false when the
+// token cannot be read and requests have to go through gh itself.
+func withGHToken(ctx context.Context, cfg config.Runtime) (config.Runtime, bool) {
+	cached, ok := ghTokens.Load(cfg.Host)
+	if ok && cached.(ghToken).value == "" && time.Since(cached.(ghToken).readAt) >= ghTokenMissTTL {
+		ghTokens.CompareAndDelete(cfg.Host, cached)
+		ok = false
+	}
+	if !ok {
+		token, source := readGHToken(ctx, cfg.Host)
+		if token != "" {
+			slog.Debug("using gh token", "host", cfg.Host, "source", source)
+		}
+		cached, _ = ghTokens.LoadOrStore(cfg.Host, ghToken{value: token, readAt: time.Now()})
+	}
+	if cached.(ghToken).value == "" {
+		return cfg, false
+	}
+	cfg.Token = cached.(ghToken).value
+	return cfg, true
+}
+
+// ResetGHTokens empties the token cache, so every host's gh token is read
+// again on its next request.
+func ResetGHTokens() {
+	ghTokens.Clear()
+}
+
+// forgetGHToken drops a token GitHub rejected, so the next request reads it
+// again after `gh auth login` or `gh auth refresh`.
+func forgetGHToken(host string) {
+	ghTokens.Delete(host)
+}
+
+// readGHToken resolves gh's token the way gh does: its environment variables,
+// then hosts.yml, then `gh auth token` for tokens kept in the system keyring.
+func readGHToken(ctx context.Context, host string) (string, string) {
+	envNames := []string{"GH_ENTERPRISE_TOKEN", "GITHUB_ENTERPRISE_TOKEN"}
+	if host == "github.com" {
+		envNames = []string{"GH_TOKEN", "GITHUB_TOKEN"}
+	}
+	for _, name := range envNames {
+		if token := strings.TrimSpace(os.Getenv(name)); token != "" {
+			return token, name
+		}
+	}
+
+	if path := ghHostsFile(); path != "" {
+		if raw, err := os.ReadFile(path); err == nil {
+			if token := hostsFileToken(raw, host); token != "" {
+				return token, path
+			}
+		}
+	}
+
+	if _, err := exec.LookPath("gh"); err != nil {
+		return "", ""
+	}
+	out, err := exec.CommandContext(ctx, "gh", "auth", "token", "--hostname", host).Output()
+	if err != nil {
+		return "", ""
+	}
+	return strings.TrimSpace(string(out)), "gh auth token"
+}
+
+func ghHostsFile() string {
+	if dir := strings.TrimSpace(os.Getenv("GH_CONFIG_DIR")); dir != "" {
+		return filepath.Join(dir, "hosts.yml")
+	}
+	if dir := strings.TrimSpace(os.Getenv("XDG_CONFIG_HOME")); dir != "" {
+		return filepath.Join(dir, "gh", "hosts.yml")
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(home, ".config", "gh", "hosts.yml")
+}
+
+// hostsFileToken finds the host-level oauth_token in gh's hosts.yml. Recent gh
+// versions keep the token in the system keyring and leave it out of the file.
+func hostsFileToken(raw []byte, host string) string {
+	inHost := false
+	hostIndent := -1
+	for _, line := range strings.Split(string(raw), "\n") {
+		trimmed := strings.TrimSpace(line)
+		if trimmed == "" || strings.HasPrefix(trimmed, "#") {
+			continue
+		}
+
+		indent := len(line) - len(strings.TrimLeft(line, " \t"))
+		if indent == 0 {
+			inHost = strings.EqualFold(unquote(strings.TrimSuffix(trimmed, ":")), host)
+			hostIndent = -1
+			continue
+		}
+		if !inHost {
+			continue
+		}
+		// Keys of the host block share the first nested indent; deeper keys
+		// belong to per-user entries.
+		if hostIndent < 0 {
+			hostIndent = indent
+		}
+		key, value, ok := strings.Cut(trimmed, ":")
+		if ok && indent == hostIndent && strings.TrimSpace(key) == "oauth_token" {
+			return unquote(strings.TrimSpace(value))
+		}
+	}
+	return ""
+}
+
+func unquote(value string) string {
+	return strings.Trim(value, `"'`)
+}
@@ -0,0 +1,176 @@
+package github
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/rbright/waybar-github/internal/config"
+)
+
+// notificationsPageSize is the most GitHub returns per page; more unread threads
+// are reported as "50+".
+const notificationsPageSize = 50
+
+type Notification struct {
+	ID         string `json:"id"`
+	Reason     string `json:"reason"`
+	Type       string `json:"type"`
+	Title      string `json:"title"`
+	Repository string `json:"repository"`
+	URL        string `json:"url"`
+	UpdatedAt  string `json:"updatedAt"`
+}
+
+type NotificationsResult struct {
+	Items []Notification
+	// More is set when unread threads exist beyond the first page.
+	More bool
+	// NotModified is set when GitHub answered 304 to If-Modified-Since; Items is empty.
+	NotModified  bool
+	LastModified string
+	PollInterval time.Duration
+}
+
+type notificationThread struct {
+	ID        string `json:"id"`
+	Reason    string `json:"reason"`
+	UpdatedAt string `json:"updated_at"`
+	Subject   struct {
+		Title string `json:"title"`
+		URL   string `json:"url"`
+		Type  string `json:"type"`
+	} `json:"subject"`
+	Repository struct {
+		FullName string `json:"full_name"`
+		HTMLURL  string `json:"html_url"`
+	} `json:"repository"`
+}
+
+// FetchNotifications lists unread notification threads. lastModified comes from
+// the previous response and makes the request conditional.
+func FetchNotifications(ctx context.Context, cfg config.Runtime, mode AuthMode, lastModified string) (NotificationsResult, error) {
+	header := http.Header{}
+	if lastModified != "" {
+		header.Set("If-Modified-Since", lastModified)
+	}
+
+	response, err := restRequest(ctx, cfg, mode, http.MethodGet, "/notifications?per_page="+strconv.Itoa(notificationsPageSize), header, nil)
+	if err != nil {
+		return NotificationsResult{}, err
+	}
+
+	result := NotificationsResult{
+		LastModified: response.Header.Get("Last-Modified"),
+		PollInterval: pollInterval(response.Header),
+	}
+	switch {
+	case response.Status == http.StatusNotModified:
+		result.NotModified = true
+		if result.LastModified == "" {
+			result.LastModified = lastModified
+		}
+		return result, nil
+	case response.Status < 200 || response.Status >= 300:
+		return NotificationsResult{}, response.err("list notifications")
+	}
+
+	var threads []notificationThread
+	if err := json.Unmarshal(response.Body, &threads); err != nil {
+		return NotificationsResult{}, fmt.Errorf("decode notifications: %w", err)
+	}
+
+	result.Items = make([]Notification, 0, len(threads))
+	for _, thread := range threads {
+		result.Items = append(result.Items, Notification{
+			ID:         strings.TrimSpace(thread.ID),
+			Reason:     strings.TrimSpace(thread.Reason),
+			Type:       strings.TrimSpace(thread.Subject.Type),
+			Title:      sanitize(thread.Subject.Title),
+			Repository: sanitize(thread.Repository.FullName),
+			URL:        notificationURL(cfg.Host, thread),
+			UpdatedAt:  strings.TrimSpace(thread.UpdatedAt),
+		})
+	}
+	result.More = strings.Contains(response.Header.Get("Link"), `rel="next"`)
+
+	slog.Debug("github notifications fetched", "mode", mode, "count", len(result.Items), "more", result.More, "poll_interval", result.PollInterval)
+	return result, nil
+}
+
+func MarkThreadRead(ctx context.Context, cfg config.Runtime, mode AuthMode, threadID string) error {
+	response, err := restRequest(ctx, cfg, mode, http.MethodPatch, "/notifications/threads/"+url.PathEscape(threadID), nil, nil)
+	if err != nil {
+		return err
+	}
+	if response.Status < 200 || response.Status >= 300 {
+		return response.err("mark notification read")
+	}
+	return nil
+}
+
+// MarkAllNotificationsRead marks everything up to readAt as read, so threads that
+// arrive while the request is in flight stay unread.
+func MarkAllNotificationsRead(ctx context.Context, cfg config.Runtime, mode AuthMode, readAt time.Time) error {
+	body, err := json.Marshal(map[string]any{"last_read_at": readAt.UTC().Format(time.RFC3339), "read": true})
+	if err != nil {
+		return fmt.Errorf("marshal mark-all-read payload: %w", err)
+	}
+	response, err := restRequest(ctx, cfg, mode, http.MethodPut, "/notifications", nil, body)
+	if err != nil {
+		return err
+	}
+	if response.Status < 200 || response.Status >= 300 {
+		return response.err("mark all notifications read")
+	}
+	return nil
+}
+
+func pollInterval(header http.Header) time.Duration {
+	seconds, err := strconv.Atoi(strings.TrimSpace(header.Get("X-Poll-Interval")))
+	if err != nil || seconds <= 0 {
+		return 0
+	}
+	return time.Duration(seconds) * time.Second
+}
+
+// notificationURL maps the thread's API subject URL to the page a browser should
+// open. Subjects without one (discussions, check suites) fall back to the repository.
+func notificationURL(host string, thread notificationThread) string {
+	repoURL := strings.TrimSpace(thread.Repository.HTMLURL)
+	_, path, ok := strings.Cut(thread.Subject.URL, "/repos/")
+	if !ok {
+		switch thread.Subject.Type {
+		case "Discussion":
+			return repoURL + "/discussions"
+		case "CheckSuite":
+			return repoURL + "/actions"
+		default:
+			return repoURL
+		}
+	}
+
+	parts := strings.Split(path, "/")
+	if len(parts) < 4 {
+		return repoURL
+	}
+	owner, repo, kind, id := parts[0], parts[1], parts[2], parts[3]
+	switch kind {
+	case "pulls":
+		kind = "pull"
+	case "commits":
+		kind = "commit"
+	case "issues":
+	case "releases":
+		return fmt.Sprintf("https://%s/%s/%s/releases", host, owner, repo)
+	default:
+		return repoURL
+	}
+	return fmt.Sprintf("https://%s/%s/%s/%s/%s", host, owner, repo, kind, id)
+}
@@ -0,0 +1,81 @@
+package github
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// RateLimit is the GraphQL budget reported alongside each search.
+type RateLimit struct {
+	Limit     int       `json:"limit"`
+	Remaining int       `json:"remaining"`
+	Cost      int       `json:"cost"`
+	ResetAt   time.Time `json:"resetAt,omitzero"`
+}
+
+// RateLimitError reports a primary or secondary rate limit. RetryAfter is zero
+// when GitHub did not say how long to back off.
+type RateLimitError struct {
+	Status     int
+	RetryAfter time.Duration
+	Message    string
+}
+
+func (e *RateLimitError) Error() string {
+	message := "github rate limit exceeded"
+	if e.Status != 0 {
+		message = fmt.Sprintf("github rate limit exceeded (status %d)", e.Status)
+	}
+	if e.RetryAfter > 0 {
+		message += fmt.Sprintf("; retry in %s", e.RetryAfter.Round(time.Second))
+	}
+	if e.Message != "" {
+		message += ": " + e.Message
+	}
+	return message
+}
+
+// AsRateLimit unwraps a *RateLimitError from err.
+func AsRateLimit(err error) (*RateLimitError, bool) {
+	var limitErr *RateLimitError
+	ok := errors.As(err, &limitErr)
+	return limitErr, ok
+}
+
+// rateLimitError recognizes rate-limited responses: 429s, and 403s that carry
+// Retry-After, an exhausted X-RateLimit-Remaining or a rate limit message. Other
+// 403s are permission errors and return nil.
+func rateLimitError(response restResponse, now time.Time) *RateLimitError {
+	if response.Status != http.StatusForbidden && response.Status != http.StatusTooManyRequests {
+		return nil
+	}
+
+	var retryAfter time.Duration
+	if seconds, err := strconv.Atoi(strings.TrimSpace(response.Header.Get("Retry-After"))); err == nil && seconds > 0 {
+		retryAfter = time.Duration(seconds) * time.Second
+	} else if strings.TrimSpace(response.Header.Get("X-RateLimit-Remaining")) == "0" {
+		if reset, err := strconv.ParseInt(strings.TrimSpace(response.Header.Get("X-RateLimit-Reset")), 10, 64); err == nil {
+			retryAfter = max(0, time.Unix(reset, 0).Sub(now))
+		}
+	}
+
+	message := strings.TrimSpace(string(response.Body))
+	if response.Status == http.StatusForbidden && retryAfter == 0 &&
+		strings.TrimSpace(response.Header.Get("X-RateLimit-Remaining")) != "0" &&
+		!strings.Contains(strings.ToLower(message), "rate limit") {
+		return nil
+	}
+
+	var payload struct {
+		Message string `json:"message"`
+	}
+	if err := json.Unmarshal(response.Body, &payload); err == nil {
+		message = payload.Message
+	}
+	return &RateLimitError{Status: response.Status, RetryAfter: retryAfter, Message: sanitize(message)}
+}
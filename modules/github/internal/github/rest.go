@@ -0,0 +1,160 @@
+package github
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/textproto"
+	"os/exec"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/rbright/waybar-github/internal/config"
+)
+
+type restResponse struct {
+	Status int
+	Header http.Header
+	Body   []byte
+}
+
+// restRequest calls the REST API with the native client, using gh's token in gh
+// mode and falling back to gh itself when that token cannot be read. It returns
+// the status and headers either way so callers can handle conditional requests
+// and rate limits uniformly.
+func restRequest(ctx context.Context, cfg config.Runtime, mode AuthMode, method, path string, header http.Header, body []byte) (restResponse, error) {
+	start := time.Now()
+	var (
+		response restResponse
+		err      error
+	)
+	switch mode {
+	case AuthGH:
+		if ghCfg, ok := withGHToken(ctx, cfg); ok {
+			response, err = restWithToken(ctx, ghCfg, method, path, header, body)
+			if err == nil && response.Status == http.StatusUnauthorized {
+				// As for GraphQL, gh retries once with its own token.
+				forgetGHToken(cfg.Host)
+				response, err = restWithGH(ctx, cfg, method, path, header, body)
+			}
+		} else {
+			response, err = restWithGH(ctx, cfg, method, path, header, body)
+		}
+	case AuthToken:
+		response, err = restWithToken(ctx, cfg, method, path, header, body)
+	default:
+		return restResponse{}, fmt.Errorf("no supported auth mode")
+	}
+	if err != nil {
+		return restResponse{}, err
+	}
+	slog.Debug("github rest response", "mode", mode, "method", method, "path", path, "status", response.Status, "bytes", len(response.Body), "duration", time.Since(start))
+	return response, nil
+}
+
+func restWithToken(ctx context.Context, cfg config.Runtime, method, path string, header http.Header, body []byte) (restResponse, error) {
+	var reader io.Reader
+	if body != nil {
+		reader = bytes.NewReader(body)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, strings.TrimRight(cfg.APIURL, "/")+path, reader)
+	if err != nil {
+		return restResponse{}, fmt.Errorf("create rest request: %w", err)
+	}
+	for key, values := range header {
+		for _, value := range values {
+			req.Header.Add(key, value)
+		}
+	}
+	req.Header.Set("Accept", "application/vnd.github+json")
+	req.Header.Set("X-GitHub-Api-Version", "2022-11-28")
+	req.Header.Set("Authorization", "Bearer "+cfg.Token)
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	client := &http.Client{Timeout: maxDuration(cfg.Timeout, 10*time.Second)}
+	resp, err := client.Do(req)
+	if err != nil {
+		return restResponse{}, fmt.Errorf("perform rest request: %w", err)
+	}
+	defer func() {
+		_ = resp.Body.Close()
+	}()
+
+	responseBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return restResponse{}, fmt.Errorf("read rest response: %w", err)
+	}
+	return restResponse{Status: resp.StatusCode, Header: resp.Header, Body: responseBody}, nil
+}
+
+func restWithGH(ctx context.Context, cfg config.Runtime, method, path string, header http.Header, body []byte) (restResponse, error) {
+	args := []string{"api", "--hostname", cfg.Host, "--include", "--method", method, path}
+	for key, values := range header {
+		for _, value := range values {
+			args = append(args, "--header", key+": "+value)
+		}
+	}
+	if body != nil {
+		args = append(args, "--input", "-")
+	}
+
+	cmd := exec.CommandContext(ctx, "gh", args...)
+	if body != nil {
+		cmd.Stdin = bytes.NewReader(body)
+	}
+	out, runErr := cmd.Output()
+
+	// gh exits non-zero for non-2xx statuses but still prints the response.
+	response, parseErr := parseIncludedResponse(out)
+	if parseErr != nil {
+		if runErr != nil {
+			return restResponse{}, fmt.Errorf("gh api request failed: %w", runErr)
+		}
+		return restResponse{}, parseErr
+	}
+	return response, nil
+}
+
+// parseIncludedResponse reads `gh api --include` output: a status line, headers,
+// a blank line and the body.
+func parseIncludedResponse(raw []byte) (restResponse, error) {
+	reader := textproto.NewReader(bufio.NewReader(bytes.NewReader(raw)))
+	statusLine, err := reader.ReadLine()
+	if err != nil {
+		return restResponse{}, fmt.Errorf("read gh api status line: %w", err)
+	}
+	proto, rest, ok := strings.Cut(statusLine, " ")
+	if !ok || !strings.HasPrefix(proto, "HTTP/") {
+		return restResponse{}, fmt.Errorf("unexpected gh api status line %q", statusLine)
+	}
+	codeText, _, _ := strings.Cut(rest, " ")
+	code, err := strconv.Atoi(codeText)
+	if err != nil {
+		return restResponse{}, fmt.Errorf("unexpected gh api status line %q", statusLine)
+	}
+
+	header, err := reader.ReadMIMEHeader()
+	if err != nil && !errors.Is(err, io.EOF) {
+		return restResponse{}, fmt.Errorf("read gh api headers: %w", err)
+	}
+	body, err := io.ReadAll(reader.R)
+	if err != nil {
+		return restResponse{}, fmt.Errorf("read gh api body: %w", err)
+	}
+	return restResponse{Status: code, Header: http.Header(header), Body: body}, nil
+}
+
+func (r restResponse) err(action string) error {
+	if limitErr := rateLimitError(r, time.Now()); limitErr != nil {
+		return fmt.Errorf("%s: %w", action, limitErr)
+	}
+	return fmt.Errorf("%s: github status %d: %s", action, r.Status, strings.TrimSpace(string(r.Body)))
+}
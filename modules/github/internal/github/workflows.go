@@ -0,0 +1,141 @@
+package github
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/rbright/waybar-github/internal/config"
+)
+
+// WorkflowRun is the latest run of one workflow on a watched branch.
+type WorkflowRun struct {
+	ID         int64  `json:"id"`
+	Repository string `json:"repository"`
+	Branch     string `json:"branch"`
+	Workflow   string `json:"workflow"`
+	// Title is the run's display title, usually the head commit message.
+	Title     string `json:"title"`
+	URL       string `json:"url"`
+	RunNumber int    `json:"runNumber"`
+	// Status is queued, in_progress, completed, …; Conclusion is set once completed.
+	Status     string    `json:"status"`
+	Conclusion string    `json:"conclusion,omitempty"`
+	UpdatedAt  time.Time `json:"updatedAt,omitzero"`
+}
+
+// Failed reports whether the run finished red.
+func (r WorkflowRun) Failed() bool {
+	switch r.Conclusion {
+	case "failure", "timed_out", "startup_failure":
+		return true
+	default:
+		return false
+	}
+}
+
+// Running reports whether the run has not finished yet.
+func (r WorkflowRun) Running() bool {
+	return r.Status != "completed"
+}
+
+// WatchedBranch is a watched branch with its default branch resolved. Error is
+// set when its runs could not be fetched this round.
+type WatchedBranch struct {
+	Repository string `json:"repository"`
+	Branch     string `json:"branch"`
+	Error      string `json:"error,omitempty"`
+}
+
+func (b WatchedBranch) Label() string {
+	return b.Repository + "@" + b.Branch
+}
+
+// Matches reports whether run belongs to this branch.
+func (b WatchedBranch) Matches(run WorkflowRun) bool {
+	return run.Repository == b.Repository && run.Branch == b.Branch
+}
+
+// workflowRunsPageSize bounds the history searched for each workflow's latest run.
+const workflowRunsPageSize = 50
+
+// FetchWorkflowRuns returns the latest run of each workflow on branch, sorted
+// by workflow name. Skipped and cancelled runs are passed over, so a run
+// cancelled by a newer push does not hide the result before it.
+func FetchWorkflowRuns(ctx context.Context, cfg config.Runtime, mode AuthMode, repository, branch string) ([]WorkflowRun, error) {
+	query := url.Values{"branch": {branch}, "exclude_pull_requests": {"true"}, "per_page": {fmt.Sprint(workflowRunsPageSize)}}
+	response, err := restRequest(ctx, cfg, mode, http.MethodGet, fmt.Sprintf("/repos/%s/actions/runs?%s", repository, query.Encode()), nil, nil)
+	if err != nil {
+		return nil, err
+	}
+	if response.Status < 200 || response.Status >= 300 {
+		return nil, response.err("list workflow runs")
+	}
+
+	var payload struct {
+		WorkflowRuns []struct {
+			ID           int64     `json:"id"`
+			WorkflowID   int64     `json:"workflow_id"`
+			Name         string    `json:"name"`
+			DisplayTitle string    `json:"display_title"`
+			HTMLURL      string    `json:"html_url"`
+			RunNumber    int       `json:"run_number"`
+			Status       string    `json:"status"`
+			Conclusion   string    `json:"conclusion"`
+			UpdatedAt    time.Time `json:"updated_at"`
+		} `json:"workflow_runs"`
+	}
+	if err := json.Unmarshal(response.Body, &payload); err != nil {
+		return nil, fmt.Errorf("decode workflow runs: %w", err)
+	}
+
+	// Runs come newest first.
+	seen := make(map[int64]bool)
+	var runs []WorkflowRun
+	for _, run := range payload.WorkflowRuns {
+		if seen[run.WorkflowID] || run.Conclusion == "skipped" || run.Conclusion == "cancelled" {
+			continue
+		}
+		seen[run.WorkflowID] = true
+		runs = append(runs, WorkflowRun{
+			ID:         run.ID,
+			Repository: repository,
+			Branch:     branch,
+			Workflow:   sanitize(run.Name),
+			Title:      sanitize(run.DisplayTitle),
+			URL:        strings.TrimSpace(run.HTMLURL),
+			RunNumber:  run.RunNumber,
+			Status:     strings.TrimSpace(run.Status),
+			Conclusion: strings.TrimSpace(run.Conclusion),
+			UpdatedAt:  run.UpdatedAt,
+		})
+	}
+	slices.SortStableFunc(runs, func(a, b WorkflowRun) int { return strings.Compare(a.Workflow, b.Workflow) })
+	return runs, nil
+}
+
+// DefaultBranch looks up the repository's default branch.
+func DefaultBranch(ctx context.Context, cfg config.Runtime, mode AuthMode, repository string) (string, error) {
+	response, err := restRequest(ctx, cfg, mode, http.MethodGet, "/repos/"+repository, nil, nil)
+	if err != nil {
+		return "", err
+	}
+	if response.Status < 200 || response.Status >= 300 {
+		return "", response.err("get repository")
+	}
+	var repo struct {
+		DefaultBranch string `json:"default_branch"`
+	}
+	if err := json.Unmarshal(response.Body, &repo); err != nil {
+		return "", fmt.Errorf("decode repository: %w", err)
+	}
+	if strings.TrimSpace(repo.DefaultBranch) == "" {
+		return "", fmt.Errorf("repository %s has no default branch", repository)
+	}
+	return strings.TrimSpace(repo.DefaultBranch), nil
+}
@@ -0,0 +1,222 @@
+// Package githubtest fakes GitHub for tests: an API server that replays
+// recorded responses, a gh executable on PATH, and the recorded fixtures.
+package githubtest
+
+import (
+	"embed"
+	"encoding/json"
+	"fmt"
+	"io"
+	"maps"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+)
+
+//go:embed testdata/*.json
+var fixtures embed.FS
+
+// Fixture returns the recorded response body testdata/<name>.json.
+func Fixture(t testing.TB, name string) []byte {
+	t.Helper()
+	raw, err := fixtures.ReadFile("testdata/" + name + ".json")
+	if err != nil {
+		t.Fatalf("read fixture %s: %v", name, err)
+	}
+	return raw
+}
+
+// Response is one recorded HTTP response.
+type Response struct {
+	Status int
+	Header http.Header
+	Body   []byte
+}
+
+// Reply answers with the named fixture and status.
+func Reply(t testing.TB, status int, name string) Response {
+	t.Helper()
+	return Response{Status: status, Header: http.Header{"Content-Type": {"application/json; charset=utf-8"}}, Body: Fixture(t, name)}
+}
+
+// Included renders r the way `gh api --include` prints it.
+func (r Response) Included() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "HTTP/2.0 %d %s\r\n", r.Status, http.StatusText(r.Status))
+	for _, name := range slices.Sorted(maps.Keys(r.Header)) {
+		for _, value := range r.Header[name] {
+			fmt.Fprintf(&b, "%s: %s\r\n", name, value)
+		}
+	}
+	b.WriteString("\r\n")
+	b.Write(r.Body)
+	return b.String()
+}
+
+// Request is one request the server received. Query and Variables are set
+// for GraphQL requests.
+type Request struct {
+	Method        string
+	Path          string
+	Header        http.Header
+	Authorization string
+	Query         string
+	Variables     map[string]any
+}
+
+// Server is a GitHub API that answers with its replies in order, repeating the
+// last one once they run out. GraphQL is served at /graphql and REST paths
+// below the root.
+type Server struct {
+	// URL is the GraphQL endpoint; the REST API is URL without "/graphql".
+	URL string
+
+	mu       sync.Mutex
+	replies  []Response
+	requests []Request
+}
+
+func NewServer(t testing.TB, replies ...Response) *Server {
+	t.Helper()
+	if len(replies) == 0 {
+		t.Fatal("githubtest: server needs at least one reply")
+	}
+	s := &Server{replies: replies}
+	server := httptest.NewServer(http.HandlerFunc(s.serve))
+	t.Cleanup(server.Close)
+	s.URL = server.URL + "/graphql"
+	return s
+}
+
+func (s *Server) serve(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Path == "/graphql" && r.Method != http.MethodPost {
+		http.NotFound(w, r)
+		return
+	}
+	request := Request{Method: r.Method, Path: r.URL.RequestURI(), Header: r.Header.Clone(), Authorization: r.Header.Get("Authorization")}
+	if r.URL.Path == "/graphql" {
+		raw, err := io.ReadAll(r.Body)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		var payload struct {
+			Query     string         `json:"query"`
+			Variables map[string]any `json:"variables"`
+		}
+		if err := json.Unmarshal(raw, &payload); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		request.Query, request.Variables = payload.Query, payload.Variables
+	}
+
+	s.mu.Lock()
+	s.requests = append(s.requests, request)
+	reply := s.replies[0]
+	if len(s.replies) > 1 {
+		s.replies = s.replies[1:]
+	}
+	s.mu.Unlock()
+
+	for name, values := range reply.Header {
+		w.Header()[name] = values
+	}
+	w.WriteHeader(reply.Status)
+	_, _ = w.Write(reply.Body)
+}
+
+// Requests returns the requests received so far.
+func (s *Server) Requests() []Request {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return slices.Clone(s.requests)
+}
+
+// GH is a fake gh on PATH. Commands answer as set with Respond; any other
+// command fails, so gh looks installed but signed out.
+type GH struct {
+	dir       string
+	responses map[string]ghResponse
+}
+
+type ghResponse struct {
+	stdout string
+	exit   int
+}
+
+// InstallGH puts a fake gh alone on PATH and hides the user's gh config and
+// token variables, so tests see only what they set up.
+func InstallGH(t testing.TB) *GH {
+	t.Helper()
+	gh := &GH{dir: t.TempDir(), responses: make(map[string]ghResponse)}
+	t.Setenv("PATH", gh.dir)
+	t.Setenv("GH_CONFIG_DIR", gh.dir)
+	for _, name := range []string{"GH_TOKEN", "GITHUB_TOKEN", "GH_ENTERPRISE_TOKEN", "GITHUB_ENTERPRISE_TOKEN"} {
+		t.Setenv(name, "")
+	}
+	gh.write(t)
+	return gh
+}
+
+// Dir is the directory holding gh, which doubles as GH_CONFIG_DIR.
+func (g *GH) Dir() string {
+	return g.dir
+}
+
+// Respond makes `gh <command> …` print stdout and exit with code. command is
+// the first two arguments, e.g. "auth token" or "api graphql".
+func (g *GH) Respond(t testing.TB, command, stdout string, code int) {
+	t.Helper()
+	g.responses[command] = ghResponse{stdout: stdout, exit: code}
+	g.write(t)
+}
+
+// Calls returns the arguments of every gh invocation so far.
+func (g *GH) Calls(t testing.TB) [][]string {
+	t.Helper()
+	raw, err := os.ReadFile(filepath.Join(g.dir, "calls"))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		t.Fatalf("read gh calls: %v", err)
+	}
+	var calls [][]string
+	for _, record := range strings.Split(string(raw), "\x1e") {
+		if record == "" {
+			continue
+		}
+		calls = append(calls, strings.Split(strings.TrimSuffix(record, "\x1f"), "\x1f"))
+	}
+	return calls
+}
+
+// write renders the script with shell builtins only, since PATH holds nothing
+// but gh itself.
+func (g *GH) write(t testing.TB) {
+	t.Helper()
+	var b strings.Builder
+	b.WriteString("#!/bin/sh\n")
+	fmt.Fprintf(&b, "printf '%%s\\037' \"$@\" >> %s\n", shellQuote(filepath.Join(g.dir, "calls")))
+	fmt.Fprintf(&b, "printf '\\036' >> %s\n", shellQuote(filepath.Join(g.dir, "calls")))
+	b.WriteString("case \"$1 $2\" in\n")
+	for _, command := range slices.Sorted(maps.Keys(g.responses)) {
+		response := g.responses[command]
+		fmt.Fprintf(&b, "%s)\n  printf '%%s' %s\n  exit %d\n  ;;\n", shellQuote(command), shellQuote(response.stdout), response.exit)
+	}
+	b.WriteString("esac\nexit 1\n")
+
+	if err := os.WriteFile(filepath.Join(g.dir, "gh"), []byte(b.String()), 0o755); err != nil {
+		t.Fatalf("write fake gh: %v", err)
+	}
+}
+
+func shellQuote(value string) string {
+	return "'" + strings.ReplaceAll(value, "'", `'\''`) + "'"
+}
@@ -0,0 +1,139 @@
+package state
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/rbright/waybar-github/internal/github"
+	"github.com/rbright/waybar-shared/itemref"
+	"github.com/rbright/waybar-shared/menu"
+)
+
+// NotificationsMeta carries what the next conditional request needs alongside
+// the last good fetch.
+type NotificationsMeta struct {
+	More         bool      `json:"more,omitempty"`
+	LastModified string    `json:"lastModified,omitempty"`
+	PollInterval int       `json:"pollIntervalSeconds,omitempty"`
+	FetchedAt    time.Time `json:"fetchedAt,omitzero"`
+	CheckedAt    time.Time `json:"checkedAt,omitzero"`
+}
+
+type NotificationsMenuData struct {
+	StatusLine string
+	Items      []github.Notification
+	Limit      int
+	// Slots are the menu slots of Items, which row ids use.
+	Slots []int
+}
+
+// SaveNotifications writes notifications and returns the menu slot of each one.
+func SaveNotifications(path string, items []github.Notification) ([]int, error) {
+	return itemref.Save(path, items, notificationKey)
+}
+
+// LoadNotifications returns the saved notifications and the menu slot of each one.
+func LoadNotifications(path string) ([]github.Notification, []int, error) {
+	return itemref.Load(path, notificationKey)
+}
+
+func ResolveNotification(path string, ref itemref.Ref) (github.Notification, bool, error) {
+	return itemref.Resolve(path, ref, notificationKey)
+}
+
+func notificationKey(item github.Notification) string {
+	return item.ID
+}
+
+func SaveNotificationsMeta(path string, meta NotificationsMeta) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return fmt.Errorf("create meta dir: %w", err)
+	}
+
+	payload, err := json.MarshalIndent(meta, "", "  ")
+	if err != nil {
+		return fmt.Errorf("marshal meta: %w", err)
+	}
+
+	return writeFileAtomically(path, append(payload, '\n'))
+}
+
+func LoadNotificationsMeta(path string) (NotificationsMeta, error) {
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return NotificationsMeta{}, nil
+		}
+		return NotificationsMeta{}, fmt.Errorf("read meta file: %w", err)
+	}
+
+	var meta NotificationsMeta
+	if err := json.Unmarshal(raw, &meta); err != nil {
+		return NotificationsMeta{}, fmt.Errorf("decode meta file: %w", err)
+	}
+	return meta, nil
+}
+
+func WriteNotificationsMenu(path string, data NotificationsMenuData) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return fmt.Errorf("create menu dir: %w", err)
+	}
+
+	m := menu.New()
+	m.Item("open_dashboard", "Open GitHub Notifications")
+	if len(data.Items) > 0 {
+		m.Item("mark_all_read", "Mark All as Read")
+	}
+
+	shown := data.Items
+	if data.Limit > 0 && len(shown) > data.Limit {
+		shown = shown[:data.Limit]
+	}
+	if len(shown) > 0 {
+		for _, group := range groupByRepository(shown, func(item github.Notification) string { return item.Repository }) {
+			m.Section(group.repository)
+			for _, idx := range group.indexes {
+				item := shown[idx]
+				label := fmt.Sprintf("%s: %s", ReasonLabel(item.Reason), fallback(item.Title, item.Type))
+				m.Item(fmt.Sprintf("open_%d", itemref.Slot(data.Slots, idx)), label)
+			}
+		}
+	} else {
+		m.Separator()
+		m.Info(fallback(data.StatusLine, "No unread notifications"))
+	}
+
+	m.Separator()
+	m.Item("refresh", "Refresh")
+
+	return writeFileAtomically(path, m.Bytes())
+}
+
+// ReasonLabel turns GitHub's notification reason into menu and tooltip text.
+func ReasonLabel(reason string) string {
+	switch reason {
+	case "review_requested":
+		return "Review requested"
+	case "mention", "team_mention":
+		return "Mention"
+	case "ci_activity":
+		return "CI"
+	case "assign":
+		return "Assigned"
+	case "author":
+		return "Author"
+	case "comment":
+		return "Comment"
+	case "state_change":
+		return "State change"
+	case "subscribed", "manual":
+		return "Subscribed"
+	case "security_alert":
+		return "Security alert"
+	default:
+		return fallback(reason, "Notification")
+	}
+}
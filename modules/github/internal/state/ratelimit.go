@@ -0,0 +1,83 @@
+package state
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/rbright/waybar-github/internal/github"
+)
+
+// RateLimit is the last known budget of one API on one host; see
+// config.Runtime.RateLimitPath.
+type RateLimit struct {
+	github.RateLimit
+	// CheckedAt is when the budget was reported.
+	CheckedAt time.Time `json:"checkedAt,omitzero"`
+	// BlockedUntil is set after a rate-limited response.
+	BlockedUntil time.Time `json:"blockedUntil,omitzero"`
+}
+
+// Wait reports how long to hold off the next fetch. It waits out a recorded
+// block, keeps reserve requests in hand until the budget resets, and otherwise
+// spaces polls so the remaining budget lasts until the reset.
+func (r RateLimit) Wait(now time.Time, reserve int) time.Duration {
+	if now.Before(r.BlockedUntil) {
+		return r.BlockedUntil.Sub(now)
+	}
+	if r.Limit == 0 || !now.Before(r.ResetAt) {
+		return 0
+	}
+
+	untilReset := r.ResetAt.Sub(now)
+	polls := (r.Remaining - reserve) / max(1, r.Cost)
+	if polls <= 0 {
+		return untilReset
+	}
+	pace := untilReset / time.Duration(polls)
+	return max(0, pace-now.Sub(r.CheckedAt))
+}
+
+// Block records a rate-limited response. Without a Retry-After it waits for the
+// known reset, or a minute when that has passed too.
+func (r *RateLimit) Block(now time.Time, retryAfter time.Duration) {
+	switch {
+	case retryAfter > 0:
+		r.BlockedUntil = now.Add(retryAfter)
+	case now.Before(r.ResetAt):
+		r.BlockedUntil = r.ResetAt
+	default:
+		r.BlockedUntil = now.Add(time.Minute)
+	}
+}
+
+func SaveRateLimit(path string, limit RateLimit) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return fmt.Errorf("create rate limit dir: %w", err)
+	}
+
+	payload, err := json.MarshalIndent(limit, "", "  ")
+	if err != nil {
+		return fmt.Errorf("marshal rate limit: %w", err)
+	}
+
+	return writeFileAtomically(path, append(payload, '\n'))
+}
+
+func LoadRateLimit(path string) (RateLimit, error) {
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return RateLimit{}, nil
+		}
+		return RateLimit{}, fmt.Errorf("read rate limit file: %w", err)
+	}
+
+	var limit RateLimit
+	if err := json.Unmarshal(raw, &limit); err != nil {
+		return RateLimit{}, fmt.Errorf("decode rate limit file: %w", err)
+	}
+	return limit, nil
+}
@@ -0,0 +1,62 @@
+package state
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// SeenMark records that a desktop notification was sent for an event. Value
+// holds per-event data, such as the comment count it was raised for.
+type SeenMark struct {
+	Value string    `json:"value,omitempty"`
+	At    time.Time `json:"at"`
+}
+
+// Seen maps event keys ("review:<id>", "ci:<id>", …) to when they were notified.
+type Seen map[string]SeenMark
+
+// LoadSeen reads the seen-set. ok is false when none has been written yet, so the
+// first run can record the current state without notifying about all of it.
+func LoadSeen(path string) (seen Seen, ok bool, err error) {
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return Seen{}, false, nil
+		}
+		return nil, false, fmt.Errorf("read seen file: %w", err)
+	}
+
+	if err := json.Unmarshal(raw, &seen); err != nil {
+		return nil, false, fmt.Errorf("decode seen file: %w", err)
+	}
+	if seen == nil {
+		seen = Seen{}
+	}
+	return seen, true, nil
+}
+
+func SaveSeen(path string, seen Seen) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return fmt.Errorf("create seen dir: %w", err)
+	}
+
+	payload, err := json.MarshalIndent(seen, "", "  ")
+	if err != nil {
+		return fmt.Errorf("marshal seen: %w", err)
+	}
+
+	return writeFileAtomically(path, append(payload, '\n'))
+}
+
+// Prune drops marks older than maxAge, so keys for pull requests that left the
+// list do not accumulate forever.
+func (s Seen) Prune(now time.Time, maxAge time.Duration) {
+	for key, mark := range s {
+		if now.Sub(mark.At) > maxAge {
+			delete(s, key)
+		}
+	}
+}
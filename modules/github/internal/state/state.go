@@ -3,17 +3,46 @@ package state
 import (
 	"encoding/json"
 	"fmt"
-	"html"
 	"os"
 	"path/filepath"
 	"strings"
+	"time"
 
 	"github.com/rbright/waybar-github/internal/github"
+	"github.com/rbright/waybar-shared/itemref"
+	"github.com/rbright/waybar-shared/menu"
 )
 
+// Meta records the last successful fetch so cached items can be rendered while offline.
+type Meta struct {
+	Count     int                  `json:"count"`
+	Searches  []github.SearchCount `json:"searches,omitempty"`
+	FetchedAt time.Time            `json:"fetchedAt,omitzero"`
+	// HostFetchedAt is the last good fetch per host, so a failing host keeps its
+	// cached section only within the stale window.
+	HostFetchedAt map[string]time.Time `json:"hostFetchedAt,omitempty"`
+	// HostCount is each host's share of Count. An item returned by several
+	// searches counts once, so it cannot be rebuilt from the section counts.
+	HostCount map[string]int `json:"hostCount,omitempty"`
+}
+
 type MenuData struct {
 	StatusLine string
 	Items      []github.PullRequest
+	// Searches switch the menu to one section per named search when there are several.
+	Searches []github.SearchCount
+	// ItemActions turns each row into a submenu of per-item actions.
+	ItemActions bool
+	// GroupByRepository groups rows by repository even with several searches.
+	GroupByRepository bool
+	// RepositorySubmenus nests each repository group in a submenu.
+	RepositorySubmenus bool
+	// SeparateDrafts lists drafts in their own section at the end.
+	SeparateDrafts bool
+	// Now dates the age shown on each row; rows carry no age when zero.
+	Now time.Time
+	// Slots are the menu slots of Items, which row ids use.
+	Slots []int
 }
 
 func EnsureDirs(stateDir, menuDir string) error {
@@ -26,33 +55,55 @@ func EnsureDirs(stateDir, menuDir string) error {
 	return nil
 }
 
-func SaveItems(path string, items []github.PullRequest) error {
+// SaveItems writes items and returns the menu slot of each one.
+func SaveItems(path string, items []github.PullRequest) ([]int, error) {
+	return itemref.Save(path, items, ItemKey)
+}
+
+// LoadItems returns the saved items and the menu slot of each one.
+func LoadItems(path string) ([]github.PullRequest, []int, error) {
+	return itemref.Load(path, ItemKey)
+}
+
+func ResolveItem(path string, ref itemref.Ref) (github.PullRequest, bool, error) {
+	return itemref.Resolve(path, ref, ItemKey)
+}
+
+// ItemKey identifies an item across refreshes, as in an id:KEY reference.
+func ItemKey(item github.PullRequest) string {
+	if item.ID != "" {
+		return item.ID
+	}
+	return item.URL
+}
+
+func SaveMeta(path string, meta Meta) error {
 	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
-		return fmt.Errorf("create items dir: %w", err)
+		return fmt.Errorf("create meta dir: %w", err)
 	}
 
-	payload, err := json.MarshalIndent(items, "", "  ")
+	payload, err := json.MarshalIndent(meta, "", "  ")
 	if err != nil {
-		return fmt.Errorf("marshal items: %w", err)
+		return fmt.Errorf("marshal meta: %w", err)
 	}
 
 	return writeFileAtomically(path, append(payload, '\n'))
 }
 
-func LoadItems(path string) ([]github.PullRequest, error) {
+func LoadMeta(path string) (Meta, error) {
 	raw, err := os.ReadFile(path)
 	if err != nil {
 		if os.IsNotExist(err) {
-			return nil, nil
+			return Meta{}, nil
 		}
-		return nil, fmt.Errorf("read items file: %w", err)
+		return Meta{}, fmt.Errorf("read meta file: %w", err)
 	}
 
-	var items []github.PullRequest
-	if err := json.Unmarshal(raw, &items); err != nil {
-		return nil, fmt.Errorf("decode items file: %w", err)
+	var meta Meta
+	if err := json.Unmarshal(raw, &meta); err != nil {
+		return Meta{}, fmt.Errorf("decode meta file: %w", err)
 	}
-	return items, nil
+	return meta, nil
 }
 
 func WriteMenu(path string, data MenuData) error {
@@ -60,44 +111,263 @@ func WriteMenu(path string, data MenuData) error {
 		return fmt.Errorf("create menu dir: %w", err)
 	}
 
-	var b strings.Builder
-	b.WriteString("<?xml version=\"1.0\" encoding=\"UTF-8\"?>\n")
-	b.WriteString("<interface>\n")
-	b.WriteString("  <object class=\"GtkMenu\" id=\"menu\">\n")
+	m := menu.New()
+	m.Item("open_dashboard", "Open GitHub Pull Requests")
 
-	writeMenuItem(&b, "open_dashboard", "Open GitHub Pull Requests")
-	writeSeparator(&b, "separator_primary")
+	rows, drafts := splitDrafts(data.Items, data.SeparateDrafts)
+	switch {
+	case len(data.Searches) > 1 && !data.GroupByRepository:
+		writeSearchSections(m, data, rows)
+	case len(data.Items) > 0:
+		writeRepositoryGroups(m, data, rows)
+	default:
+		m.Separator()
+		m.Info(fallback(data.StatusLine, "No matching pull requests"))
+	}
 
-	if len(data.Items) > 0 {
-		for idx, item := range data.Items {
-			label := fmt.Sprintf("%s #%d: %s", fallback(item.Repository, "unknown/unknown"), item.Number, fallback(item.Title, "Pull Request"))
-			writeMenuItem(&b, fmt.Sprintf("open_%d", idx+1), label)
+	if len(drafts) > 0 {
+		m.Section(fmt.Sprintf("Drafts (%d)", len(drafts)))
+		listed := make(map[string]bool)
+		for _, idx := range drafts {
+			item := data.Items[idx]
+			if listed[ItemKey(item)] {
+				continue
+			}
+			listed[ItemKey(item)] = true
+			writeItemRow(m, item, itemref.Slot(data.Slots, idx), itemLabel(item, fmt.Sprintf("%s #%d", fallback(item.Repository, "unknown/unknown"), item.Number), data.Now), data.ItemActions)
 		}
-	} else {
-		writeMenuItem(&b, "noop", fallback(data.StatusLine, "No matching pull requests"))
 	}
+	hidden, capped := github.TotalHidden(data.Searches), github.TotalCapped(data.Searches)
+	if hidden > 0 || capped > 0 {
+		m.Separator()
+	}
+	if hidden > 0 {
+		m.Info(fmt.Sprintf("%d hidden by filters", hidden))
+	}
+	if capped > 0 {
+		m.Info(fmt.Sprintf("%d over the per-repository cap", capped))
+	}
+
+	m.Separator()
+	m.Item("refresh", "Refresh")
 
-	writeSeparator(&b, "separator_footer")
-	writeMenuItem(&b, "refresh", "Refresh")
+	return writeFileAtomically(path, m.Bytes())
+}
+
+// writeSearchSections renders one section per named search. Rows keep ids from
+// their item's slot, like the repository-grouped layout. An item matched by
+// several searches gets its row in the first section only; later sections show
+// it as an insensitive copy, since GtkBuilder rejects a menu with repeated ids.
+func writeSearchSections(m *menu.Builder, data MenuData, rows []int) {
+	withHost := github.SpansHosts(data.Searches)
+	listed := make(map[string]bool)
+	for _, search := range data.Searches {
+		m.Section(fmt.Sprintf("%s (%s)", search.Label(withHost), github.CountLabel(search.Count, search)))
+		if search.Error != "" {
+			m.Info("⚠ " + search.Error)
+		}
+		shown := 0
+		for _, idx := range rows {
+			item := data.Items[idx]
+			if !search.Matches(item) {
+				continue
+			}
+			shown++
+			label := itemLabel(item, fmt.Sprintf("%s #%d", fallback(item.Repository, "unknown/unknown"), item.Number), data.Now)
+			if listed[ItemKey(item)] {
+				m.Info(label)
+				continue
+			}
+			listed[ItemKey(item)] = true
+			writeItemRow(m, item, itemref.Slot(data.Slots, idx), label, data.ItemActions)
+		}
+		if shown == 0 && search.Error == "" {
+			m.Info("None")
+		}
+	}
+}
 
-	b.WriteString("  </object>\n")
-	b.WriteString("</interface>\n")
+// writeRepositoryGroups renders one section, or submenu, per repository. Rows
+// are grouped but keep ids from their item's slot, which is what open-item
+// resolves against. An item returned by several searches is listed once.
+func writeRepositoryGroups(m *menu.Builder, data MenuData, rows []int) {
+	withHost := github.SpansHosts(data.Searches)
+	listed := make(map[string]bool)
+	var unique []int
+	for _, idx := range rows {
+		key := ItemKey(data.Items[idx])
+		if !listed[key] {
+			listed[key] = true
+			unique = append(unique, idx)
+		}
+	}
 
-	return writeFileAtomically(path, []byte(b.String()))
+	groups := groupByRepository(unique, func(idx int) string {
+		item := data.Items[idx]
+		if withHost && item.Host != "" {
+			return item.Host + " · " + fallback(item.Repository, "unknown/unknown")
+		}
+		return item.Repository
+	})
+	for n, group := range groups {
+		writeRows := func(b *menu.Builder) {
+			for _, position := range group.indexes {
+				idx := unique[position]
+				item := data.Items[idx]
+				writeItemRow(b, item, itemref.Slot(data.Slots, idx), itemLabel(item, fmt.Sprintf("#%d", item.Number), data.Now), data.ItemActions)
+			}
+		}
+		if !data.RepositorySubmenus {
+			m.Section(group.repository)
+			writeRows(m)
+			continue
+		}
+		if n == 0 {
+			m.Separator()
+		}
+		m.Submenu(fmt.Sprintf("repository_%d", n+1), fmt.Sprintf("%s (%d)", group.repository, len(group.indexes)), writeRows)
+	}
 }
 
-func writeMenuItem(b *strings.Builder, id, label string) {
-	b.WriteString("    <child>\n")
-	_, _ = fmt.Fprintf(b, "      <object class=\"GtkMenuItem\" id=\"%s\">\n", html.EscapeString(id))
-	_, _ = fmt.Fprintf(b, "        <property name=\"label\">%s</property>\n", html.EscapeString(label))
-	b.WriteString("      </object>\n")
-	b.WriteString("    </child>\n")
+// splitDrafts returns the item positions to list normally and, when separate,
+// the drafts to list in their own section.
+func splitDrafts(items []github.PullRequest, separate bool) (rows, drafts []int) {
+	for idx, item := range items {
+		if separate && item.IsDraft {
+			drafts = append(drafts, idx)
+		} else {
+			rows = append(rows, idx)
+		}
+	}
+	return rows, drafts
 }
 
-func writeSeparator(b *strings.Builder, id string) {
-	b.WriteString("    <child>\n")
-	_, _ = fmt.Fprintf(b, "      <object class=\"GtkSeparatorMenuItem\" id=\"%s\" />\n", html.EscapeString(id))
-	b.WriteString("    </child>\n")
+// writeItemRow adds the row for the item in slot n. With actions it becomes a
+// submenu whose first entry keeps the open_N id, so existing mappings still open
+// the item.
+func writeItemRow(m *menu.Builder, item github.PullRequest, n int, label string, actions bool) {
+	if !actions {
+		m.Item(fmt.Sprintf("open_%d", n), label)
+		return
+	}
+	m.Submenu(fmt.Sprintf("item_%d", n), label, func(sub *menu.Builder) {
+		sub.Item(fmt.Sprintf("open_%d", n), "Open in browser")
+		sub.Item(fmt.Sprintf("copy_url_%d", n), "Copy URL")
+		if !item.IsPullRequest() {
+			return
+		}
+		sub.Item(fmt.Sprintf("checkout_%d", n), "Check out locally")
+		if !item.Authored {
+			sub.Item(fmt.Sprintf("approve_%d", n), "Approve")
+		}
+		if item.CIFailing() {
+			sub.Item(fmt.Sprintf("rerun_failed_%d", n), "Re-run failed jobs")
+		}
+	})
+}
+
+// itemLabel renders a menu row: a CI or kind icon, the reference, the title, the
+// review state or labels, and the age.
+func itemLabel(item github.PullRequest, ref string, now time.Time) string {
+	if !item.IsPullRequest() {
+		label := kindPrefix(item) + ref + " " + fallback(item.Title, "Untitled")
+		if labels := item.LabelSummary(); labels != "" {
+			label += " · " + labels
+		}
+		return label + ageSuffix(item, now)
+	}
+
+	label := ciPrefix(item) + ref + " " + fallback(item.Title, "Pull Request")
+	if item.IsDraft {
+		label += " (draft)"
+	}
+	return label + reviewSuffix(item) + ageSuffix(item, now)
+}
+
+func kindPrefix(item github.PullRequest) string {
+	switch item.Kind {
+	case github.KindIssue:
+		return "⊙ "
+	case github.KindDiscussion:
+		return "💬 "
+	default:
+		return ""
+	}
+}
+
+func ciPrefix(item github.PullRequest) string {
+	switch {
+	case item.CIState == github.CIStateSuccess:
+		return "✓ "
+	case item.CIFailing():
+		return "✗ "
+	case item.CIPending():
+		return "● "
+	default:
+		return ""
+	}
+}
+
+func reviewSuffix(item github.PullRequest) string {
+	if summary := item.ReviewSummary(); summary != "" {
+		return " · " + summary
+	}
+	return ""
+}
+
+// ageSuffix shows how long the user's review request has waited, or otherwise
+// how long ago the item was last updated.
+func ageSuffix(item github.PullRequest, now time.Time) string {
+	switch {
+	case now.IsZero():
+		return ""
+	case item.ReviewRequested && !item.ReviewRequestedAt.IsZero():
+		return " · waiting " + shortAge(now.Sub(item.ReviewRequestedAt))
+	case !item.Updated().IsZero():
+		return " · " + shortAge(now.Sub(item.Updated()))
+	default:
+		return ""
+	}
+}
+
+// shortAge formats an age for a menu row, e.g. "45m", "5h", "2d" or "3w".
+func shortAge(age time.Duration) string {
+	days := int(age.Hours() / 24)
+	switch {
+	case age < time.Minute:
+		return "now"
+	case age < time.Hour:
+		return fmt.Sprintf("%dm", int(age.Minutes()))
+	case age < 24*time.Hour:
+		return fmt.Sprintf("%dh", int(age.Hours()))
+	case days < 14:
+		return fmt.Sprintf("%dd", days)
+	case days < 365:
+		return fmt.Sprintf("%dw", days/7)
+	default:
+		return fmt.Sprintf("%dy", days/365)
+	}
+}
+
+type repositoryGroup struct {
+	repository string
+	indexes    []int
+}
+
+func groupByRepository[T any](items []T, repositoryOf func(T) string) []repositoryGroup {
+	var groups []repositoryGroup
+	positions := make(map[string]int)
+	for idx, item := range items {
+		repository := fallback(repositoryOf(item), "unknown/unknown")
+		position, ok := positions[repository]
+		if !ok {
+			position = len(groups)
+			positions[repository] = position
+			groups = append(groups, repositoryGroup{repository: repository})
+		}
+		groups[position].indexes = append(groups[position].indexes, idx)
+	}
+	return groups
 }
 
 func writeFileAtomically(path string, content []byte) error {
@@ -0,0 +1,244 @@
+package state
+
+import (
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/rbright/waybar-github/internal/github"
+)
+
+func TestItemLabelCIPrefix(t *testing.T) {
+	tests := []struct {
+		ci   string
+		want string
+	}{
+		{ci: github.CIStateSuccess, want: "✓ acme/app #42 Fix login"},
+		{ci: github.CIStateFailure, want: "✗ acme/app #42 Fix login"},
+		{ci: github.CIStateError, want: "✗ acme/app #42 Fix login"},
+		{ci: github.CIStatePending, want: "● acme/app #42 Fix login"},
+		{ci: github.CIStateExpected, want: "● acme/app #42 Fix login"},
+		{ci: "", want: "acme/app #42 Fix login"},
+	}
+	for _, tt := range tests {
+		item := github.PullRequest{Number: 42, Title: "Fix login", CIState: tt.ci}
+		if got := itemLabel(item, "acme/app #42", time.Time{}); got != tt.want {
+			t.Fatalf("CI state %q: expected %q, got %q", tt.ci, tt.want, got)
+		}
+	}
+}
+
+func TestItemLabelReviewSuffix(t *testing.T) {
+	item := github.PullRequest{Number: 7, Title: "Add retries", IsDraft: true, ReviewDecision: github.ReviewRequired, RequestedReviewers: []string{"hubot"}}
+	if got, want := itemLabel(item, "acme/api #7", time.Time{}), "acme/api #7 Add retries (draft) · awaiting 1 reviewer"; got != want {
+		t.Fatalf("expected %q, got %q", want, got)
+	}
+}
+
+func TestSeenRoundTripAndPrune(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "seen.json")
+	now := time.Date(2024, 5, 1, 9, 0, 0, 0, time.UTC)
+
+	seen, ok, err := LoadSeen(path)
+	if err != nil || ok || len(seen) != 0 {
+		t.Fatalf("expected an empty, unwritten seen-set, got %v ok=%v err=%v", seen, ok, err)
+	}
+
+	seen["review:PR_7"] = SeenMark{At: now.Add(-time.Hour)}
+	seen["comments:PR_42"] = SeenMark{Value: "2024-04-30T09:00:00Z", At: now.Add(-31 * 24 * time.Hour)}
+	seen.Prune(now, 30*24*time.Hour)
+	if err := SaveSeen(path, seen); err != nil {
+		t.Fatalf("save: %v", err)
+	}
+
+	loaded, ok, err := LoadSeen(path)
+	if err != nil || !ok {
+		t.Fatalf("expected the saved seen-set, got ok=%v err=%v", ok, err)
+	}
+	if len(loaded) != 1 || !loaded["review:PR_7"].At.Equal(now.Add(-time.Hour)) {
+		t.Fatalf("expected only the recent mark to survive pruning, got %+v", loaded)
+	}
+}
+
+func TestRateLimitWait(t *testing.T) {
+	now := time.Date(2024, 5, 1, 9, 0, 0, 0, time.UTC)
+	budget := func(remaining int, resetIn time.Duration) RateLimit {
+		return RateLimit{RateLimit: github.RateLimit{Limit: 5000, Remaining: remaining, Cost: 1, ResetAt: now.Add(resetIn)}, CheckedAt: now.Add(-10 * time.Second)}
+	}
+
+	tests := []struct {
+		name  string
+		limit RateLimit
+		now   time.Time
+		want  time.Duration
+	}{
+		{name: "nothing recorded", limit: RateLimit{}, now: now, want: 0},
+		{name: "blocked", limit: RateLimit{BlockedUntil: now.Add(90 * time.Second)}, now: now, want: 90 * time.Second},
+		{name: "block over", limit: RateLimit{BlockedUntil: now.Add(-time.Second)}, now: now, want: 0},
+		{name: "plenty left", limit: budget(4100, time.Hour), now: now, want: 0},
+		// 100 polls over the hour are one every 36s; 10s have passed since the check.
+		{name: "paced", limit: budget(200, time.Hour), now: now, want: 26 * time.Second},
+		{name: "down to the reserve", limit: budget(100, time.Hour), now: now, want: time.Hour},
+		{name: "reset passed", limit: budget(50, time.Hour), now: now.Add(time.Hour), want: 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.limit.Wait(tt.now, 100); got != tt.want {
+				t.Fatalf("expected %s, got %s", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestRateLimitPacesByCost(t *testing.T) {
+	now := time.Date(2024, 5, 1, 9, 0, 0, 0, time.UTC)
+	limit := RateLimit{RateLimit: github.RateLimit{Limit: 5000, Remaining: 200, Cost: 4, ResetAt: now.Add(time.Hour)}, CheckedAt: now}
+
+	// 100 points above the reserve buy 25 polls at 4 points each.
+	if got, want := limit.Wait(now, 100), time.Hour/25; got != want {
+		t.Fatalf("expected %s, got %s", want, got)
+	}
+}
+
+func TestRateLimitBlock(t *testing.T) {
+	now := time.Date(2024, 5, 1, 9, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name       string
+		resetAt    time.Time
+		retryAfter time.Duration
+		want       time.Time
+	}{
+		{name: "retry after", resetAt: now.Add(time.Hour), retryAfter: 2 * time.Minute, want: now.Add(2 * time.Minute)},
+		{name: "until the reset", resetAt: now.Add(20 * time.Minute), want: now.Add(20 * time.Minute)},
+		{name: "reset unknown", want: now.Add(time.Minute)},
+		{name: "reset passed", resetAt: now.Add(-time.Minute), want: now.Add(time.Minute)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			limit := RateLimit{RateLimit: github.RateLimit{ResetAt: tt.resetAt}}
+			limit.Block(now, tt.retryAfter)
+			if !limit.BlockedUntil.Equal(tt.want) {
+				t.Fatalf("expected blocked until %s, got %s", tt.want, limit.BlockedUntil)
+			}
+			if wait := limit.Wait(now, 0); wait != tt.want.Sub(now) {
+				t.Fatalf("expected the block to hold fetches for %s, got %s", tt.want.Sub(now), wait)
+			}
+		})
+	}
+}
+
+func TestRateLimitRoundTrip(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "ratelimit-graphql-github.com.json")
+	if limit, err := LoadRateLimit(path); err != nil || limit != (RateLimit{}) {
+		t.Fatalf("expected no budget before the first save, got %+v (%v)", limit, err)
+	}
+
+	want := RateLimit{RateLimit: github.RateLimit{Limit: 5000, Remaining: 4987, Cost: 1, ResetAt: time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC)}, CheckedAt: time.Date(2024, 5, 1, 9, 0, 0, 0, time.UTC)}
+	if err := SaveRateLimit(path, want); err != nil {
+		t.Fatalf("save: %v", err)
+	}
+	got, err := LoadRateLimit(path)
+	if err != nil || !got.ResetAt.Equal(want.ResetAt) || got.Remaining != want.Remaining || !got.CheckedAt.Equal(want.CheckedAt) {
+		t.Fatalf("expected %+v, got %+v (%v)", want, got, err)
+	}
+}
+
+func TestItemLabelIssuesAndDiscussions(t *testing.T) {
+	now := time.Date(2024, 5, 1, 9, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name string
+		item github.PullRequest
+		want string
+	}{
+		{
+			name: "issue",
+			item: github.PullRequest{Kind: github.KindIssue, Title: "Login loops", Labels: []string{"bug", "p1"}, UpdatedAt: "2024-04-30T09:00:00Z"},
+			want: "⊙ acme/app #101 Login loops · bug, p1 · 1d",
+		},
+		{
+			name: "discussion",
+			item: github.PullRequest{Kind: github.KindDiscussion, Title: "Roadmap", Category: "Q&A", Answered: true},
+			want: "💬 acme/app #101 Roadmap · Q&A, answered",
+		},
+		{
+			name: "untitled issue",
+			item: github.PullRequest{Kind: github.KindIssue, CIState: github.CIStateFailure},
+			want: "⊙ acme/app #101 Untitled",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := itemLabel(tt.item, "acme/app #101", now); got != tt.want {
+				t.Fatalf("expected %q, got %q", tt.want, got)
+			}
+		})
+	}
+}
+
+// Issues and discussions have nothing to check out, approve or re-run.
+func TestWriteMenuIssueActions(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "menu.xml")
+	items := []github.PullRequest{
+		{ID: "I_1", Kind: github.KindIssue, Number: 101, Repository: "acme/app", Title: "Login loops"},
+		{ID: "PR_1", Kind: github.KindPullRequest, Number: 42, Repository: "acme/app", Title: "Fix login"},
+	}
+	if err := WriteMenu(path, MenuData{Items: items, ItemActions: true, Slots: []int{1, 2}}); err != nil {
+		t.Fatalf("write menu: %v", err)
+	}
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("read menu: %v", err)
+	}
+	menu := string(raw)
+	for _, id := range []string{"open_1", "copy_url_1", "open_2", "copy_url_2", "checkout_2", "approve_2"} {
+		if !strings.Contains(menu, `id="`+id+`"`) {
+			t.Fatalf("expected row %s, got\n%s", id, menu)
+		}
+	}
+	for _, id := range []string{"checkout_1", "approve_1"} {
+		if strings.Contains(menu, `id="`+id+`"`) {
+			t.Fatalf("expected no %s for an issue, got\n%s", id, menu)
+		}
+	}
+}
+
+// A pull request returned by two searches must not repeat row ids, or
+// GtkBuilder rejects the whole menu.
+func TestWriteMenuSearchSectionsKeepIDsUnique(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "menu.xml")
+	pr := github.PullRequest{ID: "PR_1", Kind: github.KindPullRequest, Number: 42, Repository: "acme/app", Title: "Fix login", CIState: github.CIStateFailure}
+	review, mine := pr, pr
+	review.Search, mine.Search = "Review", "Mine"
+	data := MenuData{
+		Items:       []github.PullRequest{review, mine},
+		Searches:    []github.SearchCount{{Name: "Review", Count: 1}, {Name: "Mine", Count: 1}},
+		ItemActions: true,
+		Slots:       []int{1, 1},
+	}
+	if err := WriteMenu(path, data); err != nil {
+		t.Fatalf("write menu: %v", err)
+	}
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("read menu: %v", err)
+	}
+
+	seen := make(map[string]bool)
+	for _, match := range regexp.MustCompile(`id="([^"]+)"`).FindAllStringSubmatch(string(raw), -1) {
+		if seen[match[1]] {
+			t.Fatalf("expected unique ids, got %s twice in\n%s", match[1], raw)
+		}
+		seen[match[1]] = true
+	}
+	for _, id := range []string{"item_1", "open_1", "copy_url_1", "checkout_1", "approve_1", "rerun_failed_1"} {
+		if !seen[id] {
+			t.Fatalf("expected row %s, got\n%s", id, raw)
+		}
+	}
+	if got := strings.Count(string(raw), "acme/app #42"); got != 2 {
+		t.Fatalf("expected the pull request under both searches, got %d rows in\n%s", got, raw)
+	}
+}
@@ -0,0 +1,145 @@
+package state
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"time"
+
+	"github.com/rbright/waybar-github/internal/github"
+	"github.com/rbright/waybar-shared/itemref"
+	"github.com/rbright/waybar-shared/menu"
+)
+
+// ActionsMeta records the watched branches of the last good fetch.
+type ActionsMeta struct {
+	Branches  []github.WatchedBranch `json:"branches,omitempty"`
+	FetchedAt time.Time              `json:"fetchedAt,omitzero"`
+	// DefaultBranches caches the default branch of repositories watched without
+	// one, so it is looked up only once.
+	DefaultBranches map[string]string `json:"defaultBranches,omitempty"`
+}
+
+type ActionsMenuData struct {
+	StatusLine string
+	Branches   []github.WatchedBranch
+	Runs       []github.WorkflowRun
+	Now        time.Time
+	// Slots are the menu slots of Runs, which row ids use.
+	Slots []int
+}
+
+// SaveWorkflowRuns writes runs and returns the menu slot of each one.
+func SaveWorkflowRuns(path string, runs []github.WorkflowRun) ([]int, error) {
+	return itemref.Save(path, runs, workflowRunKey)
+}
+
+// LoadWorkflowRuns returns the saved runs and the menu slot of each one.
+func LoadWorkflowRuns(path string) ([]github.WorkflowRun, []int, error) {
+	return itemref.Load(path, workflowRunKey)
+}
+
+func ResolveWorkflowRun(path string, ref itemref.Ref) (github.WorkflowRun, bool, error) {
+	return itemref.Resolve(path, ref, workflowRunKey)
+}
+
+func workflowRunKey(run github.WorkflowRun) string {
+	return strconv.FormatInt(run.ID, 10)
+}
+
+func SaveActionsMeta(path string, meta ActionsMeta) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return fmt.Errorf("create meta dir: %w", err)
+	}
+
+	payload, err := json.MarshalIndent(meta, "", "  ")
+	if err != nil {
+		return fmt.Errorf("marshal meta: %w", err)
+	}
+
+	return writeFileAtomically(path, append(payload, '\n'))
+}
+
+func LoadActionsMeta(path string) (ActionsMeta, error) {
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return ActionsMeta{}, nil
+		}
+		return ActionsMeta{}, fmt.Errorf("read meta file: %w", err)
+	}
+
+	var meta ActionsMeta
+	if err := json.Unmarshal(raw, &meta); err != nil {
+		return ActionsMeta{}, fmt.Errorf("decode meta file: %w", err)
+	}
+	return meta, nil
+}
+
+// WriteActionsMenu renders one section per watched branch. Failing and running
+// workflows are listed with links; passing ones are summarized.
+func WriteActionsMenu(path string, data ActionsMenuData) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return fmt.Errorf("create menu dir: %w", err)
+	}
+
+	m := menu.New()
+	m.Item("open_dashboard", "Open GitHub Actions")
+
+	if len(data.Branches) == 0 {
+		m.Separator()
+		m.Info(fallback(data.StatusLine, "No watched branches"))
+	}
+	for _, branch := range data.Branches {
+		m.Section(branch.Repository + " @ " + branch.Branch)
+		if branch.Error != "" {
+			m.Info("⚠ " + branch.Error)
+		}
+		total, passing := 0, 0
+		for idx, run := range data.Runs {
+			if !branch.Matches(run) {
+				continue
+			}
+			total++
+			if !run.Failed() && !run.Running() {
+				passing++
+				continue
+			}
+			m.Item(fmt.Sprintf("open_%d", itemref.Slot(data.Slots, idx)), runLabel(run, data.Now))
+		}
+		switch {
+		case total == 0 && branch.Error == "":
+			m.Info("No workflow runs")
+		case passing == total && total > 0:
+			m.Info(fmt.Sprintf("✓ All %d workflow(s) passing", total))
+		case passing > 0:
+			m.Info(fmt.Sprintf("✓ %d other(s) passing", passing))
+		}
+	}
+
+	m.Separator()
+	m.Item("refresh", "Refresh")
+
+	return writeFileAtomically(path, m.Bytes())
+}
+
+// runLabel renders a run row, e.g. "✗ CI #412 · Fix login redirect · 2h".
+func runLabel(run github.WorkflowRun, now time.Time) string {
+	prefix := "✓ "
+	switch {
+	case run.Failed():
+		prefix = "✗ "
+	case run.Running():
+		prefix = "● "
+	}
+	label := fmt.Sprintf("%s%s #%d", prefix, fallback(run.Workflow, "Workflow"), run.RunNumber)
+	if run.Title != "" {
+		label += " · " + run.Title
+	}
+	if !now.IsZero() && !run.UpdatedAt.IsZero() {
+		label += " · " + shortAge(now.Sub(run.UpdatedAt))
+	}
+	return label
+}
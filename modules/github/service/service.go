@@ -0,0 +1,50 @@
+// Package service exposes the module to the waybar-modules host, which serves
+// every module from one daemon process.
+package service
+
+import (
+	"context"
+	"fmt"
+	"io"
+
+	"github.com/rbright/waybar-github/internal/app"
+	"github.com/rbright/waybar-github/internal/config"
+	"github.com/rbright/waybar-shared/daemon"
+)
+
+// Services returns one daemon service per mode. Modes nobody subscribes to
+// stay idle, so hosting all of them costs nothing.
+func Services() ([]daemon.Service, error) {
+	modes := []string{config.ModePullRequests, config.ModeNotifications, config.ModeActions}
+	services := make([]daemon.Service, 0, len(modes))
+	for _, mode := range modes {
+		cfg, err := config.Load(mode)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", mode, err)
+		}
+		services = append(services, app.Service(cfg))
+	}
+	return services, nil
+}
+
+// Command runs the desktop-notify command, optionally behind a mode, that the
+// services start their own executable with to show a notification and wait
+// for its click. Under the host that executable is waybar-modules, which hands
+// the command here. It reports false for any other command.
+func Command(args []string) (bool, error) {
+	mode := ""
+	if len(args) > 0 && config.IsMode(args[0]) {
+		mode, args = args[0], args[1:]
+	}
+	if len(args) == 0 || args[0] != "desktop-notify" {
+		return false, nil
+	}
+
+	cfg, err := config.Load(mode)
+	if err != nil {
+		return true, err
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), app.Timeout(cfg, args))
+	defer cancel()
+	return true, app.Run(ctx, args, cfg, io.Discard)
+}
@@ -0,0 +1,121 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"os"
+	"os/signal"
+	"syscall"
+
+	agentusage "github.com/rbright/waybar-agent-usage/service"
+	github "github.com/rbright/waybar-github/service"
+	linear "github.com/rbright/waybar-linear/service"
+	schedule "github.com/rbright/waybar-schedule/service"
+	"github.com/rbright/waybar-shared/daemon"
+	sotto "github.com/rbright/waybar-sotto/service"
+)
+
+type module struct {
+	name     string
+	services func() ([]daemon.Service, error)
+	// command runs the commands a module's services start the host binary
+	// with, such as a notifier waiting for a click. It reports false for
+	// commands the module does not know.
+	command func(args []string) (bool, error)
+}
+
+var modules = []module{
+	{name: "agent-usage", services: agentusage.Services},
+	{name: "github", services: github.Services, command: github.Command},
+	{name: "linear", services: linear.Services},
+	{name: "schedule", services: schedule.Services},
+	{name: "sotto", services: sotto.Services},
+}
+
+func main() {
+	args, debug := extractFlag(os.Args[1:], "--debug")
+
+	level := slog.LevelInfo
+	if debug {
+		level = slog.LevelDebug
+	}
+	slog.SetDefault(slog.New(slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{Level: level})))
+
+	if len(args) != 1 || args[0] != "daemon" {
+		handled, err := runCommand(args)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(2)
+		}
+		if handled {
+			return
+		}
+		printUsage()
+		if len(args) == 1 && (args[0] == "-h" || args[0] == "--help" || args[0] == "help") {
+			return
+		}
+		os.Exit(2)
+	}
+
+	if err := run(); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(2)
+	}
+}
+
+func run() error {
+	var services []daemon.Service
+	for _, m := range modules {
+		loaded, err := m.services()
+		if err != nil {
+			// One broken config should not take the other modules off the bar.
+			slog.Error("module skipped", "module", m.name, "error", err)
+			continue
+		}
+		services = append(services, loaded...)
+	}
+	if len(services) == 0 {
+		return fmt.Errorf("no module could be loaded")
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	for _, service := range services {
+		slog.Info("serving module", "service", service.Name, "socket", service.SocketPath, "interval", service.Interval)
+	}
+	return daemon.Serve(ctx, services...)
+}
+
+// runCommand hands args to the first module that knows them. Hosted services
+// re-run their own executable for some work, and under the host that is this
+// binary rather than the module's.
+func runCommand(args []string) (bool, error) {
+	for _, m := range modules {
+		if m.command == nil {
+			continue
+		}
+		if handled, err := m.command(args); handled {
+			return true, err
+		}
+	}
+	return false, nil
+}
+
+func extractFlag(args []string, flag string) ([]string, bool) {
+	filtered := make([]string, 0, len(args))
+	found := false
+	for _, arg := range args {
+		if arg == flag {
+			found = true
+			continue
+		}
+		filtered = append(filtered, arg)
+	}
+	return filtered, found
+}
+
+func printUsage() {
+	fmt.Println("waybar-modules daemon [--debug]")
+}
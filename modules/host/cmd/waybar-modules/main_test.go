@@ -0,0 +1,126 @@
+package main
+
+import (
+	"bufio"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/godbus/dbus/v5"
+)
+
+const (
+	notificationsName = "org.freedesktop.Notifications"
+	notificationsPath = dbus.ObjectPath("/org/freedesktop/Notifications")
+)
+
+// fakeNotifications is a minimal org.freedesktop.Notifications that records
+// each notification and closes it unclicked.
+type fakeNotifications struct {
+	conn *dbus.Conn
+
+	mu        sync.Mutex
+	summaries []string
+}
+
+func (s *fakeNotifications) Notify(_ string, _ uint32, _ string, summary, _ string, _ []string, _ map[string]dbus.Variant, _ int32) (uint32, *dbus.Error) {
+	s.mu.Lock()
+	s.summaries = append(s.summaries, summary)
+	s.mu.Unlock()
+
+	const id = uint32(1)
+	go func() {
+		time.Sleep(20 * time.Millisecond)
+		_ = s.conn.Emit(notificationsPath, notificationsName+".NotificationClosed", id, uint32(2))
+	}()
+	return id, nil
+}
+
+func startNotificationServer(t *testing.T) *fakeNotifications {
+	t.Helper()
+
+	daemonPath, err := exec.LookPath("dbus-daemon")
+	if err != nil {
+		t.Skip("dbus-daemon not available")
+	}
+
+	address := "unix:path=" + filepath.Join(t.TempDir(), "bus")
+	cmd := exec.Command(daemonPath, "--session", "--nofork", "--nopidfile", "--print-address", "--address="+address)
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		t.Fatalf("dbus-daemon stdout: %v", err)
+	}
+	if err := cmd.Start(); err != nil {
+		t.Fatalf("start dbus-daemon: %v", err)
+	}
+	t.Cleanup(func() {
+		_ = cmd.Process.Kill()
+		_ = cmd.Wait()
+	})
+
+	line, err := bufio.NewReader(stdout).ReadString('\n')
+	if err != nil {
+		t.Fatalf("read bus address: %v", err)
+	}
+	t.Setenv("DBUS_SESSION_BUS_ADDRESS", strings.TrimSpace(line))
+
+	conn, err := dbus.ConnectSessionBus()
+	if err != nil {
+		t.Fatalf("connect private bus: %v", err)
+	}
+	t.Cleanup(func() {
+		_ = conn.Close()
+	})
+
+	server := &fakeNotifications{conn: conn}
+	if err := conn.Export(server, notificationsPath, notificationsName); err != nil {
+		t.Fatalf("export server: %v", err)
+	}
+	reply, err := conn.RequestName(notificationsName, dbus.NameFlagDoNotQueue)
+	if err != nil || reply != dbus.RequestNameReplyPrimaryOwner {
+		t.Fatalf("request name: %v %v", reply, err)
+	}
+	return server
+}
+
+// The github services show notifications by re-running their executable with
+// desktop-notify; under the host that executable is this binary.
+func TestRunCommandShowsGitHubNotifications(t *testing.T) {
+	server := startNotificationServer(t)
+	dir := t.TempDir()
+	t.Setenv("HOME", dir)
+	t.Setenv("XDG_CONFIG_HOME", filepath.Join(dir, "config"))
+	t.Setenv("XDG_STATE_HOME", filepath.Join(dir, "state"))
+	t.Setenv("WAYBAR_GITHUB_CONFIG_FILE", filepath.Join(dir, "missing.env"))
+
+	for _, args := range [][]string{
+		{"desktop-notify", "Review requested", "acme/app #42", "https://github.com/acme/app/pull/42"},
+		{"pull-requests", "desktop-notify", "CI failed", "acme/app #42", "https://github.com/acme/app/pull/42", "rerun-failed", "id:PR_1"},
+	} {
+		handled, err := runCommand(args)
+		if err != nil {
+			t.Fatalf("run %v: %v", args, err)
+		}
+		if !handled {
+			t.Fatalf("expected %v to be handled", args)
+		}
+	}
+
+	server.mu.Lock()
+	defer server.mu.Unlock()
+	if got := strings.Join(server.summaries, ", "); got != "Review requested, CI failed" {
+		t.Fatalf("expected both notifications shown, got %q", got)
+	}
+}
+
+func TestRunCommandLeavesUnknownCommands(t *testing.T) {
+	for _, args := range [][]string{nil, {"status"}, {"pull-requests", "refresh"}} {
+		handled, err := runCommand(args)
+		if handled || err != nil {
+			t.Fatalf("expected %v to be left for usage, got %v %v", args, handled, err)
+		}
+	}
+}
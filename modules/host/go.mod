@@ -0,0 +1,42 @@
+module github.com/rbright/waybar-host
+
+go 1.25.5
+
+require (
+	github.com/godbus/dbus/v5 v5.1.0
+	github.com/rbright/waybar-agent-usage v0.0.0
+	github.com/rbright/waybar-github v0.0.0
+	github.com/rbright/waybar-linear v0.0.0
+	github.com/rbright/waybar-schedule v0.0.0
+	github.com/rbright/waybar-shared v0.0.0
+	github.com/rbright/waybar-sotto v0.0.0
+)
+
+require (
+	github.com/arran4/golang-ical v0.3.2 // indirect
+	github.com/fsnotify/fsnotify v1.9.0 // indirect
+	github.com/go-viper/mapstructure/v2 v2.4.0 // indirect
+	github.com/jfreymuth/pulse v0.1.1 // indirect
+	github.com/pelletier/go-toml/v2 v2.2.4 // indirect
+	github.com/sagikazarmark/locafero v0.11.0 // indirect
+	github.com/sourcegraph/conc v0.3.1-0.20240121214520-5f936abd7ae8 // indirect
+	github.com/spf13/afero v1.15.0 // indirect
+	github.com/spf13/cast v1.10.0 // indirect
+	github.com/spf13/pflag v1.0.10 // indirect
+	github.com/spf13/viper v1.21.0 // indirect
+	github.com/subosito/gotenv v1.6.0 // indirect
+	github.com/teambition/rrule-go v1.8.2 // indirect
+	go.yaml.in/yaml/v3 v3.0.4 // indirect
+	golang.org/x/sys v0.29.0 // indirect
+	golang.org/x/text v0.28.0 // indirect
+	gopkg.in/ini.v1 v1.67.0 // indirect
+)
+
+replace (
+	github.com/rbright/waybar-agent-usage => ../agent-usage
+	github.com/rbright/waybar-github => ../github
+	github.com/rbright/waybar-linear => ../linear
+	github.com/rbright/waybar-schedule => ../schedule
+	github.com/rbright/waybar-shared => ../shared
+	github.com/rbright/waybar-sotto => ../sotto
+)
@@ -4,21 +4,30 @@ import (
 	"context"
 	"fmt"
 	"io"
-	"os/exec"
+	"log/slog"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/rbright/waybar-linear/internal/config"
 	"github.com/rbright/waybar-linear/internal/linear"
 	"github.com/rbright/waybar-linear/internal/state"
 	"github.com/rbright/waybar-linear/internal/waybar"
+	"github.com/rbright/waybar-shared/barsignal"
+	"github.com/rbright/waybar-shared/itemref"
+	"github.com/rbright/waybar-shared/logging"
+	"github.com/rbright/waybar-shared/network"
+	"github.com/rbright/waybar-shared/opener"
 )
 
 func Run(ctx context.Context, args []string, cfg config.Runtime, stdout io.Writer) error {
-	cmd, index, err := parseArgs(args)
+	cmd, ref, lines, err := parseArgs(args)
 	if err != nil {
 		return err
 	}
+	if cmd != "logs" && cmd != "store-token" && cmd != "doctor" {
+		cfg = withKeyringToken(ctx, cfg)
+	}
 
 	switch cmd {
 	case "status":
@@ -28,41 +37,65 @@ func Run(ctx context.Context, args []string, cfg config.Runtime, stdout io.Write
 		}
 		return writeOutput(stdout, out)
 	case "refresh":
-		_, err := buildStatus(ctx, cfg)
-		return err
+		if _, err := buildStatus(ctx, cfg); err != nil {
+			return err
+		}
+		signalBar(cfg)
+		return nil
 	case "open-inbox":
 		return openInbox(ctx, cfg)
 	case "mark-all-read":
 		return markAllRead(ctx, cfg)
 	case "open-item":
-		return openItem(ctx, cfg, index)
+		return openItem(ctx, cfg, ref)
+	case "logs":
+		return logging.Tail(cfg.LogPath, lines, stdout)
+	case "doctor":
+		return runDoctor(ctx, cfg, stdout)
+	case "store-token":
+		return storeToken(ctx, cfg, stdout)
 	default:
 		return fmt.Errorf("unsupported command %q", cmd)
 	}
 }
 
-func parseArgs(args []string) (command string, index int, err error) {
+// parseArgs returns the command, the item reference of item commands and the
+// line count of logs.
+func parseArgs(args []string) (command string, ref itemref.Ref, lines int, err error) {
 	if len(args) == 0 {
-		return "status", 0, nil
+		return "status", itemref.Ref{}, 0, nil
 	}
 
 	switch strings.TrimSpace(args[0]) {
-	case "status", "refresh", "open-inbox", "mark-all-read":
+	case "status", "refresh", "open-inbox", "mark-all-read", "doctor", "store-token":
 		if len(args) > 1 {
-			return "", 0, fmt.Errorf("unexpected argument %q", args[1])
+			return "", itemref.Ref{}, 0, fmt.Errorf("unexpected argument %q", args[1])
 		}
-		return strings.TrimSpace(args[0]), 0, nil
+		return strings.TrimSpace(args[0]), itemref.Ref{}, 0, nil
 	case "open-item":
 		if len(args) != 2 {
-			return "", 0, fmt.Errorf("usage: waybar-linear open-item <index>")
+			return "", itemref.Ref{}, 0, fmt.Errorf("usage: waybar-linear open-item <N|id:KEY>")
+		}
+		ref, refErr := itemref.Parse(args[1])
+		if refErr != nil {
+			return "", itemref.Ref{}, 0, refErr
 		}
-		n, convErr := strconv.Atoi(strings.TrimSpace(args[1]))
-		if convErr != nil || n < 1 {
-			return "", 0, fmt.Errorf("invalid item index %q", args[1])
+		return "open-item", ref, 0, nil
+	case "logs":
+		if len(args) > 2 {
+			return "", itemref.Ref{}, 0, fmt.Errorf("usage: waybar-linear logs [lines]")
 		}
-		return "open-item", n, nil
+		lines = 50
+		if len(args) == 2 {
+			n, convErr := strconv.Atoi(strings.TrimSpace(args[1]))
+			if convErr != nil || n < 1 {
+				return "", itemref.Ref{}, 0, fmt.Errorf("invalid line count %q", args[1])
+			}
+			lines = n
+		}
+		return "logs", itemref.Ref{}, lines, nil
 	default:
-		return "", 0, fmt.Errorf("usage: waybar-linear <status|refresh|open-inbox|mark-all-read|open-item N>")
+		return "", itemref.Ref{}, 0, fmt.Errorf("usage: waybar-linear <status|refresh|open-inbox|mark-all-read|open-item N|logs [N]|doctor|store-token>")
 	}
 }
 
@@ -72,8 +105,9 @@ func buildStatus(ctx context.Context, cfg config.Runtime) (waybar.Output, error)
 	}
 
 	if strings.TrimSpace(cfg.APIKey) == "" {
-		statusLine := "Set LINEAR_API_KEY in ~/.config/waybar/linear-notifications.env"
-		if err := state.SaveItems(cfg.ItemsPath, []linear.Notification{}); err != nil {
+		slog.Warn("linear api key missing", "config_file", cfg.ConfigFile)
+		statusLine := "Set LINEAR_API_KEY or run waybar-linear store-token"
+		if _, err := state.SaveItems(cfg.ItemsPath, []linear.Notification{}); err != nil {
 			return waybar.Output{}, err
 		}
 		if err := state.SaveMeta(cfg.MetaPath, state.Meta{}); err != nil {
@@ -89,10 +123,24 @@ func buildStatus(ctx context.Context, cfg config.Runtime) (waybar.Output, error)
 		}, nil
 	}
 
+	if cfg.NetworkCheck && !network.Online(ctx, cfg.APIURL) {
+		slog.Info("offline; skipping linear fetch")
+		meta, err := state.LoadMeta(cfg.MetaPath)
+		if err != nil {
+			return waybar.Output{}, err
+		}
+		return renderCached(cfg, meta, "offline", "Offline")
+	}
+
 	result, err := linear.FetchNotifications(ctx, cfg)
 	if err != nil {
+		slog.Warn("linear fetch failed", "error", err)
+		// Keep the last good items and menu through transient failures.
+		if meta, metaErr := state.LoadMeta(cfg.MetaPath); metaErr == nil && network.WithinStaleAge(meta.FetchedAt, cfg.StaleAfter) {
+			return renderCached(cfg, meta, "stale", "Refresh failed: "+err.Error())
+		}
 		statusLine := "Linear API request failed"
-		if saveErr := state.SaveItems(cfg.ItemsPath, []linear.Notification{}); saveErr != nil {
+		if _, saveErr := state.SaveItems(cfg.ItemsPath, []linear.Notification{}); saveErr != nil {
 			return waybar.Output{}, saveErr
 		}
 		if metaErr := state.SaveMeta(cfg.MetaPath, state.Meta{}); metaErr != nil {
@@ -108,10 +156,12 @@ func buildStatus(ctx context.Context, cfg config.Runtime) (waybar.Output, error)
 		}, nil
 	}
 
-	if err := state.SaveItems(cfg.ItemsPath, result.Items); err != nil {
+	slots, err := state.SaveItems(cfg.ItemsPath, result.Items)
+	if err != nil {
 		return waybar.Output{}, err
 	}
-	if err := state.SaveMeta(cfg.MetaPath, state.Meta{URLKey: result.URLKey}); err != nil {
+	meta := state.Meta{URLKey: result.URLKey, UnreadCount: result.UnreadCount, FetchedAt: time.Now().UTC()}
+	if err := state.SaveMeta(cfg.MetaPath, meta); err != nil {
 		return waybar.Output{}, err
 	}
 
@@ -124,14 +174,30 @@ func buildStatus(ctx context.Context, cfg config.Runtime) (waybar.Output, error)
 		StatusLine:   statusLine,
 		Items:        result.Items,
 		AllowMarkAll: result.UnreadCount > 0,
+		Slots:        slots,
 	}); err != nil {
 		return waybar.Output{}, err
 	}
 
-	tooltip := fmt.Sprintf("Linear unread notifications: %d", result.UnreadCount)
-	if len(result.Items) > 0 {
-		lines := make([]string, 0, len(result.Items))
-		for _, item := range result.Items {
+	tooltip := buildTooltip(result.UnreadCount, result.Items) + "\nClick to open dropdown"
+
+	className := "clear"
+	if result.UnreadCount > 0 {
+		className = "normal"
+	}
+
+	return waybar.Output{
+		Text:    strconv.Itoa(result.UnreadCount),
+		Tooltip: tooltip,
+		Class:   className,
+	}, nil
+}
+
+func buildTooltip(unreadCount int, items []linear.Notification) string {
+	tooltip := fmt.Sprintf("Linear unread notifications: %d", unreadCount)
+	if len(items) > 0 {
+		lines := make([]string, 0, len(items))
+		for _, item := range items {
 			line := fallback(item.Subtitle, "Notification")
 			if strings.TrimSpace(item.Title) != "" && strings.TrimSpace(item.Title) != strings.TrimSpace(item.Subtitle) {
 				line += ": " + item.Title
@@ -140,15 +206,29 @@ func buildStatus(ctx context.Context, cfg config.Runtime) (waybar.Output, error)
 		}
 		tooltip += "\n" + strings.Join(lines, "\n")
 	}
-	tooltip += "\nClick to open dropdown"
+	return tooltip
+}
 
-	className := "clear"
-	if result.UnreadCount > 0 {
-		className = "normal"
+// renderCached shows the last successful fetch without touching saved items or
+// the menu, so the dropdown keeps working while offline or after a failed refresh.
+func renderCached(cfg config.Runtime, meta state.Meta, className, reason string) (waybar.Output, error) {
+	if meta.FetchedAt.IsZero() {
+		return waybar.Output{
+			Text:    "?",
+			Tooltip: fmt.Sprintf("Linear notifications: %s; nothing cached yet", reason),
+			Class:   className,
+		}, nil
+	}
+
+	items, _, err := state.LoadItems(cfg.ItemsPath)
+	if err != nil {
+		return waybar.Output{}, err
 	}
 
+	tooltip := buildTooltip(meta.UnreadCount, items)
+	tooltip += "\n\n" + network.CachedNote(reason, meta.FetchedAt)
 	return waybar.Output{
-		Text:    strconv.Itoa(result.UnreadCount),
+		Text:    strconv.Itoa(meta.UnreadCount),
 		Tooltip: tooltip,
 		Class:   className,
 	}, nil
@@ -164,7 +244,7 @@ func openInbox(ctx context.Context, cfg config.Runtime) error {
 	if strings.TrimSpace(meta.URLKey) != "" {
 		url = fmt.Sprintf("https://linear.app/%s/inbox", strings.TrimSpace(meta.URLKey))
 	}
-	return openURL(ctx, url)
+	return openURL(ctx, cfg, url)
 }
 
 func markAllRead(ctx context.Context, cfg config.Runtime) error {
@@ -183,48 +263,48 @@ func markAllRead(ctx context.Context, cfg config.Runtime) error {
 		}
 	}
 
-	_, err = buildStatus(ctx, cfg)
-	return err
+	if _, err := buildStatus(ctx, cfg); err != nil {
+		return err
+	}
+	signalBar(cfg)
+	return nil
 }
 
-func openItem(ctx context.Context, cfg config.Runtime, index int) error {
-	items, err := state.LoadItems(cfg.ItemsPath)
-	if err != nil {
+func openItem(ctx context.Context, cfg config.Runtime, ref itemref.Ref) error {
+	item, ok, err := state.ResolveItem(cfg.ItemsPath, ref)
+	if err != nil || !ok {
 		return err
 	}
-	if len(items) == 0 || index > len(items) {
-		return nil
-	}
 
-	item := items[index-1]
 	url := strings.TrimSpace(item.URL)
 	if url == "" {
 		return nil
 	}
 
-	if err := openURL(ctx, url); err != nil {
+	if err := openURL(ctx, cfg, url); err != nil {
 		return err
 	}
 
 	if strings.TrimSpace(cfg.APIKey) != "" && strings.TrimSpace(item.ID) != "" {
-		if err := linear.MarkRead(ctx, cfg, item.ID); err == nil {
-			_, _ = buildStatus(ctx, cfg)
+		if err := linear.MarkRead(ctx, cfg, item.ID); err != nil {
+			slog.Warn("linear mark read failed", "id", item.ID, "error", err)
+		} else if _, err := buildStatus(ctx, cfg); err == nil {
+			signalBar(cfg)
 		}
 	}
 
 	return nil
 }
 
-func openURL(ctx context.Context, url string) error {
-	if _, err := exec.LookPath("xdg-open"); err != nil {
-		return fmt.Errorf("xdg-open not found")
-	}
+func openURL(ctx context.Context, cfg config.Runtime, url string) error {
+	return opener.Open(ctx, cfg.Opener, url)
+}
 
-	cmd := exec.CommandContext(ctx, "xdg-open", url)
-	if err := cmd.Start(); err != nil {
-		return fmt.Errorf("open url: %w", err)
+func signalBar(cfg config.Runtime) {
+	if err := barsignal.Send(cfg.Signal); err != nil {
+		// The next interval catches up, so a failed signal only delays the bar.
+		slog.Debug("signal waybar failed", "signal", cfg.Signal, "error", err)
 	}
-	return nil
 }
 
 func writeOutput(w io.Writer, output waybar.Output) error {
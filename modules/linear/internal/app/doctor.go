@@ -0,0 +1,49 @@
+package app
+
+import (
+	"context"
+	"fmt"
+	"io"
+
+	"github.com/rbright/waybar-linear/internal/config"
+	"github.com/rbright/waybar-shared/doctor"
+	"github.com/rbright/waybar-shared/opener"
+	"github.com/rbright/waybar-shared/secrets"
+)
+
+func runDoctor(ctx context.Context, cfg config.Runtime, stdout io.Writer) error {
+	var report doctor.Report
+
+	report.ConfigFile("config", cfg.ConfigFile, config.ValidateEnvFile)
+	report.LogLevel(cfg.LogLevel)
+	checkOpener(&report, cfg, "open notifications")
+	switch resolved := withKeyringToken(ctx, cfg); {
+	case cfg.APIKey != "":
+		report.OK("api key", "set in environment")
+	case resolved.APIKey != "":
+		report.OK("api key", "found in keyring ("+secrets.FormatAttributes(cfg.SecretAttributes)+")")
+	default:
+		report.Fail("api key", "set WAYBAR_LINEAR_API_KEY/LINEAR_API_KEY or run `waybar-linear store-token`")
+	}
+	report.WritableDir("state dir", cfg.StateDir)
+	report.WritableDir("menu dir", cfg.MenuDir)
+
+	if err := report.Write(stdout); err != nil {
+		return err
+	}
+	return report.Err()
+}
+
+func checkOpener(report *doctor.Report, cfg config.Runtime, purpose string) {
+	if rules, err := opener.LoadRules(cfg.Opener.RulesFile); err != nil {
+		report.Fail("opener rules", err.Error())
+	} else if len(rules) > 0 {
+		report.OK("opener rules", fmt.Sprintf("%d rule(s) from %s", len(rules), cfg.Opener.RulesFile))
+	}
+	command, err := opener.Describe(cfg.Opener)
+	if err != nil {
+		report.Fail("url opener", err.Error()+" (needed to "+purpose+")")
+		return
+	}
+	report.OK("url opener", command)
+}
@@ -0,0 +1,55 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"strings"
+
+	"github.com/rbright/waybar-linear/internal/config"
+	"github.com/rbright/waybar-shared/secrets"
+)
+
+var stdin io.Reader = os.Stdin
+
+// withKeyringToken fills the API key from the Secret Service when neither the
+// environment nor the env file provides one.
+func withKeyringToken(ctx context.Context, cfg config.Runtime) config.Runtime {
+	if strings.TrimSpace(cfg.APIKey) != "" || len(cfg.SecretAttributes) == 0 {
+		return cfg
+	}
+
+	token, err := secrets.Lookup(ctx, cfg.SecretAttributes)
+	if err != nil {
+		if !errors.Is(err, secrets.ErrNotFound) {
+			slog.Warn("keyring lookup failed", "attributes", secrets.FormatAttributes(cfg.SecretAttributes), "error", err)
+		}
+		return cfg
+	}
+	slog.Debug("api key loaded from keyring")
+	cfg.APIKey = token
+	return cfg
+}
+
+func storeToken(ctx context.Context, cfg config.Runtime, stdout io.Writer) error {
+	if len(cfg.SecretAttributes) == 0 {
+		return errors.New("WAYBAR_LINEAR_SECRET_ATTRIBUTES is empty")
+	}
+
+	token, err := secrets.ReadValue(stdin)
+	if err != nil {
+		return fmt.Errorf("read token from stdin: %w", err)
+	}
+	if token == "" {
+		return errors.New("usage: waybar-linear store-token < token-file (token is read from stdin)")
+	}
+
+	if err := secrets.Store(ctx, "Waybar Linear API key", cfg.SecretAttributes, token); err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(stdout, "Stored API key in the Secret Service (%s)\n", secrets.FormatAttributes(cfg.SecretAttributes))
+	return err
+}
@@ -8,6 +8,9 @@ import (
 	"strings"
 	"time"
 
+	"github.com/rbright/waybar-shared/daemon"
+	"github.com/rbright/waybar-shared/opener"
+	"github.com/rbright/waybar-shared/secrets"
 	"github.com/spf13/viper"
 )
 
@@ -26,6 +29,23 @@ type Runtime struct {
 	MenuPath  string
 	ItemsPath string
 	MetaPath  string
+
+	SocketPath     string
+	DaemonInterval time.Duration
+	Signal         int
+
+	// NetworkCheck skips remote fetches while offline and renders cached state instead.
+	NetworkCheck bool
+	// StaleAfter is how long the last good fetch is shown after refresh errors; 0 disables it.
+	StaleAfter time.Duration
+
+	LogLevel string
+	LogPath  string
+
+	Opener opener.Config
+
+	// SecretAttributes locate the token in the Secret Service when none is set in the environment.
+	SecretAttributes map[string]string
 }
 
 func Load() (Runtime, error) {
@@ -63,12 +83,27 @@ func Load() (Runtime, error) {
 	_ = v.BindEnv("timeout_seconds", "WAYBAR_LINEAR_TIMEOUT_SECONDS")
 	_ = v.BindEnv("state_dir", "WAYBAR_LINEAR_STATE_DIR")
 	_ = v.BindEnv("menu_dir", "WAYBAR_LINEAR_MENU_DIR")
+	_ = v.BindEnv("socket_path", "WAYBAR_LINEAR_SOCKET_PATH")
+	_ = v.BindEnv("daemon_interval_seconds", "WAYBAR_LINEAR_DAEMON_INTERVAL_SECONDS")
+	_ = v.BindEnv("signal", "WAYBAR_LINEAR_SIGNAL")
+	_ = v.BindEnv("network_check", "WAYBAR_LINEAR_NETWORK_CHECK")
+	_ = v.BindEnv("stale_after_seconds", "WAYBAR_LINEAR_STALE_AFTER_SECONDS")
+	_ = v.BindEnv("log_level", "WAYBAR_LINEAR_LOG_LEVEL")
+	_ = v.BindEnv("opener_command", "WAYBAR_LINEAR_OPENER_COMMAND", "WAYBAR_OPENER_COMMAND")
+	_ = v.BindEnv("opener_rules_file", "WAYBAR_LINEAR_OPENER_RULES_FILE", "WAYBAR_OPENER_RULES_FILE")
 
 	v.SetDefault("api_url", "https://api.linear.app/graphql")
 	v.SetDefault("max_items", 8)
 	v.SetDefault("timeout_seconds", 15)
 	v.SetDefault("state_dir", filepath.Join(xdgState, "waybar", "linear-notifications"))
 	v.SetDefault("menu_dir", filepath.Join(xdgState, "waybar", "menus"))
+	v.SetDefault("socket_path", daemon.SocketPath("linear"))
+	v.SetDefault("daemon_interval_seconds", 60)
+	v.SetDefault("signal", 0)
+	v.SetDefault("network_check", true)
+	v.SetDefault("stale_after_seconds", 900)
+	v.SetDefault("log_level", "warn")
+	v.SetDefault("opener_rules_file", filepath.Join(xdgConfig, "waybar", "url-opener.rules"))
 
 	maxItems := v.GetInt("max_items")
 	if maxItems < 1 {
@@ -98,6 +133,35 @@ func Load() (Runtime, error) {
 		menuDir = filepath.Join(xdgState, "waybar", "menus")
 	}
 
+	socketPath := strings.TrimSpace(v.GetString("socket_path"))
+	if socketPath == "" {
+		socketPath = daemon.SocketPath("linear")
+	}
+
+	daemonIntervalSeconds := v.GetInt("daemon_interval_seconds")
+	if daemonIntervalSeconds <= 0 {
+		daemonIntervalSeconds = 60
+	}
+
+	staleAfterSeconds := v.GetInt("stale_after_seconds")
+	if staleAfterSeconds < 0 {
+		staleAfterSeconds = 0
+	}
+
+	signal := v.GetInt("signal")
+	if signal < 0 || signal > 30 {
+		signal = 0
+	}
+
+	secretAttributes := "service=waybar-linear"
+	if raw, ok := os.LookupEnv("WAYBAR_LINEAR_SECRET_ATTRIBUTES"); ok {
+		secretAttributes = raw
+	}
+	parsedSecretAttributes, err := secrets.ParseAttributes(secretAttributes)
+	if err != nil {
+		return Runtime{}, fmt.Errorf("parse WAYBAR_LINEAR_SECRET_ATTRIBUTES: %w", err)
+	}
+
 	return Runtime{
 		ConfigFile: configFile,
 		APIURL:     apiURL,
@@ -109,6 +173,22 @@ func Load() (Runtime, error) {
 		MenuPath:   filepath.Join(menuDir, "linear-notifications.xml"),
 		ItemsPath:  filepath.Join(stateDir, "items.json"),
 		MetaPath:   filepath.Join(stateDir, "meta.json"),
+
+		SocketPath:     socketPath,
+		DaemonInterval: time.Duration(daemonIntervalSeconds) * time.Second,
+		Signal:         signal,
+		NetworkCheck:   v.GetBool("network_check"),
+		StaleAfter:     time.Duration(staleAfterSeconds) * time.Second,
+
+		LogLevel: strings.TrimSpace(v.GetString("log_level")),
+		LogPath:  filepath.Join(stateDir, "linear.log"),
+
+		Opener: opener.Config{
+			Command:   strings.TrimSpace(v.GetString("opener_command")),
+			RulesFile: strings.TrimSpace(v.GetString("opener_rules_file")),
+		},
+
+		SecretAttributes: parsedSecretAttributes,
 	}, nil
 }
 
@@ -164,3 +244,37 @@ func loadEnvFile(path string) error {
 	}
 	return nil
 }
+
+// ValidateEnvFile reports lines that the env-file loader would silently skip.
+func ValidateEnvFile(path string) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("open env file %s: %w", path, err)
+	}
+	defer func() {
+		_ = file.Close()
+	}()
+
+	var problems []string
+	scanner := bufio.NewScanner(file)
+	for lineNumber := 1; scanner.Scan(); lineNumber++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		line = strings.TrimSpace(strings.TrimPrefix(line, "export "))
+
+		key, _, ok := strings.Cut(line, "=")
+		if !ok || strings.TrimSpace(key) == "" {
+			problems = append(problems, fmt.Sprintf("line %d", lineNumber))
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return fmt.Errorf("scan env file %s: %w", path, err)
+	}
+
+	if len(problems) > 0 {
+		return fmt.Errorf("%s: malformed KEY=VALUE on %s", path, strings.Join(problems, ", "))
+	}
+	return nil
+}
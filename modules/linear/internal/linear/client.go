@@ -6,6 +6,7 @@ import (
 	"encoding/json"
 	"fmt"
 	"io"
+	"log/slog"
 	"net/http"
 	"strings"
 	"time"
@@ -124,6 +125,7 @@ func FetchNotifications(ctx context.Context, cfg config.Runtime) (FetchResult, e
 		}
 	}
 
+	slog.Debug("linear notifications fetched", "nodes", len(response.Data.Notifications.Nodes), "unread", count)
 	return FetchResult{
 		URLKey:      strings.TrimSpace(response.Data.Organization.URLKey),
 		UnreadCount: count,
@@ -177,6 +179,7 @@ func doGraphQL(ctx context.Context, cfg config.Runtime, query string, variables
 	req.Header.Set("Content-Type", "application/json")
 	req.Header.Set("Authorization", cfg.APIKey)
 
+	start := time.Now()
 	client := &http.Client{Timeout: maxDuration(cfg.Timeout, 10*time.Second)}
 	resp, err := client.Do(req)
 	if err != nil {
@@ -190,6 +193,7 @@ func doGraphQL(ctx context.Context, cfg config.Runtime, query string, variables
 	if err != nil {
 		return nil, fmt.Errorf("read graphql response: %w", err)
 	}
+	slog.Debug("linear graphql response", "status", resp.StatusCode, "duration", time.Since(start), "bytes", len(responseBody))
 
 	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
 		return nil, fmt.Errorf("linear graphql status %d: %s", resp.StatusCode, strings.TrimSpace(string(responseBody)))
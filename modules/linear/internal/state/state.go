@@ -3,22 +3,28 @@ package state
 import (
 	"encoding/json"
 	"fmt"
-	"html"
 	"os"
 	"path/filepath"
 	"strings"
+	"time"
 
 	"github.com/rbright/waybar-linear/internal/linear"
+	"github.com/rbright/waybar-shared/itemref"
+	"github.com/rbright/waybar-shared/menu"
 )
 
 type Meta struct {
-	URLKey string `json:"urlKey"`
+	URLKey      string    `json:"urlKey"`
+	UnreadCount int       `json:"unreadCount"`
+	FetchedAt   time.Time `json:"fetchedAt,omitzero"`
 }
 
 type MenuData struct {
 	StatusLine   string
 	Items        []linear.Notification
 	AllowMarkAll bool
+	// Slots are the menu slots of Items, which row ids use.
+	Slots []int
 }
 
 func EnsureDirs(stateDir, menuDir string) error {
@@ -31,33 +37,22 @@ func EnsureDirs(stateDir, menuDir string) error {
 	return nil
 }
 
-func SaveItems(path string, items []linear.Notification) error {
-	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
-		return fmt.Errorf("create items dir: %w", err)
-	}
-
-	payload, err := json.MarshalIndent(items, "", "  ")
-	if err != nil {
-		return fmt.Errorf("marshal items: %w", err)
-	}
+// SaveItems writes items and returns the menu slot of each one.
+func SaveItems(path string, items []linear.Notification) ([]int, error) {
+	return itemref.Save(path, items, itemKey)
+}
 
-	return writeFileAtomically(path, append(payload, '\n'))
+// LoadItems returns the saved items and the menu slot of each one.
+func LoadItems(path string) ([]linear.Notification, []int, error) {
+	return itemref.Load(path, itemKey)
 }
 
-func LoadItems(path string) ([]linear.Notification, error) {
-	raw, err := os.ReadFile(path)
-	if err != nil {
-		if os.IsNotExist(err) {
-			return nil, nil
-		}
-		return nil, fmt.Errorf("read items file: %w", err)
-	}
+func ResolveItem(path string, ref itemref.Ref) (linear.Notification, bool, error) {
+	return itemref.Resolve(path, ref, itemKey)
+}
 
-	var items []linear.Notification
-	if err := json.Unmarshal(raw, &items); err != nil {
-		return nil, fmt.Errorf("decode items file: %w", err)
-	}
-	return items, nil
+func itemKey(item linear.Notification) string {
+	return item.ID
 }
 
 func SaveMeta(path string, meta Meta) error {
@@ -94,16 +89,12 @@ func WriteMenu(path string, data MenuData) error {
 		return fmt.Errorf("create menu dir: %w", err)
 	}
 
-	var b strings.Builder
-	b.WriteString("<?xml version=\"1.0\" encoding=\"UTF-8\"?>\n")
-	b.WriteString("<interface>\n")
-	b.WriteString("  <object class=\"GtkMenu\" id=\"menu\">\n")
-
-	writeMenuItem(&b, "open_inbox", "Open Linear Inbox")
+	m := menu.New()
+	m.Item("open_inbox", "Open Linear Inbox")
 	if data.AllowMarkAll {
-		writeMenuItem(&b, "mark_all_read", "Mark All as Read")
+		m.Item("mark_all_read", "Mark All as Read")
 	}
-	writeSeparator(&b, "separator_primary")
+	m.Separator()
 
 	if len(data.Items) > 0 {
 		for idx, item := range data.Items {
@@ -111,33 +102,16 @@ func WriteMenu(path string, data MenuData) error {
 			if strings.TrimSpace(item.Title) != "" && strings.TrimSpace(item.Title) != strings.TrimSpace(item.Subtitle) {
 				label += ": " + item.Title
 			}
-			writeMenuItem(&b, fmt.Sprintf("open_%d", idx+1), label)
+			m.Item(fmt.Sprintf("open_%d", itemref.Slot(data.Slots, idx)), label)
 		}
 	} else {
-		writeMenuItem(&b, "noop", fallback(data.StatusLine, "No unread notifications"))
+		m.Info(fallback(data.StatusLine, "No unread notifications"))
 	}
 
-	writeSeparator(&b, "separator_footer")
-	writeMenuItem(&b, "refresh", "Refresh")
-
-	b.WriteString("  </object>\n")
-	b.WriteString("</interface>\n")
-
-	return writeFileAtomically(path, []byte(b.String()))
-}
-
-func writeMenuItem(b *strings.Builder, id, label string) {
-	b.WriteString("    <child>\n")
-	_, _ = fmt.Fprintf(b, "      <object class=\"GtkMenuItem\" id=\"%s\">\n", html.EscapeString(id))
-	_, _ = fmt.Fprintf(b, "        <property name=\"label\">%s</property>\n", html.EscapeString(label))
-	b.WriteString("      </object>\n")
-	b.WriteString("    </child>\n")
-}
+	m.Separator()
+	m.Item("refresh", "Refresh")
 
-func writeSeparator(b *strings.Builder, id string) {
-	b.WriteString("    <child>\n")
-	_, _ = fmt.Fprintf(b, "      <object class=\"GtkSeparatorMenuItem\" id=\"%s\" />\n", html.EscapeString(id))
-	b.WriteString("    </child>\n")
+	return writeFileAtomically(path, m.Bytes())
 }
 
 func writeFileAtomically(path string, content []byte) error {
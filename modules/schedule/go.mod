@@ -24,3 +24,7 @@ require (
 	golang.org/x/sys v0.29.0 // indirect
 	golang.org/x/text v0.28.0 // indirect
 )
+
+require github.com/rbright/waybar-shared v0.0.0
+
+replace github.com/rbright/waybar-shared => ../shared
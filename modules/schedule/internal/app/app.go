@@ -5,6 +5,7 @@ import (
 	"errors"
 	"fmt"
 	"io"
+	"log/slog"
 	"os/exec"
 	"sort"
 	"strconv"
@@ -17,10 +18,14 @@ import (
 	"github.com/rbright/waybar-schedule/internal/selector"
 	"github.com/rbright/waybar-schedule/internal/state"
 	"github.com/rbright/waybar-schedule/internal/waybar"
+	"github.com/rbright/waybar-shared/barsignal"
+	"github.com/rbright/waybar-shared/itemref"
+	"github.com/rbright/waybar-shared/logging"
+	"github.com/rbright/waybar-shared/opener"
 )
 
 func Run(ctx context.Context, args []string, cfg config.Runtime, stdout io.Writer) error {
-	cmd, index, err := parseArgs(args)
+	cmd, ref, lines, err := parseArgs(args)
 	if err != nil {
 		return err
 	}
@@ -33,41 +38,63 @@ func Run(ctx context.Context, args []string, cfg config.Runtime, stdout io.Write
 		}
 		return writeOutput(stdout, out)
 	case "refresh":
-		_, statusErr := buildStatus(ctx, cfg)
-		return statusErr
+		if _, statusErr := buildStatus(ctx, cfg); statusErr != nil {
+			return statusErr
+		}
+		signalBar(cfg)
+		return nil
 	case "join-next":
 		return joinNext(ctx, cfg)
 	case "join-item":
-		return joinItem(ctx, cfg, index)
+		return joinItem(ctx, cfg, ref)
 	case "select-calendars":
 		return selectCalendars(ctx, cfg, stdout)
+	case "logs":
+		return logging.Tail(cfg.LogPath, lines, stdout)
+	case "doctor":
+		return runDoctor(ctx, cfg, stdout)
 	default:
 		return fmt.Errorf("unsupported command %q", cmd)
 	}
 }
 
-func parseArgs(args []string) (command string, index int, err error) {
+// parseArgs returns the command, the item reference of item commands and the
+// line count of logs.
+func parseArgs(args []string) (command string, ref itemref.Ref, lines int, err error) {
 	if len(args) == 0 {
-		return "status", 0, nil
+		return "status", itemref.Ref{}, 0, nil
 	}
 
 	switch strings.TrimSpace(args[0]) {
-	case "status", "refresh", "join-next", "select-calendars":
+	case "status", "refresh", "join-next", "select-calendars", "doctor":
 		if len(args) > 1 {
-			return "", 0, fmt.Errorf("unexpected argument %q", args[1])
+			return "", itemref.Ref{}, 0, fmt.Errorf("unexpected argument %q", args[1])
 		}
-		return strings.TrimSpace(args[0]), 0, nil
+		return strings.TrimSpace(args[0]), itemref.Ref{}, 0, nil
 	case "join-item":
 		if len(args) != 2 {
-			return "", 0, fmt.Errorf("usage: waybar-schedule join-item <index>")
+			return "", itemref.Ref{}, 0, fmt.Errorf("usage: waybar-schedule join-item <N|id:KEY>")
 		}
-		n, convErr := strconv.Atoi(strings.TrimSpace(args[1]))
-		if convErr != nil || n < 1 {
-			return "", 0, fmt.Errorf("invalid item index %q", args[1])
+		ref, refErr := itemref.Parse(args[1])
+		if refErr != nil {
+			return "", itemref.Ref{}, 0, refErr
 		}
-		return "join-item", n, nil
+		return "join-item", ref, 0, nil
+	case "logs":
+		if len(args) > 2 {
+			return "", itemref.Ref{}, 0, fmt.Errorf("usage: waybar-schedule logs [lines]")
+		}
+		lines = 50
+		if len(args) == 2 {
+			n, convErr := strconv.Atoi(strings.TrimSpace(args[1]))
+			if convErr != nil || n < 1 {
+				return "", itemref.Ref{}, 0, fmt.Errorf("invalid line count %q", args[1])
+			}
+			lines = n
+		}
+		return "logs", itemref.Ref{}, lines, nil
 	default:
-		return "", 0, fmt.Errorf("usage: waybar-schedule <status|refresh|join-next|join-item N|select-calendars>")
+		return "", itemref.Ref{}, 0, fmt.Errorf("usage: waybar-schedule <status|refresh|join-next|join-item N|select-calendars|logs [N]|doctor>")
 	}
 }
 
@@ -78,6 +105,7 @@ func buildStatus(ctx context.Context, cfg config.Runtime) (waybar.Output, error)
 
 	client, err := eds.New(ctx)
 	if err != nil {
+		slog.Warn("eds unavailable", "error", err)
 		return renderUnknownState(cfg, "EDS is not available")
 	}
 	defer func() {
@@ -86,6 +114,7 @@ func buildStatus(ctx context.Context, cfg config.Runtime) (waybar.Output, error)
 
 	calendars, err := client.ListCalendars(ctx)
 	if err != nil {
+		slog.Warn("eds list calendars failed", "error", err)
 		return renderErrorState(cfg, fmt.Sprintf("Failed to list calendars: %s", err.Error()))
 	}
 
@@ -100,9 +129,10 @@ func buildStatus(ctx context.Context, cfg config.Runtime) (waybar.Output, error)
 
 	selectedUIDs := resolveSelectedUIDs(calendars, selection)
 	selectedCalendars := filterCalendarsByUID(calendars, selectedUIDs)
+	slog.Debug("calendars resolved", "available", len(calendars), "selected", len(selectedCalendars), "selection_file", selection.Exists)
 	if len(selectedCalendars) == 0 {
 		statusLine := "No calendars selected"
-		if err := state.SaveMeetings(cfg.ItemsPath, []schedule.Occurrence{}); err != nil {
+		if _, err := state.SaveMeetings(cfg.ItemsPath, []schedule.Occurrence{}); err != nil {
 			return waybar.Output{}, err
 		}
 		if err := state.WriteMenu(cfg.MenuPath, state.MenuData{StatusLine: statusLine}); err != nil {
@@ -119,15 +149,25 @@ func buildStatus(ctx context.Context, cfg config.Runtime) (waybar.Output, error)
 	windowStart := now.Add(-cfg.QueryLookback)
 	windowEnd := now.Add(cfg.QueryAhead)
 
+	start := time.Now()
 	rawEvents, err := client.FetchRawEvents(ctx, selectedCalendars, windowStart, windowEnd)
 	if err != nil {
+		slog.Warn("calendar query failed", "duration", time.Since(start), "error", err)
 		return renderErrorState(cfg, fmt.Sprintf("Calendar query failed: %s", err.Error()))
 	}
 
 	occurrences := schedule.ExpandEvents(rawEvents, windowStart, windowEnd)
 	meetingOccurrences := schedule.MeetingOnly(occurrences)
 	upcoming := schedule.Upcoming(meetingOccurrences, now, cfg.Lookahead, cfg.MaxItems, cfg.IncludeAllDay)
-	if err := state.SaveMeetings(cfg.ItemsPath, upcoming); err != nil {
+	slog.Debug("calendar events fetched",
+		"duration", time.Since(start),
+		"events", len(rawEvents),
+		"occurrences", len(occurrences),
+		"meetings", len(meetingOccurrences),
+		"upcoming", len(upcoming),
+	)
+	slots, err := state.SaveMeetings(cfg.ItemsPath, upcoming)
+	if err != nil {
 		return waybar.Output{}, err
 	}
 
@@ -138,7 +178,7 @@ func buildStatus(ctx context.Context, cfg config.Runtime) (waybar.Output, error)
 	}
 
 	statusLine := fmt.Sprintf("No meeting link in next %d minutes", int(cfg.Lookahead.Minutes()))
-	menuData := state.MenuData{StatusLine: statusLine, Items: upcoming}
+	menuData := state.MenuData{StatusLine: statusLine, Items: upcoming, Slots: slots, CalendarSubmenus: cfg.CalendarSubmenus}
 	if hasNext {
 		menuData.Next = &next
 		statusLine = "Upcoming meeting"
@@ -276,33 +316,30 @@ func filterCalendarsByUID(calendars []schedule.Calendar, selectedUIDs []string)
 }
 
 func joinNext(ctx context.Context, cfg config.Runtime) error {
-	items, err := state.LoadMeetings(cfg.ItemsPath)
+	items, _, err := state.LoadMeetings(cfg.ItemsPath)
 	if err != nil {
 		return err
 	}
 	if len(items) == 0 {
 		return nil
 	}
-	return openMeeting(ctx, items[0])
+	return openMeeting(ctx, cfg, items[0])
 }
 
-func joinItem(ctx context.Context, cfg config.Runtime, index int) error {
-	items, err := state.LoadMeetings(cfg.ItemsPath)
-	if err != nil {
+func joinItem(ctx context.Context, cfg config.Runtime, ref itemref.Ref) error {
+	item, ok, err := state.ResolveMeeting(cfg.ItemsPath, ref)
+	if err != nil || !ok {
 		return err
 	}
-	if len(items) == 0 || index > len(items) {
-		return nil
-	}
-	return openMeeting(ctx, items[index-1])
+	return openMeeting(ctx, cfg, item)
 }
 
-func openMeeting(ctx context.Context, item schedule.Occurrence) error {
+func openMeeting(ctx context.Context, cfg config.Runtime, item schedule.Occurrence) error {
 	url := strings.TrimSpace(item.JoinURL)
 	if url == "" {
 		return nil
 	}
-	return openURL(ctx, url)
+	return openURL(ctx, cfg, url)
 }
 
 func selectCalendars(ctx context.Context, cfg config.Runtime, stdout io.Writer) error {
@@ -339,6 +376,7 @@ func selectCalendars(ctx context.Context, cfg config.Runtime, stdout io.Writer)
 		if errors.Is(err, selector.ErrSelectionCancelled) {
 			return nil
 		}
+		slog.Warn("calendar selection failed", "error", err)
 		notifySelectionError(ctx, err.Error())
 		return err
 	}
@@ -350,21 +388,14 @@ func selectCalendars(ctx context.Context, cfg config.Runtime, stdout io.Writer)
 	if _, err := buildStatus(ctx, cfg); err != nil {
 		return err
 	}
+	signalBar(cfg)
 
 	_, _ = fmt.Fprintf(stdout, "Saved %d selected calendar(s)\n", len(selected))
 	return nil
 }
 
-func openURL(ctx context.Context, url string) error {
-	if _, err := exec.LookPath("xdg-open"); err != nil {
-		return fmt.Errorf("xdg-open not found")
-	}
-
-	cmd := exec.CommandContext(ctx, "xdg-open", url)
-	if err := cmd.Start(); err != nil {
-		return fmt.Errorf("open url: %w", err)
-	}
-	return nil
+func openURL(ctx context.Context, cfg config.Runtime, url string) error {
+	return opener.Open(ctx, cfg.Opener, url)
 }
 
 func notifySelectionError(ctx context.Context, message string) {
@@ -380,7 +411,7 @@ func notifySelectionError(ctx context.Context, message string) {
 }
 
 func renderUnknownState(cfg config.Runtime, tooltip string) (waybar.Output, error) {
-	if err := state.SaveMeetings(cfg.ItemsPath, []schedule.Occurrence{}); err != nil {
+	if _, err := state.SaveMeetings(cfg.ItemsPath, []schedule.Occurrence{}); err != nil {
 		return waybar.Output{}, err
 	}
 	if err := state.WriteMenu(cfg.MenuPath, state.MenuData{StatusLine: tooltip}); err != nil {
@@ -390,7 +421,7 @@ func renderUnknownState(cfg config.Runtime, tooltip string) (waybar.Output, erro
 }
 
 func renderErrorState(cfg config.Runtime, tooltip string) (waybar.Output, error) {
-	if err := state.SaveMeetings(cfg.ItemsPath, []schedule.Occurrence{}); err != nil {
+	if _, err := state.SaveMeetings(cfg.ItemsPath, []schedule.Occurrence{}); err != nil {
 		return waybar.Output{}, err
 	}
 	if err := state.WriteMenu(cfg.MenuPath, state.MenuData{StatusLine: "Calendar query failed"}); err != nil {
@@ -399,6 +430,13 @@ func renderErrorState(cfg config.Runtime, tooltip string) (waybar.Output, error)
 	return waybar.Output{Text: "!", Tooltip: tooltip, Class: "error"}, nil
 }
 
+func signalBar(cfg config.Runtime) {
+	if err := barsignal.Send(cfg.Signal); err != nil {
+		// The next interval catches up, so a failed signal only delays the bar.
+		slog.Debug("signal waybar failed", "signal", cfg.Signal, "error", err)
+	}
+}
+
 func writeOutput(w io.Writer, output waybar.Output) error {
 	payload, err := waybar.Encode(output)
 	if err != nil {
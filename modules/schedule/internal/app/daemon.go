@@ -0,0 +1,32 @@
+package app
+
+import (
+	"context"
+	"io"
+	"time"
+
+	"github.com/rbright/waybar-schedule/internal/config"
+	"github.com/rbright/waybar-shared/daemon"
+)
+
+// Timeout bounds one command run for args.
+func Timeout(cfg config.Runtime, args []string) time.Duration {
+	timeout := cfg.Timeout + 5*time.Second
+	if timeout < 10*time.Second {
+		timeout = 10 * time.Second
+	}
+	return timeout
+}
+
+// Service serves Run for cfg from a daemon.
+func Service(cfg config.Runtime) daemon.Service {
+	return daemon.Service{
+		Name:       "schedule",
+		SocketPath: cfg.SocketPath,
+		Interval:   cfg.DaemonInterval,
+		Timeout:    Timeout(cfg, nil),
+		Handler: func(ctx context.Context, args []string, stdout io.Writer) error {
+			return Run(ctx, args, cfg, stdout)
+		},
+	}
+}
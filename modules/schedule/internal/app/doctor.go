@@ -0,0 +1,54 @@
+package app
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"path/filepath"
+
+	"github.com/rbright/waybar-schedule/internal/config"
+	"github.com/rbright/waybar-schedule/internal/eds"
+	"github.com/rbright/waybar-shared/doctor"
+	"github.com/rbright/waybar-shared/opener"
+)
+
+func runDoctor(ctx context.Context, cfg config.Runtime, stdout io.Writer) error {
+	var report doctor.Report
+
+	report.ConfigFile("config", cfg.ConfigFile, config.ValidateEnvFile)
+	report.LogLevel(cfg.LogLevel)
+	checkOpener(&report, cfg, "join meetings")
+	report.Command("notify-send", "needed for join-next feedback", false)
+	report.Command("zenity", "needed for select-calendars", false)
+
+	if client, err := eds.New(ctx); err != nil {
+		report.Fail("evolution data server", err.Error())
+	} else {
+		source, calendar := client.ServiceNames()
+		report.OK("evolution data server", fmt.Sprintf("%s, %s", source, calendar))
+		_ = client.Close()
+	}
+
+	report.WritableDir("state dir", cfg.StateDir)
+	report.WritableDir("menu dir", cfg.MenuDir)
+	report.WritableDir("selection dir", filepath.Dir(cfg.SelectionPath))
+
+	if err := report.Write(stdout); err != nil {
+		return err
+	}
+	return report.Err()
+}
+
+func checkOpener(report *doctor.Report, cfg config.Runtime, purpose string) {
+	if rules, err := opener.LoadRules(cfg.Opener.RulesFile); err != nil {
+		report.Fail("opener rules", err.Error())
+	} else if len(rules) > 0 {
+		report.OK("opener rules", fmt.Sprintf("%d rule(s) from %s", len(rules), cfg.Opener.RulesFile))
+	}
+	command, err := opener.Describe(cfg.Opener)
+	if err != nil {
+		report.Fail("url opener", err.Error()+" (needed to "+purpose+")")
+		return
+	}
+	report.OK("url opener", command)
+}
@@ -8,6 +8,8 @@ import (
 	"strings"
 	"time"
 
+	"github.com/rbright/waybar-shared/daemon"
+	"github.com/rbright/waybar-shared/opener"
 	"github.com/spf13/viper"
 )
 
@@ -22,6 +24,8 @@ type Runtime struct {
 	IncludeAllDay bool
 	MaxItems      int
 	Timeout       time.Duration
+	// CalendarSubmenus nests each calendar's meetings in a dropdown submenu.
+	CalendarSubmenus bool
 
 	StateDir      string
 	MenuDir       string
@@ -29,6 +33,15 @@ type Runtime struct {
 	ItemsPath     string
 	CalendarsPath string
 	SelectionPath string
+
+	SocketPath     string
+	DaemonInterval time.Duration
+	Signal         int
+
+	LogLevel string
+	LogPath  string
+
+	Opener opener.Config
 }
 
 func Load() (Runtime, error) {
@@ -64,9 +77,16 @@ func Load() (Runtime, error) {
 	_ = v.BindEnv("query_ahead_days", "WAYBAR_SCHEDULE_QUERY_AHEAD_DAYS", "QUERY_AHEAD_DAYS")
 	_ = v.BindEnv("include_all_day", "WAYBAR_SCHEDULE_INCLUDE_ALL_DAY", "INCLUDE_ALL_DAY")
 	_ = v.BindEnv("max_items", "WAYBAR_SCHEDULE_MAX_ITEMS", "MAX_ITEMS")
+	_ = v.BindEnv("calendar_submenus", "WAYBAR_SCHEDULE_CALENDAR_SUBMENUS")
 	_ = v.BindEnv("timeout_seconds", "WAYBAR_SCHEDULE_TIMEOUT_SECONDS")
 	_ = v.BindEnv("state_dir", "WAYBAR_SCHEDULE_STATE_DIR")
 	_ = v.BindEnv("menu_dir", "WAYBAR_SCHEDULE_MENU_DIR")
+	_ = v.BindEnv("socket_path", "WAYBAR_SCHEDULE_SOCKET_PATH")
+	_ = v.BindEnv("daemon_interval_seconds", "WAYBAR_SCHEDULE_DAEMON_INTERVAL_SECONDS")
+	_ = v.BindEnv("signal", "WAYBAR_SCHEDULE_SIGNAL")
+	_ = v.BindEnv("log_level", "WAYBAR_SCHEDULE_LOG_LEVEL")
+	_ = v.BindEnv("opener_command", "WAYBAR_SCHEDULE_OPENER_COMMAND", "WAYBAR_OPENER_COMMAND")
+	_ = v.BindEnv("opener_rules_file", "WAYBAR_SCHEDULE_OPENER_RULES_FILE", "WAYBAR_OPENER_RULES_FILE")
 	_ = v.BindEnv("selection_file", "WAYBAR_SCHEDULE_SELECTION_FILE")
 
 	v.SetDefault("lookahead_minutes", 60)
@@ -74,9 +94,15 @@ func Load() (Runtime, error) {
 	v.SetDefault("query_ahead_days", 14)
 	v.SetDefault("include_all_day", false)
 	v.SetDefault("max_items", 8)
+	v.SetDefault("calendar_submenus", false)
 	v.SetDefault("timeout_seconds", 20)
 	v.SetDefault("state_dir", filepath.Join(xdgState, "waybar", "schedule"))
 	v.SetDefault("menu_dir", filepath.Join(xdgState, "waybar", "menus"))
+	v.SetDefault("socket_path", daemon.SocketPath("schedule"))
+	v.SetDefault("daemon_interval_seconds", 60)
+	v.SetDefault("signal", 0)
+	v.SetDefault("log_level", "warn")
+	v.SetDefault("opener_rules_file", filepath.Join(xdgConfig, "waybar", "url-opener.rules"))
 	v.SetDefault("selection_file", filepath.Join(xdgConfig, "waybar", "schedule-selected-calendars.json"))
 
 	maxItems := v.GetInt("max_items")
@@ -122,6 +148,21 @@ func Load() (Runtime, error) {
 		selectionPath = filepath.Join(xdgConfig, "waybar", "schedule-selected-calendars.json")
 	}
 
+	socketPath := strings.TrimSpace(v.GetString("socket_path"))
+	if socketPath == "" {
+		socketPath = daemon.SocketPath("schedule")
+	}
+
+	daemonIntervalSeconds := v.GetInt("daemon_interval_seconds")
+	if daemonIntervalSeconds <= 0 {
+		daemonIntervalSeconds = 60
+	}
+
+	signal := v.GetInt("signal")
+	if signal < 0 || signal > 30 {
+		signal = 0
+	}
+
 	return Runtime{
 		ConfigFile: configFile,
 		Lookahead:  time.Duration(lookaheadMinutes) * time.Minute,
@@ -131,12 +172,27 @@ func Load() (Runtime, error) {
 		IncludeAllDay: v.GetBool("include_all_day"),
 		MaxItems:      maxItems,
 		Timeout:       time.Duration(timeoutSeconds) * time.Second,
+
+		CalendarSubmenus: v.GetBool("calendar_submenus"),
+
 		StateDir:      stateDir,
 		MenuDir:       menuDir,
 		MenuPath:      filepath.Join(menuDir, "schedule.xml"),
 		ItemsPath:     filepath.Join(stateDir, "meetings.json"),
 		CalendarsPath: filepath.Join(stateDir, "calendars.json"),
 		SelectionPath: selectionPath,
+
+		SocketPath:     socketPath,
+		DaemonInterval: time.Duration(daemonIntervalSeconds) * time.Second,
+		Signal:         signal,
+
+		LogLevel: strings.TrimSpace(v.GetString("log_level")),
+		LogPath:  filepath.Join(stateDir, "schedule.log"),
+
+		Opener: opener.Config{
+			Command:   strings.TrimSpace(v.GetString("opener_command")),
+			RulesFile: strings.TrimSpace(v.GetString("opener_rules_file")),
+		},
 	}, nil
 }
 
@@ -192,3 +248,37 @@ func loadEnvFile(path string) error {
 	}
 	return nil
 }
+
+// ValidateEnvFile reports lines that the env-file loader would silently skip.
+func ValidateEnvFile(path string) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("open env file %s: %w", path, err)
+	}
+	defer func() {
+		_ = file.Close()
+	}()
+
+	var problems []string
+	scanner := bufio.NewScanner(file)
+	for lineNumber := 1; scanner.Scan(); lineNumber++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		line = strings.TrimSpace(strings.TrimPrefix(line, "export "))
+
+		key, _, ok := strings.Cut(line, "=")
+		if !ok || strings.TrimSpace(key) == "" {
+			problems = append(problems, fmt.Sprintf("line %d", lineNumber))
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return fmt.Errorf("scan env file %s: %w", path, err)
+	}
+
+	if len(problems) > 0 {
+		return fmt.Errorf("%s: malformed KEY=VALUE on %s", path, strings.Join(problems, ", "))
+	}
+	return nil
+}
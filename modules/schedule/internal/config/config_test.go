@@ -3,6 +3,7 @@ package config
 import (
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
 	"time"
 )
@@ -49,3 +50,19 @@ func TestLoad_ConfigFileOverrides(t *testing.T) {
 		t.Fatalf("selection path mismatch: %s", cfg.SelectionPath)
 	}
 }
+
+func TestValidateEnvFileReportsMalformedLines(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "schedule.env")
+	content := "# comment\nexport MAX_ITEMS=4\nLOOKAHEAD_MINUTES\n=oops\n"
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatalf("write config file: %v", err)
+	}
+
+	err := ValidateEnvFile(path)
+	if err == nil {
+		t.Fatal("expected malformed lines to be reported")
+	}
+	if got := err.Error(); !strings.Contains(got, "line 3, line 4") {
+		t.Fatalf("unexpected error %q", got)
+	}
+}
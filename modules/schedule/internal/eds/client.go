@@ -57,6 +57,11 @@ func (c *Client) Close() error {
 	return c.conn.Close()
 }
 
+// ServiceNames reports the resolved Evolution Data Server bus names.
+func (c *Client) ServiceNames() (source, calendar string) {
+	return c.sourceService, c.calendarService
+}
+
 func findServiceName(conn *dbus.Conn, prefix string) (string, error) {
 	dbusObj := conn.Object("org.freedesktop.DBus", "/org/freedesktop/DBus")
 
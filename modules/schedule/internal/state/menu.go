@@ -2,19 +2,24 @@ package state
 
 import (
 	"fmt"
-	"html"
 	"os"
 	"path/filepath"
 	"strings"
 	"time"
 
 	"github.com/rbright/waybar-schedule/internal/schedule"
+	"github.com/rbright/waybar-shared/itemref"
+	"github.com/rbright/waybar-shared/menu"
 )
 
 type MenuData struct {
 	StatusLine string
 	Next       *schedule.Occurrence
 	Items      []schedule.Occurrence
+	// Slots are the menu slots of Items, which row ids use.
+	Slots []int
+	// CalendarSubmenus nests each calendar's meetings in a submenu.
+	CalendarSubmenus bool
 }
 
 func WriteMenu(path string, data MenuData) error {
@@ -22,51 +27,81 @@ func WriteMenu(path string, data MenuData) error {
 		return fmt.Errorf("create menu dir: %w", err)
 	}
 
-	var b strings.Builder
-	b.WriteString("<?xml version=\"1.0\" encoding=\"UTF-8\"?>\n")
-	b.WriteString("<interface>\n")
-	b.WriteString("  <object class=\"GtkMenu\" id=\"menu\">\n")
-
+	m := menu.New()
 	if data.Next != nil {
 		prefix := "Open next"
 		if strings.TrimSpace(data.Next.JoinURL) != "" {
 			prefix = "Join call"
 		}
-		writeMenuItem(&b, "join_next", fmt.Sprintf("%s: %s", prefix, fallback(data.Next.Title, "Meeting")))
-		writeSeparator(&b, "separator_next")
+		m.Item("join_next", fmt.Sprintf("%s: %s", prefix, fallback(data.Next.Title, "Meeting")))
 	}
 
-	if len(data.Items) > 0 {
-		for idx, item := range data.Items {
-			label := fmt.Sprintf("%s — %s", formatStart(item.Start), fallback(item.Title, "Meeting"))
-			writeMenuItem(&b, fmt.Sprintf("join_%d", idx+1), label)
+	switch {
+	case len(data.Items) > 0 && data.CalendarSubmenus:
+		m.Separator()
+		for n, group := range groupByCalendar(data.Items) {
+			label := fmt.Sprintf("%s (%d)", group.name, len(group.indexes))
+			m.Submenu(fmt.Sprintf("calendar_%d", n+1), label, func(sub *menu.Builder) {
+				writeMeetings(sub, data, group.indexes)
+			})
+		}
+	case len(data.Items) > 0:
+		indexes := make([]int, len(data.Items))
+		for idx := range indexes {
+			indexes[idx] = idx
 		}
-	} else {
-		writeMenuItem(&b, "noop", fallback(data.StatusLine, "No upcoming meetings"))
+		writeMeetings(m, data, indexes)
+	default:
+		m.Separator()
+		m.Info(fallback(data.StatusLine, "No upcoming meetings"))
 	}
 
-	writeSeparator(&b, "separator_actions")
-	writeMenuItem(&b, "select_calendars", "Select Calendars…")
-	writeMenuItem(&b, "refresh", "Refresh")
+	m.Separator()
+	m.Item("select_calendars", "Select Calendars…")
+	m.Item("refresh", "Refresh")
 
-	b.WriteString("  </object>\n")
-	b.WriteString("</interface>\n")
+	return writeFileAtomically(path, m.Bytes())
+}
 
-	return writeFileAtomically(path, []byte(b.String()))
+// writeMeetings lists the meetings at indexes under one section per day.
+func writeMeetings(m *menu.Builder, data MenuData, indexes []int) {
+	now := time.Now()
+	day := ""
+	for _, idx := range indexes {
+		item := data.Items[idx]
+		if heading := dayHeading(item.Start, now); heading != day {
+			day = heading
+			m.Section(heading)
+		}
+		label := fmt.Sprintf("%s — %s", formatStart(item), fallback(item.Title, "Meeting"))
+		icon := ""
+		if strings.TrimSpace(item.JoinURL) != "" {
+			icon = "camera-web"
+		}
+		m.IconItem(fmt.Sprintf("join_%d", itemref.Slot(data.Slots, idx)), label, icon)
+	}
 }
 
-func writeMenuItem(b *strings.Builder, id, label string) {
-	b.WriteString("    <child>\n")
-	_, _ = fmt.Fprintf(b, "      <object class=\"GtkMenuItem\" id=\"%s\">\n", html.EscapeString(id))
-	_, _ = fmt.Fprintf(b, "        <property name=\"label\">%s</property>\n", html.EscapeString(label))
-	b.WriteString("      </object>\n")
-	b.WriteString("    </child>\n")
+type calendarGroup struct {
+	name    string
+	indexes []int
 }
 
-func writeSeparator(b *strings.Builder, id string) {
-	b.WriteString("    <child>\n")
-	_, _ = fmt.Fprintf(b, "      <object class=\"GtkSeparatorMenuItem\" id=\"%s\" />\n", html.EscapeString(id))
-	b.WriteString("    </child>\n")
+// groupByCalendar groups meetings by calendar, in order of each calendar's
+// first meeting.
+func groupByCalendar(items []schedule.Occurrence) []calendarGroup {
+	var groups []calendarGroup
+	positions := make(map[string]int)
+	for idx, item := range items {
+		position, ok := positions[item.CalendarUID]
+		if !ok {
+			position = len(groups)
+			positions[item.CalendarUID] = position
+			groups = append(groups, calendarGroup{name: fallback(item.CalendarName, "Calendar")})
+		}
+		groups[position].indexes = append(groups[position].indexes, idx)
+	}
+	return groups
 }
 
 func fallback(value, defaultValue string) string {
@@ -76,10 +111,25 @@ func fallback(value, defaultValue string) string {
 	return value
 }
 
-func formatStart(value time.Time) string {
-	now := time.Now()
-	if now.Year() == value.Year() && now.YearDay() == value.YearDay() {
-		return value.Format("15:04")
+func formatStart(item schedule.Occurrence) string {
+	if item.AllDay {
+		return "All day"
 	}
-	return value.Format("Mon 15:04")
+	return item.Start.Format("15:04")
+}
+
+func dayHeading(value, now time.Time) string {
+	switch {
+	case sameDay(value, now):
+		return "Today"
+	case sameDay(value, now.AddDate(0, 0, 1)):
+		return "Tomorrow"
+	default:
+		return value.Format("Mon 2 Jan")
+	}
+}
+
+func sameDay(a, b time.Time) bool {
+	a, b = a.Local(), b.Local()
+	return a.Year() == b.Year() && a.YearDay() == b.YearDay()
 }
@@ -37,3 +37,49 @@ func TestWriteMenu_ContainsExpectedActions(t *testing.T) {
 		}
 	}
 }
+
+func TestWriteMenu_CalendarSubmenus(t *testing.T) {
+	t.Parallel()
+
+	menuPath := filepath.Join(t.TempDir(), "schedule.xml")
+	start := time.Now().Add(time.Hour)
+	items := []schedule.Occurrence{
+		{CalendarUID: "work", CalendarName: "Work", Title: "Standup", Start: start},
+		{CalendarUID: "home", CalendarName: "Home", Title: "Dentist", Start: start.Add(time.Hour)},
+		{CalendarUID: "work", CalendarName: "Work", Title: "Review", Start: start.Add(2 * time.Hour)},
+	}
+
+	data := MenuData{Items: items, Slots: []int{1, 4, 2}, CalendarSubmenus: true}
+	if err := WriteMenu(menuPath, data); err != nil {
+		t.Fatalf("write menu: %v", err)
+	}
+
+	raw, err := os.ReadFile(menuPath)
+	if err != nil {
+		t.Fatalf("read menu: %v", err)
+	}
+	text := string(raw)
+
+	for _, expected := range []string{
+		`<object class="GtkMenuItem" id="calendar_1">`,
+		`<property name="label">Work (2)</property>`,
+		`<object class="GtkMenu" id="calendar_1_menu">`,
+		`<property name="label">Home (1)</property>`,
+		`<object class="GtkMenu" id="calendar_2_menu">`,
+	} {
+		if !strings.Contains(text, expected) {
+			t.Fatalf("expected %q in menu:\n%s", expected, text)
+		}
+	}
+
+	// Meetings keep the join ids of their slots inside the calendar submenus.
+	work := text[strings.Index(text, `id="calendar_1_menu"`):strings.Index(text, `id="calendar_2"`)]
+	for _, id := range []string{`id="join_1"`, `id="join_2"`} {
+		if !strings.Contains(work, id) {
+			t.Fatalf("expected %s in the Work submenu:\n%s", id, work)
+		}
+	}
+	if home := text[strings.Index(text, `id="calendar_2_menu"`):]; !strings.Contains(home, `id="join_4"`) {
+		t.Fatalf("expected join_4 in the Home submenu:\n%s", home)
+	}
+}
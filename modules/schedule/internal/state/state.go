@@ -6,10 +6,12 @@ import (
 	"os"
 	"path/filepath"
 	"sort"
+	"strconv"
 	"strings"
 	"time"
 
 	"github.com/rbright/waybar-schedule/internal/schedule"
+	"github.com/rbright/waybar-shared/itemref"
 )
 
 type Selection struct {
@@ -34,33 +36,23 @@ func EnsureDirs(stateDir, menuDir, selectionPath string) error {
 	return nil
 }
 
-func SaveMeetings(path string, meetings []schedule.Occurrence) error {
-	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
-		return fmt.Errorf("create meetings dir: %w", err)
-	}
-
-	payload, err := json.MarshalIndent(meetings, "", "  ")
-	if err != nil {
-		return fmt.Errorf("marshal meetings: %w", err)
-	}
+// SaveMeetings writes meetings and returns the menu slot of each one.
+func SaveMeetings(path string, meetings []schedule.Occurrence) ([]int, error) {
+	return itemref.Save(path, meetings, meetingKey)
+}
 
-	return writeFileAtomically(path, append(payload, '\n'))
+// LoadMeetings returns the saved meetings and the menu slot of each one.
+func LoadMeetings(path string) ([]schedule.Occurrence, []int, error) {
+	return itemref.Load(path, meetingKey)
 }
 
-func LoadMeetings(path string) ([]schedule.Occurrence, error) {
-	raw, err := os.ReadFile(path)
-	if err != nil {
-		if os.IsNotExist(err) {
-			return nil, nil
-		}
-		return nil, fmt.Errorf("read meetings file: %w", err)
-	}
+func ResolveMeeting(path string, ref itemref.Ref) (schedule.Occurrence, bool, error) {
+	return itemref.Resolve(path, ref, meetingKey)
+}
 
-	var meetings []schedule.Occurrence
-	if err := json.Unmarshal(raw, &meetings); err != nil {
-		return nil, fmt.Errorf("decode meetings file: %w", err)
-	}
-	return meetings, nil
+// meetingKey identifies one occurrence of a (possibly recurring) event.
+func meetingKey(meeting schedule.Occurrence) string {
+	return meeting.CalendarUID + "/" + meeting.UID + "/" + strconv.FormatInt(meeting.Start.Unix(), 10)
 }
 
 func SaveCalendars(path string, calendars []schedule.Calendar) error {
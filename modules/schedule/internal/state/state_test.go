@@ -0,0 +1,43 @@
+package state
+
+import (
+	"errors"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/rbright/waybar-schedule/internal/schedule"
+	"github.com/rbright/waybar-shared/itemref"
+)
+
+func TestResolveMeetingByOccurrence(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "meetings.json")
+	start := time.Date(2026, 3, 2, 9, 0, 0, 0, time.UTC)
+
+	standup := schedule.Occurrence{CalendarUID: "work", UID: "standup", Title: "Standup", Start: start}
+	tomorrow := standup
+	tomorrow.Start = start.Add(24 * time.Hour)
+	review := schedule.Occurrence{CalendarUID: "work", UID: "review", Title: "Review", Start: start.Add(2 * time.Hour)}
+
+	if _, err := SaveMeetings(path, []schedule.Occurrence{standup, review}); err != nil {
+		t.Fatalf("save first: %v", err)
+	}
+	slots, err := SaveMeetings(path, []schedule.Occurrence{review, tomorrow})
+	if err != nil {
+		t.Fatalf("save second: %v", err)
+	}
+	if !slices.Equal(slots, []int{2, 3}) {
+		t.Fatalf("expected review to keep slot 2 and tomorrow's standup to get slot 3, got %v", slots)
+	}
+
+	item, ok, err := ResolveMeeting(path, itemref.Ref{Slot: 2})
+	if err != nil || !ok || item.UID != "review" {
+		t.Fatalf("expected review, got %+v ok=%v err=%v", item, ok, err)
+	}
+
+	// The recurring standup moved to tomorrow; today's occurrence must not resolve to it.
+	if _, _, err := ResolveMeeting(path, itemref.Ref{Slot: 1}); !errors.Is(err, itemref.ErrStale) {
+		t.Fatalf("expected stale occurrence to be refused, got %v", err)
+	}
+}
@@ -0,0 +1,18 @@
+// Package service exposes the module to the waybar-modules host, which serves
+// every module from one daemon process.
+package service
+
+import (
+	"github.com/rbright/waybar-schedule/internal/app"
+	"github.com/rbright/waybar-schedule/internal/config"
+	"github.com/rbright/waybar-shared/daemon"
+)
+
+// Services returns the module's daemon service.
+func Services() ([]daemon.Service, error) {
+	cfg, err := config.Load()
+	if err != nil {
+		return nil, err
+	}
+	return []daemon.Service{app.Service(cfg)}, nil
+}
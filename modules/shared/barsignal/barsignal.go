@@ -0,0 +1,103 @@
+// Package barsignal asks running Waybar processes to re-run a module, the way
+// `pkill -RTMIN+N waybar` does.
+package barsignal
+
+import (
+	"bufio"
+	"fmt"
+	"math/bits"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"syscall"
+)
+
+// fallbackRTMin is glibc's SIGRTMIN, used when a Waybar process does not show
+// which real-time signals it handles.
+const fallbackRTMin = 34
+
+var (
+	procRoot    = "/proc"
+	killProcess = syscall.Kill
+)
+
+// Send asks every running Waybar to re-run the module configured with
+// `"signal": n`.
+func Send(n int) error {
+	if n <= 0 {
+		return nil
+	}
+
+	pids, err := findWaybarPIDs(procRoot)
+	if err != nil {
+		return err
+	}
+
+	var firstErr error
+	for _, pid := range pids {
+		sig := syscall.Signal(rtMin(procRoot, pid) + n)
+		if err := killProcess(pid, sig); err != nil && firstErr == nil {
+			firstErr = fmt.Errorf("signal waybar pid %d: %w", pid, err)
+		}
+	}
+	return firstErr
+}
+
+func findWaybarPIDs(root string) ([]int, error) {
+	entries, err := os.ReadDir(root)
+	if err != nil {
+		return nil, fmt.Errorf("read %s: %w", root, err)
+	}
+
+	pids := make([]int, 0, 1)
+	for _, entry := range entries {
+		pid, err := strconv.Atoi(entry.Name())
+		if err != nil || pid <= 0 {
+			continue
+		}
+
+		comm, err := os.ReadFile(filepath.Join(root, entry.Name(), "comm"))
+		if err != nil {
+			continue
+		}
+
+		switch strings.TrimSpace(string(comm)) {
+		case "waybar", ".waybar-wrapped":
+			pids = append(pids, pid)
+		}
+	}
+	return pids, nil
+}
+
+// rtMin returns SIGRTMIN as the Waybar process sees it, which depends on its
+// libc (34 on glibc, 35 on musl). Waybar handles SIGRTMIN+1 through SIGRTMAX,
+// so the lowest real-time signal in its caught mask is SIGRTMIN+1.
+func rtMin(root string, pid int) int {
+	file, err := os.Open(filepath.Join(root, strconv.Itoa(pid), "status"))
+	if err != nil {
+		return fallbackRTMin
+	}
+	defer func() {
+		_ = file.Close()
+	}()
+
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		value, ok := strings.CutPrefix(scanner.Text(), "SigCgt:")
+		if !ok {
+			continue
+		}
+		caught, err := strconv.ParseUint(strings.TrimSpace(value), 16, 64)
+		if err != nil {
+			return fallbackRTMin
+		}
+		// Bit k stands for signal k+1; real-time signals start at 32.
+		realtime := caught >> 31
+		if realtime == 0 {
+			return fallbackRTMin
+		}
+		return 32 + bits.TrailingZeros64(realtime) - 1
+	}
+	return fallbackRTMin
+}
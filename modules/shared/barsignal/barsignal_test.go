@@ -0,0 +1,136 @@
+package barsignal
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"syscall"
+	"testing"
+)
+
+// Caught-signal masks of a Waybar that handles SIGINT, SIGUSR1, SIGUSR2,
+// SIGCHLD and SIGRTMIN+1 through SIGRTMAX.
+const (
+	glibcSigCgt = "fffffffc00010a02"
+	muslSigCgt  = "fffffff800010a02"
+)
+
+func writeProc(t *testing.T, root, pid, comm string) {
+	t.Helper()
+
+	dir := filepath.Join(root, pid)
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		t.Fatalf("mkdir proc entry: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "comm"), []byte(comm+"\n"), 0o644); err != nil {
+		t.Fatalf("write comm: %v", err)
+	}
+}
+
+func writeStatus(t *testing.T, root, pid, sigCgt string) {
+	t.Helper()
+
+	status := "Name:\twaybar\nSigBlk:\t0000000000000000\nSigIgn:\t0000000000001000\nSigCgt:\t" + sigCgt + "\n"
+	if err := os.WriteFile(filepath.Join(root, pid, "status"), []byte(status), 0o644); err != nil {
+		t.Fatalf("write status: %v", err)
+	}
+}
+
+func stubKill(t *testing.T, root string, kill func(int, syscall.Signal) error) {
+	t.Helper()
+
+	originalRoot, originalKill := procRoot, killProcess
+	t.Cleanup(func() {
+		procRoot, killProcess = originalRoot, originalKill
+	})
+	procRoot, killProcess = root, kill
+}
+
+func TestFindWaybarPIDs(t *testing.T) {
+	root := t.TempDir()
+	writeProc(t, root, "100", "waybar")
+	writeProc(t, root, "200", ".waybar-wrapped")
+	writeProc(t, root, "300", "bash")
+	writeProc(t, root, "self", "waybar")
+
+	pids, err := findWaybarPIDs(root)
+	if err != nil {
+		t.Fatalf("find pids: %v", err)
+	}
+	slices.Sort(pids)
+	if !slices.Equal(pids, []int{100, 200}) {
+		t.Fatalf("unexpected pids: %v", pids)
+	}
+}
+
+func TestRTMin(t *testing.T) {
+	tests := []struct {
+		name   string
+		sigCgt string
+		want   int
+	}{
+		{name: "glibc", sigCgt: glibcSigCgt, want: 34},
+		{name: "musl", sigCgt: muslSigCgt, want: 35},
+		{name: "no realtime handlers", sigCgt: "0000000000010a02", want: fallbackRTMin},
+		{name: "malformed", sigCgt: "zz", want: fallbackRTMin},
+		{name: "no status", want: fallbackRTMin},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			root := t.TempDir()
+			writeProc(t, root, "42", "waybar")
+			if tt.sigCgt != "" {
+				writeStatus(t, root, "42", tt.sigCgt)
+			}
+
+			if got := rtMin(root, 42); got != tt.want {
+				t.Fatalf("expected SIGRTMIN %d, got %d", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestSendUsesEachWaybarsRealtimeBase(t *testing.T) {
+	root := t.TempDir()
+	writeProc(t, root, "42", "waybar")
+	writeStatus(t, root, "42", glibcSigCgt)
+	writeProc(t, root, "43", ".waybar-wrapped")
+	writeStatus(t, root, "43", muslSigCgt)
+
+	got := map[int]syscall.Signal{}
+	stubKill(t, root, func(pid int, sig syscall.Signal) error {
+		got[pid] = sig
+		return nil
+	})
+
+	if err := Send(8); err != nil {
+		t.Fatalf("send: %v", err)
+	}
+	if got[42] != syscall.Signal(42) || got[43] != syscall.Signal(43) || len(got) != 2 {
+		t.Fatalf("expected SIGRTMIN+8 per process (42 and 43), got %v", got)
+	}
+}
+
+func TestSendReportsKillErrors(t *testing.T) {
+	root := t.TempDir()
+	writeProc(t, root, "42", "waybar")
+
+	stubKill(t, root, func(int, syscall.Signal) error {
+		return syscall.EPERM
+	})
+
+	if err := Send(1); err == nil {
+		t.Fatal("expected kill error")
+	}
+}
+
+func TestSendDisabled(t *testing.T) {
+	stubKill(t, t.TempDir(), func(int, syscall.Signal) error {
+		t.Fatal("kill should not be called when signal is disabled")
+		return nil
+	})
+
+	if err := Send(0); err != nil {
+		t.Fatalf("send: %v", err)
+	}
+}
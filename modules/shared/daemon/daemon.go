@@ -0,0 +1,494 @@
+// Package daemon serves module commands over Unix sockets so Waybar can stream
+// output from one long-lived process instead of re-running a module binary on
+// every interval.
+package daemon
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+type Handler func(ctx context.Context, args []string, stdout io.Writer) error
+
+// Service is one module command set served on its own socket. A process can
+// serve several at once; they share one refresh scheduler.
+type Service struct {
+	Name       string
+	SocketPath string
+	Interval   time.Duration
+	Timeout    time.Duration
+	// CommandTimeout, when set, gives a command its own limit in place of
+	// Timeout. Commands allowed longer than Timeout are user actions, such as a
+	// checkout or a notification waiting for a click; they run alongside
+	// refreshes instead of holding them up.
+	CommandTimeout func(args []string) time.Duration
+	Handler        Handler
+}
+
+type request struct {
+	Args      []string `json:"args"`
+	Subscribe bool     `json:"subscribe,omitempty"`
+}
+
+type reply struct {
+	Output string `json:"output,omitempty"`
+	Error  string `json:"error,omitempty"`
+}
+
+type topic struct {
+	args        []string
+	last        []byte
+	subscribers map[chan []byte]struct{}
+}
+
+type server struct {
+	opts Service
+
+	runMu      sync.Mutex
+	refreshing atomic.Bool
+
+	mu     sync.Mutex
+	topics map[string]*topic
+}
+
+func SocketPath(name string) string {
+	runtimeDir := strings.TrimSpace(os.Getenv("XDG_RUNTIME_DIR"))
+	if runtimeDir == "" {
+		runtimeDir = os.TempDir()
+	}
+	return filepath.Join(runtimeDir, "waybar-modules", name+".sock")
+}
+
+// Serve listens on every service's socket until ctx is done. Each service
+// runs one command at a time, but a slow service never holds up another.
+func Serve(ctx context.Context, services ...Service) error {
+	if len(services) == 0 {
+		return fmt.Errorf("daemon needs at least one service")
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	servers := make([]*server, 0, len(services))
+	listeners := make([]net.Listener, 0, len(services))
+	defer func() {
+		for i, listener := range listeners {
+			_ = listener.Close()
+			_ = os.Remove(servers[i].opts.SocketPath)
+		}
+	}()
+
+	for _, opts := range services {
+		if opts.Handler == nil {
+			return fmt.Errorf("daemon handler is required for %s", serviceName(opts))
+		}
+		if opts.Interval <= 0 {
+			opts.Interval = time.Minute
+		}
+		if opts.Timeout <= 0 {
+			opts.Timeout = 30 * time.Second
+		}
+
+		listener, err := listen(opts.SocketPath)
+		if err != nil {
+			return err
+		}
+		servers = append(servers, &server{opts: opts, topics: make(map[string]*topic)})
+		listeners = append(listeners, listener)
+	}
+
+	go func() {
+		<-ctx.Done()
+		for _, listener := range listeners {
+			_ = listener.Close()
+		}
+	}()
+
+	go schedule(ctx, servers)
+
+	errs := make(chan error, len(servers))
+	for i, s := range servers {
+		go func() {
+			errs <- s.accept(ctx, listeners[i])
+		}()
+	}
+
+	var err error
+	for range servers {
+		if acceptErr := <-errs; acceptErr != nil && err == nil {
+			err = acceptErr
+			cancel()
+		}
+	}
+	return err
+}
+
+func serviceName(opts Service) string {
+	if opts.Name != "" {
+		return opts.Name
+	}
+	return opts.SocketPath
+}
+
+func (s *server) accept(ctx context.Context, listener net.Listener) error {
+	for {
+		conn, err := listener.Accept()
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil
+			}
+			return fmt.Errorf("accept daemon connection: %w", err)
+		}
+		go s.handle(ctx, conn)
+	}
+}
+
+func listen(path string) (net.Listener, error) {
+	if strings.TrimSpace(path) == "" {
+		return nil, fmt.Errorf("daemon socket path is required")
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return nil, fmt.Errorf("create socket dir: %w", err)
+	}
+
+	if _, err := os.Stat(path); err == nil {
+		if conn, dialErr := net.Dial("unix", path); dialErr == nil {
+			_ = conn.Close()
+			return nil, fmt.Errorf("daemon already running at %s", path)
+		}
+		if err := os.Remove(path); err != nil {
+			return nil, fmt.Errorf("remove stale socket: %w", err)
+		}
+	}
+
+	listener, err := net.Listen("unix", path)
+	if err != nil {
+		return nil, fmt.Errorf("listen on %s: %w", path, err)
+	}
+	if err := os.Chmod(path, 0o600); err != nil {
+		_ = listener.Close()
+		return nil, fmt.Errorf("chmod socket: %w", err)
+	}
+	return listener, nil
+}
+
+// schedule refreshes each service on its own interval from a single timer. A
+// service still busy with its previous refresh skips the tick rather than
+// queueing another fetch behind it.
+func schedule(ctx context.Context, servers []*server) {
+	due := make([]time.Time, len(servers))
+	start := time.Now()
+	for i, s := range servers {
+		due[i] = start.Add(s.opts.Interval)
+	}
+
+	timer := time.NewTimer(time.Until(earliest(due)))
+	defer timer.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-timer.C:
+			for i, s := range servers {
+				if due[i].After(now) {
+					continue
+				}
+				due[i] = now.Add(s.opts.Interval)
+				if s.refreshing.CompareAndSwap(false, true) {
+					go func() {
+						defer s.refreshing.Store(false)
+						s.refreshAll(ctx)
+					}()
+				}
+			}
+			timer.Reset(time.Until(earliest(due)))
+		}
+	}
+}
+
+func earliest(times []time.Time) time.Time {
+	first := times[0]
+	for _, t := range times[1:] {
+		if t.Before(first) {
+			first = t
+		}
+	}
+	return first
+}
+
+func (s *server) handle(ctx context.Context, conn net.Conn) {
+	defer func() {
+		_ = conn.Close()
+	}()
+
+	line, err := bufio.NewReader(conn).ReadBytes('\n')
+	if err != nil && !errors.Is(err, io.EOF) {
+		return
+	}
+
+	var req request
+	if err := json.Unmarshal(line, &req); err != nil {
+		writeReply(conn, reply{Error: fmt.Sprintf("decode request: %s", err.Error())})
+		return
+	}
+
+	if req.Subscribe {
+		s.stream(ctx, conn, req.Args)
+		return
+	}
+
+	var out bytes.Buffer
+	runErr := s.run(ctx, req.Args, &out)
+
+	response := reply{Output: out.String()}
+	if runErr != nil {
+		response.Error = runErr.Error()
+	}
+	writeReply(conn, response)
+
+	// Commands may rewrite state, so push fresh output to every subscriber.
+	s.refreshAll(ctx)
+}
+
+func (s *server) stream(ctx context.Context, conn net.Conn, args []string) {
+	key := strings.Join(args, "\x00")
+	updates := make(chan []byte, 4)
+
+	s.mu.Lock()
+	t, ok := s.topics[key]
+	if !ok {
+		t = &topic{args: append([]string(nil), args...), subscribers: make(map[chan []byte]struct{})}
+		s.topics[key] = t
+	}
+	t.subscribers[updates] = struct{}{}
+	last := t.last
+	s.mu.Unlock()
+
+	defer func() {
+		s.mu.Lock()
+		delete(t.subscribers, updates)
+		if len(t.subscribers) == 0 {
+			delete(s.topics, key)
+		}
+		s.mu.Unlock()
+	}()
+
+	if last != nil {
+		updates <- last
+	} else {
+		go s.refresh(ctx, key)
+	}
+
+	closed := make(chan struct{})
+	go func() {
+		_, _ = io.Copy(io.Discard, conn)
+		close(closed)
+	}()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-closed:
+			return
+		case payload := <-updates:
+			if _, err := conn.Write(payload); err != nil {
+				return
+			}
+		}
+	}
+}
+
+func (s *server) refreshAll(ctx context.Context) {
+	s.mu.Lock()
+	keys := make([]string, 0, len(s.topics))
+	for key := range s.topics {
+		keys = append(keys, key)
+	}
+	s.mu.Unlock()
+
+	for _, key := range keys {
+		s.refresh(ctx, key)
+	}
+}
+
+func (s *server) refresh(ctx context.Context, key string) {
+	s.mu.Lock()
+	t, ok := s.topics[key]
+	if !ok {
+		s.mu.Unlock()
+		return
+	}
+	args := t.args
+	s.mu.Unlock()
+
+	var out bytes.Buffer
+	if err := s.run(ctx, args, &out); err != nil {
+		return
+	}
+	payload := out.Bytes()
+	if len(payload) == 0 {
+		return
+	}
+	if payload[len(payload)-1] != '\n' {
+		payload = append(payload, '\n')
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	t, ok = s.topics[key]
+	if !ok {
+		return
+	}
+	t.last = payload
+	for subscriber := range t.subscribers {
+		select {
+		case subscriber <- payload:
+		default:
+			// Slow subscribers only need the latest output.
+		}
+	}
+}
+
+func (s *server) run(ctx context.Context, args []string, stdout io.Writer) error {
+	timeout := s.timeout(args)
+	if timeout <= s.opts.Timeout {
+		s.runMu.Lock()
+		defer s.runMu.Unlock()
+	}
+
+	runCtx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	start := time.Now()
+	err := s.opts.Handler(runCtx, args, stdout)
+	if err != nil {
+		slog.Warn("daemon command failed", "service", serviceName(s.opts), "args", args, "duration", time.Since(start), "error", err)
+	} else {
+		slog.Debug("daemon command finished", "service", serviceName(s.opts), "args", args, "duration", time.Since(start))
+	}
+	return err
+}
+
+// timeout is the limit for args: CommandTimeout's when it has one, else Timeout.
+func (s *server) timeout(args []string) time.Duration {
+	if s.opts.CommandTimeout != nil {
+		if timeout := s.opts.CommandTimeout(args); timeout > 0 {
+			return timeout
+		}
+	}
+	return s.opts.Timeout
+}
+
+func writeReply(w io.Writer, response reply) {
+	payload, err := json.Marshal(response)
+	if err != nil {
+		return
+	}
+	_, _ = w.Write(append(payload, '\n'))
+}
+
+func Subscribe(ctx context.Context, socketPath string, args []string, stdout io.Writer) error {
+	conn, err := dial(ctx, socketPath)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = conn.Close()
+	}()
+
+	go func() {
+		<-ctx.Done()
+		_ = conn.Close()
+	}()
+
+	if err := writeRequest(conn, request{Args: args, Subscribe: true}); err != nil {
+		return err
+	}
+
+	scanner := bufio.NewScanner(conn)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		if _, err := fmt.Fprintln(stdout, scanner.Text()); err != nil {
+			return fmt.Errorf("write output: %w", err)
+		}
+	}
+	if ctx.Err() != nil {
+		return nil
+	}
+	if err := scanner.Err(); err != nil {
+		return fmt.Errorf("read daemon stream: %w", err)
+	}
+	return fmt.Errorf("daemon closed the connection")
+}
+
+func Call(ctx context.Context, socketPath string, args []string, stdout io.Writer) error {
+	conn, err := dial(ctx, socketPath)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = conn.Close()
+	}()
+
+	if deadline, ok := ctx.Deadline(); ok {
+		_ = conn.SetDeadline(deadline)
+	}
+
+	if err := writeRequest(conn, request{Args: args}); err != nil {
+		return err
+	}
+
+	line, err := bufio.NewReader(conn).ReadBytes('\n')
+	if err != nil && !errors.Is(err, io.EOF) {
+		return fmt.Errorf("read daemon reply: %w", err)
+	}
+
+	var response reply
+	if err := json.Unmarshal(line, &response); err != nil {
+		return fmt.Errorf("decode daemon reply: %w", err)
+	}
+	if response.Output != "" {
+		if _, err := io.WriteString(stdout, response.Output); err != nil {
+			return fmt.Errorf("write output: %w", err)
+		}
+	}
+	if response.Error != "" {
+		return errors.New(response.Error)
+	}
+	return nil
+}
+
+func dial(ctx context.Context, socketPath string) (net.Conn, error) {
+	var dialer net.Dialer
+	conn, err := dialer.DialContext(ctx, "unix", socketPath)
+	if err != nil {
+		return nil, fmt.Errorf("daemon not reachable at %s: %w", socketPath, err)
+	}
+	return conn, nil
+}
+
+func writeRequest(w io.Writer, req request) error {
+	payload, err := json.Marshal(req)
+	if err != nil {
+		return fmt.Errorf("marshal daemon request: %w", err)
+	}
+	if _, err := w.Write(append(payload, '\n')); err != nil {
+		return fmt.Errorf("write daemon request: %w", err)
+	}
+	return nil
+}
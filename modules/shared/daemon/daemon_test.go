@@ -0,0 +1,313 @@
+package daemon
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+type lockedBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *lockedBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Write(p)
+}
+
+func (b *lockedBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.String()
+}
+
+func startServer(t *testing.T, handler Handler) string {
+	t.Helper()
+	return startServices(t, Service{Name: "module", Interval: time.Hour, Handler: handler})[0]
+}
+
+// startServices serves services from one daemon, giving each a socket in a
+// short temp dir (socket paths have a tight length limit), and returns the
+// socket paths in order.
+func startServices(t *testing.T, services ...Service) []string {
+	t.Helper()
+
+	dir, err := os.MkdirTemp("", "waybar-daemon-")
+	if err != nil {
+		t.Fatalf("mkdir temp: %v", err)
+	}
+	t.Cleanup(func() {
+		_ = os.RemoveAll(dir)
+	})
+
+	paths := make([]string, len(services))
+	for i := range services {
+		paths[i] = filepath.Join(dir, services[i].Name+".sock")
+		services[i].SocketPath = paths[i]
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() {
+		done <- Serve(ctx, services...)
+	}()
+	t.Cleanup(func() {
+		cancel()
+		<-done
+	})
+
+	for _, path := range paths {
+		waitFor(t, func() bool {
+			_, err := os.Stat(path)
+			return err == nil
+		})
+	}
+	return paths
+}
+
+func TestCallRunsCommandInDaemon(t *testing.T) {
+	socketPath := startServer(t, func(_ context.Context, args []string, stdout io.Writer) error {
+		_, err := fmt.Fprintf(stdout, "ran %s\n", strings.Join(args, " "))
+		return err
+	})
+
+	var out bytes.Buffer
+	if err := Call(context.Background(), socketPath, []string{"open-item", "2"}, &out); err != nil {
+		t.Fatalf("call: %v", err)
+	}
+	if out.String() != "ran open-item 2\n" {
+		t.Fatalf("unexpected output %q", out.String())
+	}
+}
+
+func TestCallReturnsHandlerError(t *testing.T) {
+	socketPath := startServer(t, func(context.Context, []string, io.Writer) error {
+		return fmt.Errorf("boom")
+	})
+
+	err := Call(context.Background(), socketPath, []string{"refresh"}, io.Discard)
+	if err == nil || err.Error() != "boom" {
+		t.Fatalf("expected handler error, got %v", err)
+	}
+}
+
+func TestSubscribeStreamsUpdatesAfterCommands(t *testing.T) {
+	var mu sync.Mutex
+	count := 0
+	socketPath := startServer(t, func(_ context.Context, args []string, stdout io.Writer) error {
+		if args[0] != "status" {
+			return nil
+		}
+		mu.Lock()
+		count++
+		n := count
+		mu.Unlock()
+		_, err := fmt.Fprintf(stdout, "{\"text\":\"%d\"}\n", n)
+		return err
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	out := &lockedBuffer{}
+	go func() {
+		_ = Subscribe(ctx, socketPath, []string{"status"}, out)
+	}()
+
+	waitFor(t, func() bool { return strings.Contains(out.String(), `{"text":"1"}`) })
+
+	if err := Call(context.Background(), socketPath, []string{"refresh"}, io.Discard); err != nil {
+		t.Fatalf("call: %v", err)
+	}
+
+	waitFor(t, func() bool { return strings.Contains(out.String(), `{"text":"2"}`) })
+}
+
+func TestServeHostsServicesOnSeparateSockets(t *testing.T) {
+	echo := func(name string) Handler {
+		return func(_ context.Context, args []string, stdout io.Writer) error {
+			_, err := fmt.Fprintf(stdout, "%s %s\n", name, strings.Join(args, " "))
+			return err
+		}
+	}
+	paths := startServices(t,
+		Service{Name: "github", Interval: time.Hour, Handler: echo("github")},
+		Service{Name: "linear", Interval: time.Hour, Handler: echo("linear")},
+	)
+
+	for i, want := range []string{"github refresh\n", "linear refresh\n"} {
+		var out bytes.Buffer
+		if err := Call(context.Background(), paths[i], []string{"refresh"}, &out); err != nil {
+			t.Fatalf("call %s: %v", paths[i], err)
+		}
+		if out.String() != want {
+			t.Fatalf("expected %q, got %q", want, out.String())
+		}
+	}
+}
+
+func TestScheduleRefreshesEachServiceOnItsOwnInterval(t *testing.T) {
+	var fast, slow atomic.Int32
+	counter := func(n *atomic.Int32) Handler {
+		return func(_ context.Context, _ []string, stdout io.Writer) error {
+			_, err := fmt.Fprintf(stdout, "{\"text\":\"%d\"}\n", n.Add(1))
+			return err
+		}
+	}
+	paths := startServices(t,
+		Service{Name: "fast", Interval: 20 * time.Millisecond, Handler: counter(&fast)},
+		Service{Name: "slow", Interval: time.Hour, Handler: counter(&slow)},
+	)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	for _, path := range paths {
+		go func() {
+			_ = Subscribe(ctx, path, []string{"status"}, io.Discard)
+		}()
+	}
+
+	waitFor(t, func() bool { return fast.Load() >= 4 && slow.Load() >= 1 })
+	if got := slow.Load(); got != 1 {
+		t.Fatalf("expected the hourly service to render once, got %d", got)
+	}
+}
+
+func TestScheduleSkipsTicksWhileAServiceIsBusy(t *testing.T) {
+	release := make(chan struct{})
+	var calls atomic.Int32
+	var quick atomic.Int32
+	paths := startServices(t,
+		Service{Name: "stuck", Interval: 10 * time.Millisecond, Handler: func(ctx context.Context, _ []string, stdout io.Writer) error {
+			if calls.Add(1) > 1 {
+				select {
+				case <-release:
+				case <-ctx.Done():
+				}
+			}
+			_, err := io.WriteString(stdout, "{}\n")
+			return err
+		}},
+		Service{Name: "quick", Interval: 10 * time.Millisecond, Handler: func(_ context.Context, _ []string, stdout io.Writer) error {
+			quick.Add(1)
+			_, err := io.WriteString(stdout, "{}\n")
+			return err
+		}},
+	)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	for _, path := range paths {
+		go func() {
+			_ = Subscribe(ctx, path, []string{"status"}, io.Discard)
+		}()
+	}
+
+	// The stuck service blocks on its first scheduled refresh; the other one
+	// keeps refreshing and the stuck one never piles up extra runs.
+	waitFor(t, func() bool { return quick.Load() >= 10 })
+	if got := calls.Load(); got != 2 {
+		t.Fatalf("expected the busy service to run twice (initial render and one refresh), got %d", got)
+	}
+	close(release)
+}
+
+func TestCommandTimeoutOutlastsTheDefault(t *testing.T) {
+	release := make(chan struct{})
+	path := startServices(t, Service{
+		Name:     "module",
+		Interval: time.Hour,
+		Timeout:  50 * time.Millisecond,
+		CommandTimeout: func(args []string) time.Duration {
+			if args[0] == "checkout" {
+				return time.Minute
+			}
+			return 0
+		},
+		Handler: func(ctx context.Context, args []string, stdout io.Writer) error {
+			if args[0] == "status" {
+				_, err := io.WriteString(stdout, "{}\n")
+				return err
+			}
+			select {
+			case <-release:
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+			_, err := io.WriteString(stdout, "checked out\n")
+			return err
+		},
+	})[0]
+
+	done := make(chan error, 1)
+	var out bytes.Buffer
+	go func() {
+		done <- Call(context.Background(), path, []string{"checkout", "1"}, &out)
+	}()
+
+	// Well past the default limit, the checkout still runs, and status keeps
+	// being served meanwhile.
+	time.Sleep(150 * time.Millisecond)
+	var status bytes.Buffer
+	if err := Call(context.Background(), path, []string{"status"}, &status); err != nil || status.String() != "{}\n" {
+		t.Fatalf("expected status while the checkout runs, got %q (%v)", status.String(), err)
+	}
+	close(release)
+	if err := <-done; err != nil || out.String() != "checked out\n" {
+		t.Fatalf("expected the checkout to finish, got %q (%v)", out.String(), err)
+	}
+
+	// Commands without their own limit keep the default.
+	slow := startServices(t, Service{
+		Name:           "slow",
+		Interval:       time.Hour,
+		Timeout:        50 * time.Millisecond,
+		CommandTimeout: func([]string) time.Duration { return 0 },
+		Handler: func(ctx context.Context, _ []string, _ io.Writer) error {
+			<-ctx.Done()
+			return ctx.Err()
+		},
+	})[0]
+	if err := Call(context.Background(), slow, []string{"status"}, io.Discard); err == nil || !strings.Contains(err.Error(), "deadline") {
+		t.Fatalf("expected the default limit to apply, got %v", err)
+	}
+}
+
+func TestServeRefusesARunningDaemon(t *testing.T) {
+	paths := startServer(t, func(context.Context, []string, io.Writer) error { return nil })
+
+	err := Serve(context.Background(), Service{Name: "again", SocketPath: paths, Handler: func(context.Context, []string, io.Writer) error { return nil }})
+	if err == nil || !strings.Contains(err.Error(), "already running") {
+		t.Fatalf("expected already running error, got %v", err)
+	}
+}
+
+func TestCallFailsWithoutDaemon(t *testing.T) {
+	err := Call(context.Background(), filepath.Join(t.TempDir(), "missing.sock"), []string{"status"}, io.Discard)
+	if err == nil {
+		t.Fatal("expected error when daemon is not running")
+	}
+}
+
+func waitFor(t *testing.T, condition func() bool) {
+	t.Helper()
+
+	deadline := time.Now().Add(2 * time.Second)
+	for time.Now().Before(deadline) {
+		if condition() {
+			return
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Fatal("condition not met before deadline")
+}
@@ -0,0 +1,187 @@
+// Package doctor collects the checks behind each module's `doctor` command and
+// prints them as a report.
+package doctor
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"syscall"
+
+	"github.com/rbright/waybar-shared/logging"
+)
+
+type Status string
+
+const (
+	StatusOK   Status = "ok"
+	StatusWarn Status = "warn"
+	StatusFail Status = "fail"
+)
+
+type Result struct {
+	Name   string
+	Status Status
+	Detail string
+}
+
+type Report struct {
+	Results []Result
+}
+
+func (r *Report) OK(name, detail string) {
+	r.Results = append(r.Results, Result{Name: name, Status: StatusOK, Detail: detail})
+}
+
+func (r *Report) Warn(name, detail string) {
+	r.Results = append(r.Results, Result{Name: name, Status: StatusWarn, Detail: detail})
+}
+
+func (r *Report) Fail(name, detail string) {
+	r.Results = append(r.Results, Result{Name: name, Status: StatusFail, Detail: detail})
+}
+
+func (r *Report) Failures() int {
+	count := 0
+	for _, result := range r.Results {
+		if result.Status == StatusFail {
+			count++
+		}
+	}
+	return count
+}
+
+func (r *Report) Write(w io.Writer) error {
+	for _, result := range r.Results {
+		if _, err := fmt.Fprintf(w, "%-6s %s: %s\n", "["+string(result.Status)+"]", result.Name, result.Detail); err != nil {
+			return fmt.Errorf("write doctor report: %w", err)
+		}
+	}
+	return nil
+}
+
+// Err returns a non-nil error when any check failed, so callers exit non-zero.
+func (r *Report) Err() error {
+	if failures := r.Failures(); failures > 0 {
+		return fmt.Errorf("doctor found %d problem(s)", failures)
+	}
+	return nil
+}
+
+// Command checks that an executable is on PATH. Missing optional commands only warn.
+func (r *Report) Command(name, purpose string, required bool) bool {
+	path, err := exec.LookPath(name)
+	if err == nil {
+		r.OK(name, path)
+		return true
+	}
+
+	detail := fmt.Sprintf("not found on PATH (%s)", purpose)
+	if required {
+		r.Fail(name, detail)
+	} else {
+		r.Warn(name, detail)
+	}
+	return false
+}
+
+// WritableDir checks that dir can be written, or created if it is missing,
+// without creating it: the check runs against the nearest existing parent.
+func (r *Report) WritableDir(name, dir string) {
+	if strings.TrimSpace(dir) == "" {
+		r.Fail(name, "path is empty")
+		return
+	}
+
+	existing, err := nearestExisting(dir)
+	if err != nil {
+		r.Fail(name, fmt.Sprintf("%s: %s", dir, err.Error()))
+		return
+	}
+
+	probe, err := os.CreateTemp(existing, ".doctor-*")
+	if err != nil {
+		r.Fail(name, fmt.Sprintf("%s is not writable: %s", existing, err.Error()))
+		return
+	}
+	probePath := probe.Name()
+	_ = probe.Close()
+	_ = os.Remove(probePath)
+
+	if existing != filepath.Clean(dir) {
+		r.OK(name, fmt.Sprintf("%s will be created under writable %s", dir, existing))
+		return
+	}
+	r.OK(name, dir+" is writable")
+}
+
+func nearestExisting(dir string) (string, error) {
+	dir = filepath.Clean(dir)
+	for {
+		info, err := os.Stat(dir)
+		if err == nil {
+			if !info.IsDir() {
+				return "", fmt.Errorf("%s is not a directory", dir)
+			}
+			return dir, nil
+		}
+		// ENOTDIR means a parent is a file, which the walk reports below.
+		if !os.IsNotExist(err) && !errors.Is(err, syscall.ENOTDIR) {
+			return "", err
+		}
+
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return "", err
+		}
+		dir = parent
+	}
+}
+
+// Secret reports whether a secret is configured without revealing its value.
+func (r *Report) Secret(name, value, missingDetail string, required bool) {
+	if strings.TrimSpace(value) != "" {
+		r.OK(name, "set")
+		return
+	}
+	if required {
+		r.Fail(name, missingDetail)
+	} else {
+		r.Warn(name, missingDetail)
+	}
+}
+
+func (r *Report) ConfigFile(name, path string, validate func(string) error) {
+	if _, err := os.Stat(path); err != nil {
+		if os.IsNotExist(err) {
+			r.Warn(name, path+" not found; using defaults")
+			return
+		}
+		r.Fail(name, err.Error())
+		return
+	}
+
+	if err := validate(path); err != nil {
+		r.Fail(name, err.Error())
+		return
+	}
+	r.OK(name, path)
+}
+
+// LogLevel checks the configured log level; unknown levels only warn because
+// logging falls back to warn.
+func (r *Report) LogLevel(level string) {
+	if _, _, err := logging.ParseLevel(level); err != nil {
+		r.Warn("log level", err.Error()+"; falling back to warn")
+		return
+	}
+	level = strings.ToLower(strings.TrimSpace(level))
+	if level == "" {
+		level = "warn"
+	}
+	r.OK("log level", level)
+}
@@ -0,0 +1,137 @@
+package doctor
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestReportErrCountsFailuresOnly(t *testing.T) {
+	var report Report
+	report.OK("first", "fine")
+	report.Warn("second", "optional")
+	if err := report.Err(); err != nil {
+		t.Fatalf("expected no error without failures, got %v", err)
+	}
+
+	report.Fail("third", "broken")
+	if err := report.Err(); err == nil || !strings.Contains(err.Error(), "1 problem") {
+		t.Fatalf("expected failure count in error, got %v", err)
+	}
+}
+
+func TestSecretNeverPrintsValue(t *testing.T) {
+	var report Report
+	report.Secret("token", "ghp_secretvalue", "missing", true)
+
+	var out bytes.Buffer
+	if err := report.Write(&out); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+	if strings.Contains(out.String(), "ghp_secretvalue") {
+		t.Fatalf("secret leaked into report: %s", out.String())
+	}
+	if report.Results[0].Status != StatusOK {
+		t.Fatalf("expected ok status, got %s", report.Results[0].Status)
+	}
+}
+
+func TestConfigFileStatuses(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "module.env")
+
+	var report Report
+	report.ConfigFile("missing", path, nil)
+	if err := os.WriteFile(path, []byte("KEY=value\n"), 0o600); err != nil {
+		t.Fatalf("write config: %v", err)
+	}
+	report.ConfigFile("invalid", path, func(string) error { return errors.New("bad line") })
+	report.ConfigFile("valid", path, func(string) error { return nil })
+
+	want := []Status{StatusWarn, StatusFail, StatusOK}
+	for i, status := range want {
+		if report.Results[i].Status != status {
+			t.Fatalf("result %d: expected %s, got %s", i, status, report.Results[i].Status)
+		}
+	}
+}
+
+func TestWritableDir(t *testing.T) {
+	root := t.TempDir()
+	file := filepath.Join(root, "file")
+	if err := os.WriteFile(file, nil, 0o600); err != nil {
+		t.Fatalf("write file: %v", err)
+	}
+
+	tests := []struct {
+		name   string
+		dir    string
+		status Status
+		detail string
+	}{
+		{name: "existing", dir: root, status: StatusOK, detail: "is writable"},
+		{name: "missing", dir: filepath.Join(root, "nested", "state"), status: StatusOK, detail: "will be created under writable " + root},
+		{name: "under a file", dir: filepath.Join(file, "state"), status: StatusFail, detail: "is not a directory"},
+		{name: "empty", dir: " ", status: StatusFail, detail: "path is empty"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var report Report
+			report.WritableDir("state dir", tt.dir)
+			result := report.Results[0]
+			if result.Status != tt.status || !strings.Contains(result.Detail, tt.detail) {
+				t.Fatalf("expected %s containing %q, got %+v", tt.status, tt.detail, result)
+			}
+		})
+	}
+
+	if _, err := os.Stat(filepath.Join(root, "nested")); !os.IsNotExist(err) {
+		t.Fatalf("doctor must not create missing dirs, stat: %v", err)
+	}
+	entries, err := os.ReadDir(root)
+	if err != nil {
+		t.Fatalf("read dir: %v", err)
+	}
+	if len(entries) != 1 {
+		t.Fatalf("expected probe files to be removed, found %d entries", len(entries))
+	}
+}
+
+func TestWritableDirFailsOnReadOnlyParent(t *testing.T) {
+	if os.Geteuid() == 0 {
+		t.Skip("root can write to read-only directories")
+	}
+	parent := filepath.Join(t.TempDir(), "readonly")
+	if err := os.Mkdir(parent, 0o500); err != nil {
+		t.Fatalf("mkdir: %v", err)
+	}
+
+	var report Report
+	report.WritableDir("state dir", filepath.Join(parent, "state"))
+	if report.Results[0].Status != StatusFail {
+		t.Fatalf("expected failure under a read-only parent, got %+v", report.Results[0])
+	}
+}
+
+func TestLogLevel(t *testing.T) {
+	tests := []struct {
+		level  string
+		status Status
+		detail string
+	}{
+		{level: "", status: StatusOK, detail: "warn"},
+		{level: " DEBUG ", status: StatusOK, detail: "debug"},
+		{level: "verbose", status: StatusWarn, detail: `invalid log level "verbose"; falling back to warn`},
+	}
+	for _, tt := range tests {
+		var report Report
+		report.LogLevel(tt.level)
+		result := report.Results[0]
+		if result.Status != tt.status || result.Detail != tt.detail {
+			t.Fatalf("level %q: expected %s %q, got %+v", tt.level, tt.status, tt.detail, result)
+		}
+	}
+}
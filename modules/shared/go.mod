@@ -0,0 +1,5 @@
+module github.com/rbright/waybar-shared
+
+go 1.25.5
+
+require github.com/godbus/dbus/v5 v5.1.0
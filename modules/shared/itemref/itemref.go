@@ -0,0 +1,258 @@
+// Package itemref stores the items behind a dropdown menu and resolves the
+// references that menu actions pass back.
+//
+// Waybar's menu-actions are static, so a row id such as open_3 always runs the
+// same command. Each item is therefore bound to a slot that keeps its number
+// for as long as the item is listed; row ids and actions use the slot rather
+// than the row's position. When an item goes away its slot is retired for one
+// refresh, so a click from a menu rendered before that refresh is refused
+// instead of opening whatever took the item's place.
+package itemref
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// ErrStale means the action refers to an item that is no longer listed.
+var ErrStale = errors.New("menu item is out of date; refresh and try again")
+
+// Ref identifies a menu item by slot or by stable key.
+type Ref struct {
+	Slot int
+	Key  string
+}
+
+// Parse accepts "N" for slot N or "id:KEY".
+func Parse(raw string) (Ref, error) {
+	raw = strings.TrimSpace(raw)
+	if key, ok := strings.CutPrefix(raw, "id:"); ok {
+		if strings.TrimSpace(key) == "" {
+			return Ref{}, fmt.Errorf("invalid item reference %q", raw)
+		}
+		return Ref{Key: strings.TrimSpace(key)}, nil
+	}
+
+	slot, err := strconv.Atoi(raw)
+	if err != nil || slot < 1 {
+		return Ref{}, fmt.Errorf("invalid item index %q", raw)
+	}
+	return Ref{Slot: slot}, nil
+}
+
+// slot binds a slot number, its position plus one, to an item key. A retired
+// slot still names the key it held so a late click can be refused.
+type slot struct {
+	Key     string `json:"key,omitempty"`
+	Retired bool   `json:"retired,omitempty"`
+}
+
+type itemsFile[T any] struct {
+	Items []T    `json:"items"`
+	Slots []slot `json:"slots,omitempty"`
+}
+
+// Save writes items and returns the slot of each one, aligned with items.
+// Items sharing a key share a slot.
+func Save[T any](path string, items []T, key func(T) string) ([]int, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return nil, fmt.Errorf("create items dir: %w", err)
+	}
+
+	if items == nil {
+		items = []T{}
+	}
+	var previous []slot
+	if current, err := load[T](path); err == nil {
+		previous = current.Slots
+	}
+
+	keys := make([]string, 0, len(items))
+	for _, item := range items {
+		keys = append(keys, key(item))
+	}
+	file := itemsFile[T]{Items: items, Slots: assign(previous, keys)}
+
+	payload, err := json.MarshalIndent(file, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("marshal items: %w", err)
+	}
+	if err := writeFileAtomically(path, append(payload, '\n')); err != nil {
+		return nil, err
+	}
+	return slotsOf(file.Slots, keys), nil
+}
+
+// Load returns the saved items and the slot of each one.
+func Load[T any](path string, key func(T) string) ([]T, []int, error) {
+	file, err := load[T](path)
+	if err != nil {
+		return nil, nil, err
+	}
+	keys := make([]string, 0, len(file.Items))
+	for _, item := range file.Items {
+		keys = append(keys, key(item))
+	}
+	return file.Items, slotsOf(file.Slots, keys), nil
+}
+
+// Resolve maps ref onto the saved items. A retired slot or a key that is no
+// longer listed returns ErrStale; a slot that was never handed out is not found.
+func Resolve[T any](path string, ref Ref, key func(T) string) (T, bool, error) {
+	var zero T
+
+	file, err := load[T](path)
+	if err != nil {
+		return zero, false, err
+	}
+
+	want := ref.Key
+	if want == "" {
+		// Files written before slots existed are resolved by position.
+		if file.Slots == nil {
+			if ref.Slot > len(file.Items) {
+				return zero, false, nil
+			}
+			return file.Items[ref.Slot-1], true, nil
+		}
+		if ref.Slot > len(file.Slots) || file.Slots[ref.Slot-1].Key == "" {
+			return zero, false, nil
+		}
+		if file.Slots[ref.Slot-1].Retired {
+			return zero, false, ErrStale
+		}
+		want = file.Slots[ref.Slot-1].Key
+	}
+
+	for _, item := range file.Items {
+		if key(item) == want {
+			return item, true, nil
+		}
+	}
+	return zero, false, ErrStale
+}
+
+// Slot returns the slot of the idx-th item from the slots Save or Load
+// returned, or its position when there is none.
+func Slot(slots []int, idx int) int {
+	if idx < len(slots) {
+		return slots[idx]
+	}
+	return idx + 1
+}
+
+// assign keeps every listed key in its previous slot, retires the slots of keys
+// that went away, frees slots retired last time, and gives new keys the lowest
+// free slot.
+func assign(previous []slot, keys []string) []slot {
+	listed := make(map[string]bool, len(keys))
+	for _, key := range keys {
+		listed[key] = true
+	}
+
+	next := make([]slot, len(previous))
+	bound := make(map[string]bool, len(keys))
+	for idx, s := range previous {
+		switch {
+		case s.Key == "":
+		case listed[s.Key] && !bound[s.Key]:
+			next[idx] = slot{Key: s.Key}
+			bound[s.Key] = true
+		case !s.Retired && !listed[s.Key]:
+			next[idx] = slot{Key: s.Key, Retired: true}
+		}
+	}
+
+	free := 0
+	for _, key := range keys {
+		if bound[key] {
+			continue
+		}
+		for free < len(next) && next[free].Key != "" {
+			free++
+		}
+		if free == len(next) {
+			next = append(next, slot{})
+		}
+		next[free] = slot{Key: key}
+		bound[key] = true
+	}
+
+	for len(next) > 0 && next[len(next)-1].Key == "" {
+		next = next[:len(next)-1]
+	}
+	return next
+}
+
+// slotsOf returns the slot of each key. Keys with no slot, as in files written
+// before slots existed, fall back to their position.
+func slotsOf(slots []slot, keys []string) []int {
+	numbers := make(map[string]int, len(slots))
+	for idx, s := range slots {
+		if s.Key != "" && !s.Retired {
+			numbers[s.Key] = idx + 1
+		}
+	}
+	out := make([]int, len(keys))
+	for idx, key := range keys {
+		if n, ok := numbers[key]; ok {
+			out[idx] = n
+		} else {
+			out[idx] = idx + 1
+		}
+	}
+	return out
+}
+
+func load[T any](path string) (itemsFile[T], error) {
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return itemsFile[T]{}, nil
+		}
+		return itemsFile[T]{}, fmt.Errorf("read items file: %w", err)
+	}
+
+	// The oldest files hold a bare array.
+	if trimmed := strings.TrimSpace(string(raw)); strings.HasPrefix(trimmed, "[") {
+		var items []T
+		if err := json.Unmarshal(raw, &items); err != nil {
+			return itemsFile[T]{}, fmt.Errorf("decode items file: %w", err)
+		}
+		return itemsFile[T]{Items: items}, nil
+	}
+
+	var file itemsFile[T]
+	if err := json.Unmarshal(raw, &file); err != nil {
+		return itemsFile[T]{}, fmt.Errorf("decode items file: %w", err)
+	}
+	return file, nil
+}
+
+func writeFileAtomically(path string, content []byte) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".tmp-*")
+	if err != nil {
+		return fmt.Errorf("create temp file: %w", err)
+	}
+	tmpPath := tmp.Name()
+
+	if _, err := tmp.Write(content); err != nil {
+		_ = tmp.Close()
+		_ = os.Remove(tmpPath)
+		return fmt.Errorf("write temp file: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		_ = os.Remove(tmpPath)
+		return fmt.Errorf("close temp file: %w", err)
+	}
+	if err := os.Rename(tmpPath, path); err != nil {
+		_ = os.Remove(tmpPath)
+		return fmt.Errorf("rename temp file: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,134 @@
+package itemref
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+type item struct {
+	ID string `json:"id"`
+}
+
+func itemKey(i item) string { return i.ID }
+
+func save(t *testing.T, path string, ids ...string) []int {
+	t.Helper()
+	items := make([]item, 0, len(ids))
+	for _, id := range ids {
+		items = append(items, item{ID: id})
+	}
+	slots, err := Save(path, items, itemKey)
+	if err != nil {
+		t.Fatalf("save %v: %v", ids, err)
+	}
+	return slots
+}
+
+func resolve(t *testing.T, path, raw string) (item, bool, error) {
+	t.Helper()
+	ref, err := Parse(raw)
+	if err != nil {
+		t.Fatalf("parse %q: %v", raw, err)
+	}
+	return Resolve(path, ref, itemKey)
+}
+
+func TestSaveKeepsSlotsAcrossRefreshes(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "items.json")
+
+	steps := []struct {
+		ids  []string
+		want []int
+	}{
+		{ids: []string{"a", "b", "c"}, want: []int{1, 2, 3}},
+		// b went away: its slot is retired, so d cannot take it yet.
+		{ids: []string{"c", "a", "d"}, want: []int{3, 1, 4}},
+		// Retired slots are free again one refresh later.
+		{ids: []string{"e", "a", "c", "d"}, want: []int{2, 1, 3, 4}},
+		// A key that comes back while its slot is retired gets it back.
+		{ids: []string{"a"}, want: []int{1}},
+		{ids: []string{"e", "a"}, want: []int{2, 1}},
+		{ids: []string{"a", "a"}, want: []int{1, 1}},
+	}
+	for n, step := range steps {
+		if got := save(t, path, step.ids...); !slices.Equal(got, step.want) {
+			t.Fatalf("step %d %v: expected slots %v, got %v", n, step.ids, step.want, got)
+		}
+		_, slots, err := Load(path, itemKey)
+		if err != nil || !slices.Equal(slots, step.want) {
+			t.Fatalf("step %d: expected loaded slots %v, got %v (%v)", n, step.want, slots, err)
+		}
+	}
+}
+
+// A refresh between rendering the menu and clicking a row must not turn the
+// click into an action on a different item.
+func TestResolveAfterRefreshBetweenRenderAndClick(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "items.json")
+
+	// The menu is rendered with a as open_1 and b as open_2.
+	save(t, path, "a", "b")
+	// A refresh drops a and lists c first before the user clicks.
+	save(t, path, "c", "b")
+
+	cases := []struct {
+		raw     string
+		wantID  string
+		wantErr error
+	}{
+		{raw: "1", wantErr: ErrStale},
+		{raw: "2", wantID: "b"},
+		{raw: "3", wantID: "c"},
+		{raw: "id:b", wantID: "b"},
+		{raw: "id:a", wantErr: ErrStale},
+	}
+	for _, tc := range cases {
+		got, ok, err := resolve(t, path, tc.raw)
+		if tc.wantErr != nil {
+			if !errors.Is(err, tc.wantErr) {
+				t.Fatalf("%q: expected %v, got %+v ok=%v err=%v", tc.raw, tc.wantErr, got, ok, err)
+			}
+			continue
+		}
+		if err != nil || !ok || got.ID != tc.wantID {
+			t.Fatalf("%q: expected %s, got %+v ok=%v err=%v", tc.raw, tc.wantID, got, ok, err)
+		}
+	}
+
+	if _, ok, err := resolve(t, path, "4"); ok || err != nil {
+		t.Fatalf("expected an unused slot to be not found, got ok=%v err=%v", ok, err)
+	}
+}
+
+func TestResolveReadsOlderFilesByPosition(t *testing.T) {
+	for name, content := range map[string]string{
+		"bare array": `[{"id":"a"},{"id":"b"}]`,
+		"generation": `{"generation":"abcd1234","items":[{"id":"a"},{"id":"b"}]}`,
+	} {
+		t.Run(name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "items.json")
+			if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+				t.Fatalf("write items: %v", err)
+			}
+
+			got, ok, err := resolve(t, path, "2")
+			if err != nil || !ok || got.ID != "b" {
+				t.Fatalf("expected b, got %+v ok=%v err=%v", got, ok, err)
+			}
+			if slots := save(t, path, "b", "c"); !slices.Equal(slots, []int{1, 2}) {
+				t.Fatalf("expected fresh slots, got %v", slots)
+			}
+		})
+	}
+}
+
+func TestParseRejectsInvalid(t *testing.T) {
+	for _, raw := range []string{"", "0", "x", "id:", "-1", "2@abcd"} {
+		if _, err := Parse(raw); err == nil {
+			t.Fatalf("expected error for %q", raw)
+		}
+	}
+}
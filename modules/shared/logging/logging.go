@@ -0,0 +1,160 @@
+// Package logging writes a module's slog records to a size-rotated JSON file
+// under its state dir, and reads them back for the `logs` command.
+package logging
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+const maxFileSize = 1 << 20
+
+// Setup routes the default slog logger to a size-rotated JSON log file.
+// Level "off" discards all records; unknown levels fall back to warn and say
+// so in the log.
+func Setup(path, level string) (io.Closer, error) {
+	parsed, enabled, levelErr := ParseLevel(level)
+	if !enabled {
+		slog.SetDefault(slog.New(slog.NewJSONHandler(io.Discard, nil)))
+		return io.NopCloser(nil), nil
+	}
+
+	file, err := openRotating(path, maxFileSize)
+	if err != nil {
+		return nil, err
+	}
+
+	handler := slog.NewJSONHandler(file, &slog.HandlerOptions{Level: parsed})
+	slog.SetDefault(slog.New(handler).With("pid", os.Getpid()))
+	if levelErr != nil {
+		slog.Warn("using warn log level", "error", levelErr)
+	}
+	return file, nil
+}
+
+func ParseLevel(raw string) (slog.Level, bool, error) {
+	switch strings.ToLower(strings.TrimSpace(raw)) {
+	case "", "warn", "warning":
+		return slog.LevelWarn, true, nil
+	case "debug":
+		return slog.LevelDebug, true, nil
+	case "info":
+		return slog.LevelInfo, true, nil
+	case "error":
+		return slog.LevelError, true, nil
+	case "off", "none":
+		return slog.LevelError, false, nil
+	default:
+		return slog.LevelWarn, true, fmt.Errorf("invalid log level %q", raw)
+	}
+}
+
+func Tail(path string, lines int, w io.Writer) error {
+	if lines <= 0 {
+		lines = 50
+	}
+
+	collected := make([]string, 0, lines)
+	for _, candidate := range []string{path + ".1", path} {
+		file, err := os.Open(candidate)
+		if err != nil {
+			if os.IsNotExist(err) {
+				continue
+			}
+			return fmt.Errorf("open log file: %w", err)
+		}
+
+		scanner := bufio.NewScanner(file)
+		scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+		for scanner.Scan() {
+			collected = append(collected, scanner.Text())
+			if len(collected) > lines {
+				collected = collected[1:]
+			}
+		}
+		scanErr := scanner.Err()
+		_ = file.Close()
+		if scanErr != nil {
+			return fmt.Errorf("read log file: %w", scanErr)
+		}
+	}
+
+	for _, line := range collected {
+		if _, err := fmt.Fprintln(w, line); err != nil {
+			return fmt.Errorf("write log line: %w", err)
+		}
+	}
+	return nil
+}
+
+type rotatingFile struct {
+	mu      sync.Mutex
+	path    string
+	maxSize int64
+	file    *os.File
+	size    int64
+}
+
+func openRotating(path string, maxSize int64) (*rotatingFile, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return nil, fmt.Errorf("create log dir: %w", err)
+	}
+
+	r := &rotatingFile{path: path, maxSize: maxSize}
+	if err := r.open(); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+func (r *rotatingFile) open() error {
+	file, err := os.OpenFile(r.path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o600)
+	if err != nil {
+		return fmt.Errorf("open log file: %w", err)
+	}
+	info, err := file.Stat()
+	if err != nil {
+		_ = file.Close()
+		return fmt.Errorf("stat log file: %w", err)
+	}
+	r.file = file
+	r.size = info.Size()
+	return nil
+}
+
+func (r *rotatingFile) Write(p []byte) (int, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.size+int64(len(p)) > r.maxSize && r.size > 0 {
+		if err := r.rotate(); err != nil {
+			return 0, err
+		}
+	}
+
+	n, err := r.file.Write(p)
+	r.size += int64(n)
+	return n, err
+}
+
+func (r *rotatingFile) rotate() error {
+	if err := r.file.Close(); err != nil {
+		return fmt.Errorf("close log file: %w", err)
+	}
+	if err := os.Rename(r.path, r.path+".1"); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("rotate log file: %w", err)
+	}
+	return r.open()
+}
+
+func (r *rotatingFile) Close() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.file.Close()
+}
@@ -0,0 +1,132 @@
+package logging
+
+import (
+	"bytes"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestRotatingFileRollsOverAtMaxSize(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "module.log")
+
+	file, err := openRotating(path, 32)
+	if err != nil {
+		t.Fatalf("open: %v", err)
+	}
+	for _, line := range []string{"first line of log\n", "second line of log\n", "third\n"} {
+		if _, err := file.Write([]byte(line)); err != nil {
+			t.Fatalf("write: %v", err)
+		}
+	}
+	if err := file.Close(); err != nil {
+		t.Fatalf("close: %v", err)
+	}
+
+	rotated, err := os.ReadFile(path + ".1")
+	if err != nil {
+		t.Fatalf("read rotated file: %v", err)
+	}
+	if string(rotated) != "first line of log\n" {
+		t.Fatalf("unexpected rotated content %q", rotated)
+	}
+
+	current, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("read current file: %v", err)
+	}
+	if string(current) != "second line of log\nthird\n" {
+		t.Fatalf("unexpected current content %q", current)
+	}
+}
+
+func TestTailReadsAcrossRotatedFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "module.log")
+	if err := os.WriteFile(path+".1", []byte("a\nb\nc\n"), 0o600); err != nil {
+		t.Fatalf("write rotated: %v", err)
+	}
+	if err := os.WriteFile(path, []byte("d\ne\n"), 0o600); err != nil {
+		t.Fatalf("write current: %v", err)
+	}
+
+	var out bytes.Buffer
+	if err := Tail(path, 3, &out); err != nil {
+		t.Fatalf("tail: %v", err)
+	}
+	if out.String() != "c\nd\ne\n" {
+		t.Fatalf("unexpected tail %q", out.String())
+	}
+}
+
+func TestSetupFiltersByLevel(t *testing.T) {
+	previous := slog.Default()
+	t.Cleanup(func() {
+		slog.SetDefault(previous)
+	})
+
+	path := filepath.Join(t.TempDir(), "module.log")
+	closer, err := Setup(path, "info")
+	if err != nil {
+		t.Fatalf("setup: %v", err)
+	}
+	slog.Debug("hidden record")
+	slog.Info("visible record", "status", 200)
+	if err := closer.Close(); err != nil {
+		t.Fatalf("close: %v", err)
+	}
+
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("read log: %v", err)
+	}
+	text := string(raw)
+	if strings.Contains(text, "hidden record") {
+		t.Fatalf("debug record should be filtered: %s", text)
+	}
+	if !strings.Contains(text, `"msg":"visible record"`) || !strings.Contains(text, `"status":200`) {
+		t.Fatalf("expected structured info record: %s", text)
+	}
+}
+
+func TestSetupLogsAnInvalidLevel(t *testing.T) {
+	previous := slog.Default()
+	t.Cleanup(func() {
+		slog.SetDefault(previous)
+	})
+
+	path := filepath.Join(t.TempDir(), "module.log")
+	closer, err := Setup(path, "verbose")
+	if err != nil {
+		t.Fatalf("setup: %v", err)
+	}
+	slog.Info("hidden record")
+	if err := closer.Close(); err != nil {
+		t.Fatalf("close: %v", err)
+	}
+
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("read log: %v", err)
+	}
+	text := string(raw)
+	if !strings.Contains(text, `"level":"WARN"`) || !strings.Contains(text, `invalid log level \"verbose\"`) {
+		t.Fatalf("expected a warning about the level: %s", text)
+	}
+	if strings.Contains(text, "hidden record") {
+		t.Fatalf("unknown levels should fall back to warn: %s", text)
+	}
+}
+
+func TestParseLevel(t *testing.T) {
+	if _, enabled, err := ParseLevel("off"); enabled || err != nil {
+		t.Fatalf("expected off to disable logging")
+	}
+	if level, enabled, err := ParseLevel("DEBUG"); !enabled || err != nil || level != slog.LevelDebug {
+		t.Fatalf("expected debug level, got %v %v %v", level, enabled, err)
+	}
+	if _, _, err := ParseLevel("verbose"); err == nil {
+		t.Fatal("expected error for unknown level")
+	}
+}
@@ -0,0 +1,142 @@
+// Package menu builds the GtkBuilder files behind Waybar's dropdown menus.
+package menu
+
+import (
+	"fmt"
+	"html"
+	"strings"
+)
+
+// MaxLabelRunes bounds row labels so long titles do not stretch the dropdown.
+const MaxLabelRunes = 72
+
+// Builder renders a GtkBuilder interface for Waybar's "menu-file". Rows keep
+// the ids given by callers so existing "menu-actions" mappings continue to work.
+type Builder struct {
+	b       strings.Builder
+	indent  int
+	autoIDs map[string]int
+	started bool
+	pending bool
+}
+
+func New() *Builder {
+	m := &Builder{indent: 4, autoIDs: make(map[string]int)}
+	m.b.WriteString("<?xml version=\"1.0\" encoding=\"UTF-8\"?>\n")
+	m.b.WriteString("<interface>\n")
+	m.b.WriteString("  <object class=\"GtkMenu\" id=\"menu\">\n")
+	return m
+}
+
+func (m *Builder) Item(id, label string) {
+	m.row("GtkMenuItem", id, label, "")
+}
+
+// IconItem adds a row with a themed icon (e.g. "camera-web").
+func (m *Builder) IconItem(id, label, iconName string) {
+	if strings.TrimSpace(iconName) == "" {
+		m.Item(id, label)
+		return
+	}
+	image := fmt.Sprintf("<property name=\"always-show-image\">True</property>\n"+
+		"%[1]s    <property name=\"image\">\n"+
+		"%[1]s      <object class=\"GtkImage\" id=\"%[2]s_icon\">\n"+
+		"%[1]s        <property name=\"icon-name\">%[3]s</property>\n"+
+		"%[1]s      </object>\n"+
+		"%[1]s    </property>", m.pad(), html.EscapeString(id), html.EscapeString(iconName))
+	m.row("GtkImageMenuItem", id, label, image)
+}
+
+func (m *Builder) Check(id, label string, active bool) {
+	m.row("GtkCheckMenuItem", id, label, fmt.Sprintf("<property name=\"active\">%s</property>", gtkBool(active)))
+}
+
+// Info adds an insensitive row for status text that has no action.
+func (m *Builder) Info(label string) {
+	m.row("GtkMenuItem", m.nextID("info"), label, "<property name=\"sensitive\">False</property>")
+}
+
+// Section starts a titled group, separated from any preceding rows.
+func (m *Builder) Section(title string) {
+	m.Separator()
+	m.Info(title)
+}
+
+// Separator is emitted lazily so leading, trailing and doubled separators collapse.
+func (m *Builder) Separator() {
+	if m.started {
+		m.pending = true
+	}
+}
+
+// Submenu nests the rows added by fill under a parent row.
+func (m *Builder) Submenu(id, label string, fill func(*Builder)) {
+	child := &Builder{indent: m.indent + 8, autoIDs: m.autoIDs}
+	fill(child)
+
+	pad := m.pad()
+	nested := fmt.Sprintf("<property name=\"submenu\">\n"+
+		"%[1]s      <object class=\"GtkMenu\" id=\"%[2]s_menu\">\n"+
+		"%[3]s"+
+		"%[1]s      </object>\n"+
+		"%[1]s    </property>", pad, html.EscapeString(id), child.b.String())
+	m.row("GtkMenuItem", id, label, nested)
+}
+
+func (m *Builder) Bytes() []byte {
+	return []byte(m.b.String() + "  </object>\n</interface>\n")
+}
+
+// Truncate collapses whitespace and shortens label to at most limit runes.
+// It runs before XML escaping so entities are never split.
+func Truncate(label string, limit int) string {
+	label = strings.Join(strings.Fields(label), " ")
+	runes := []rune(label)
+	if limit <= 0 || len(runes) <= limit {
+		return label
+	}
+	return strings.TrimSpace(string(runes[:limit-1])) + "…"
+}
+
+func (m *Builder) row(class, id, label, extra string) {
+	m.flushSeparator()
+	m.started = true
+
+	pad := m.pad()
+	m.b.WriteString(pad + "<child>\n")
+	_, _ = fmt.Fprintf(&m.b, "%s  <object class=\"%s\" id=\"%s\">\n", pad, class, html.EscapeString(id))
+	_, _ = fmt.Fprintf(&m.b, "%s    <property name=\"label\">%s</property>\n", pad, html.EscapeString(Truncate(label, MaxLabelRunes)))
+	if extra != "" {
+		_, _ = fmt.Fprintf(&m.b, "%s    %s\n", pad, extra)
+	}
+	_, _ = fmt.Fprintf(&m.b, "%s  </object>\n", pad)
+	m.b.WriteString(pad + "</child>\n")
+}
+
+func (m *Builder) flushSeparator() {
+	if !m.pending {
+		return
+	}
+	m.pending = false
+
+	pad := m.pad()
+	m.b.WriteString(pad + "<child>\n")
+	_, _ = fmt.Fprintf(&m.b, "%s  <object class=\"GtkSeparatorMenuItem\" id=\"%s\" />\n", pad, m.nextID("separator"))
+	m.b.WriteString(pad + "</child>\n")
+}
+
+func (m *Builder) nextID(prefix string) string {
+	m.autoIDs[prefix]++
+	return fmt.Sprintf("%s_%d", prefix, m.autoIDs[prefix])
+}
+
+func (m *Builder) pad() string {
+	return strings.Repeat(" ", m.indent)
+}
+
+func gtkBool(value bool) string {
+	if value {
+		return "True"
+	}
+	return "False"
+}
@@ -0,0 +1,65 @@
+package menu
+
+import (
+	"encoding/xml"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+)
+
+func TestBuilderProducesWellFormedNestedMenu(t *testing.T) {
+	m := New()
+	m.Separator()
+	m.Item("open_dashboard", "Open <dashboard> & more")
+	m.Section("rbright/waybar-modules")
+	m.Check("select_1", "Built-in microphone", true)
+	m.IconItem("join_1", "Standup", "camera-web")
+	m.Submenu("calendars", "Calendars", func(sub *Builder) {
+		sub.Check("calendar_1", "Work", true)
+		sub.Separator()
+		sub.Info("No other calendars")
+		sub.Separator()
+	})
+	m.Separator()
+	m.Separator()
+	m.Item("refresh", "Refresh")
+
+	raw := m.Bytes()
+	decoder := xml.NewDecoder(strings.NewReader(string(raw)))
+	for {
+		if _, err := decoder.Token(); err != nil {
+			if errors.Is(err, io.EOF) {
+				break
+			}
+			t.Fatalf("menu is not well-formed XML: %v\n%s", err, raw)
+		}
+	}
+
+	text := string(raw)
+	for _, expected := range []string{
+		`Open &lt;dashboard&gt; &amp; more`,
+		`<object class="GtkCheckMenuItem" id="select_1">`,
+		`<property name="active">True</property>`,
+		`<property name="icon-name">camera-web</property>`,
+		`<object class="GtkMenu" id="calendars_menu">`,
+		`<property name="sensitive">False</property>`,
+	} {
+		if !strings.Contains(text, expected) {
+			t.Fatalf("expected %q in menu:\n%s", expected, text)
+		}
+	}
+
+	if got := strings.Count(text, "GtkSeparatorMenuItem"); got != 3 {
+		t.Fatalf("expected leading, doubled and trailing separators to collapse to 3, got %d:\n%s", got, text)
+	}
+}
+
+func TestTruncate(t *testing.T) {
+	if got := Truncate("  short\n label ", 20); got != "short label" {
+		t.Fatalf("unexpected whitespace handling %q", got)
+	}
+	if got := Truncate("ääääääääää", 5); got != "ääää…" {
+		t.Fatalf("expected rune-safe truncation, got %q", got)
+	}
+}
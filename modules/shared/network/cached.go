@@ -0,0 +1,32 @@
+package network
+
+import (
+	"fmt"
+	"time"
+)
+
+// WithinStaleAge reports whether a fetch from fetchedAt may still be shown in
+// place of a refresh error; a staleAfter of 0 disables that.
+func WithinStaleAge(fetchedAt time.Time, staleAfter time.Duration) bool {
+	return staleAfter > 0 && !fetchedAt.IsZero() && now().Sub(fetchedAt) < staleAfter
+}
+
+// CachedNote explains why the last good fetch is shown, e.g.
+// "Offline; showing data from 5m ago".
+func CachedNote(reason string, fetchedAt time.Time) string {
+	return fmt.Sprintf("%s; showing data from %s", reason, FormatAge(now().Sub(fetchedAt)))
+}
+
+// FormatAge formats how long ago something happened, e.g. "just now" or "3h ago".
+func FormatAge(age time.Duration) string {
+	switch {
+	case age < time.Minute:
+		return "just now"
+	case age < time.Hour:
+		return fmt.Sprintf("%dm ago", int(age.Minutes()))
+	case age < 48*time.Hour:
+		return fmt.Sprintf("%dh ago", int(age.Hours()))
+	default:
+		return fmt.Sprintf("%dd ago", int(age.Hours()/24))
+	}
+}
@@ -0,0 +1,133 @@
+// Package network decides whether remote fetches are worth attempting, and
+// formats the note shown with the last good fetch when they are not.
+package network
+
+import (
+	"context"
+	"log/slog"
+	"net"
+	"net/url"
+	"sync"
+	"time"
+
+	"github.com/godbus/dbus/v5"
+)
+
+// NetworkManager NMConnectivityState values.
+const (
+	connectivityUnknown = 0
+	connectivityNone    = 1
+	connectivityPortal  = 2
+	connectivityLimited = 3
+	connectivityFull    = 4
+)
+
+const probeTimeout = 1500 * time.Millisecond
+
+// cacheTTL is how long a result is reused. Modules hosted by one daemon tend
+// to refresh together, so they share a single check.
+const cacheTTL = 10 * time.Second
+
+var (
+	nmConnectivity = networkManagerConnectivity
+	dialProbe      = func(ctx context.Context, address string) error {
+		dialer := net.Dialer{Timeout: probeTimeout}
+		conn, err := dialer.DialContext(ctx, "tcp", address)
+		if err != nil {
+			return err
+		}
+		return conn.Close()
+	}
+	now = time.Now
+)
+
+type result struct {
+	online    bool
+	checkedAt time.Time
+}
+
+var (
+	cacheMu sync.Mutex
+	cache   = make(map[string]result)
+)
+
+// Online reports whether remote fetches are worth attempting. NetworkManager
+// decides when it reports full connectivity or none at all. Limited and portal
+// connectivity can still reach hosts on the local network or a VPN, so those,
+// like an unknown state, fall back to a short TCP probe to the endpoint's host.
+// Results are reused for a few seconds per host.
+func Online(ctx context.Context, endpoint string) bool {
+	address := probeAddress(endpoint)
+
+	cacheMu.Lock()
+	cached, ok := cache[address]
+	cacheMu.Unlock()
+	if ok && now().Sub(cached.checkedAt) < cacheTTL {
+		return cached.online
+	}
+
+	online := check(ctx, address)
+	cacheMu.Lock()
+	cache[address] = result{online: online, checkedAt: now()}
+	cacheMu.Unlock()
+	return online
+}
+
+func check(ctx context.Context, address string) bool {
+	if state, err := nmConnectivity(ctx); err == nil {
+		switch state {
+		case connectivityFull:
+			return true
+		case connectivityNone:
+			slog.Debug("networkmanager reports offline", "connectivity", state)
+			return false
+		}
+		slog.Debug("networkmanager connectivity inconclusive", "connectivity", state)
+	} else {
+		slog.Debug("networkmanager connectivity unavailable", "error", err)
+	}
+
+	if address == "" {
+		return true
+	}
+	probeCtx, cancel := context.WithTimeout(ctx, probeTimeout)
+	defer cancel()
+	if err := dialProbe(probeCtx, address); err != nil {
+		slog.Debug("connectivity probe failed", "address", address, "error", err)
+		return false
+	}
+	return true
+}
+
+func networkManagerConnectivity(ctx context.Context) (uint32, error) {
+	conn, err := dbus.ConnectSystemBus(dbus.WithContext(ctx))
+	if err != nil {
+		return connectivityUnknown, err
+	}
+	defer func() {
+		_ = conn.Close()
+	}()
+
+	variant, err := conn.Object("org.freedesktop.NetworkManager", "/org/freedesktop/NetworkManager").
+		GetProperty("org.freedesktop.NetworkManager.Connectivity")
+	if err != nil {
+		return connectivityUnknown, err
+	}
+	state, _ := variant.Value().(uint32)
+	return state, nil
+}
+
+func probeAddress(endpoint string) string {
+	parsed, err := url.Parse(endpoint)
+	if err != nil || parsed.Hostname() == "" {
+		return ""
+	}
+	port := parsed.Port()
+	if port == "" {
+		port = "443"
+		if parsed.Scheme == "http" {
+			port = "80"
+		}
+	}
+	return net.JoinHostPort(parsed.Hostname(), port)
+}
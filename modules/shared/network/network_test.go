@@ -0,0 +1,181 @@
+package network
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+// stubNetwork fakes NetworkManager and the probe, empties the result cache and
+// returns the clock and the number of checks made so far.
+func stubNetwork(t *testing.T, state uint32, nmErr error, probeErr error) (probed *string, clock *time.Time, checks *int) {
+	t.Helper()
+	originalNM, originalDial, originalNow := nmConnectivity, dialProbe, now
+	t.Cleanup(func() {
+		nmConnectivity, dialProbe, now = originalNM, originalDial, originalNow
+		resetCache()
+	})
+	resetCache()
+
+	probed = new(string)
+	clock = new(time.Time)
+	*clock = time.Date(2026, 3, 2, 9, 0, 0, 0, time.UTC)
+	checks = new(int)
+	nmConnectivity = func(context.Context) (uint32, error) {
+		*checks++
+		return state, nmErr
+	}
+	dialProbe = func(_ context.Context, address string) error {
+		*probed = address
+		return probeErr
+	}
+	now = func() time.Time { return *clock }
+	return probed, clock, checks
+}
+
+func resetCache() {
+	cacheMu.Lock()
+	defer cacheMu.Unlock()
+	cache = make(map[string]result)
+}
+
+func TestOnlineTrustsNetworkManager(t *testing.T) {
+	tests := []struct {
+		name  string
+		state uint32
+		want  bool
+	}{
+		{name: "full", state: connectivityFull, want: true},
+		{name: "none", state: connectivityNone, want: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			probed, _, _ := stubNetwork(t, tt.state, nil, errors.New("unreachable"))
+			if got := Online(context.Background(), "https://api.example.com"); got != tt.want {
+				t.Fatalf("expected online=%v, got %v", tt.want, got)
+			}
+			if *probed != "" {
+				t.Fatalf("expected no probe, got %q", *probed)
+			}
+		})
+	}
+}
+
+// Limited and portal connectivity can still reach a VPN or LAN host such as a
+// GitHub Enterprise server, so the probe decides.
+func TestOnlineProbesInconclusiveStates(t *testing.T) {
+	states := map[string]uint32{
+		"unknown": connectivityUnknown,
+		"portal":  connectivityPortal,
+		"limited": connectivityLimited,
+	}
+	for name, state := range states {
+		t.Run(name, func(t *testing.T) {
+			probed, _, _ := stubNetwork(t, state, nil, nil)
+			if !Online(context.Background(), "https://ghe.internal/api/graphql") {
+				t.Fatal("expected online when the probe succeeds")
+			}
+			if *probed != "ghe.internal:443" {
+				t.Fatalf("unexpected probe address %q", *probed)
+			}
+
+			stubNetwork(t, state, nil, errors.New("unreachable"))
+			if Online(context.Background(), "https://ghe.internal/api/graphql") {
+				t.Fatal("expected offline when the probe fails")
+			}
+		})
+	}
+}
+
+func TestOnlineFallsBackToProbe(t *testing.T) {
+	probed, _, _ := stubNetwork(t, 0, errors.New("no system bus"), nil)
+	if !Online(context.Background(), "https://api.example.com/v1") {
+		t.Fatal("expected online when probe succeeds")
+	}
+	if *probed != "api.example.com:443" {
+		t.Fatalf("unexpected probe address %q", *probed)
+	}
+
+	stubNetwork(t, connectivityUnknown, nil, errors.New("unreachable"))
+	if Online(context.Background(), "https://api.example.com") {
+		t.Fatal("expected offline when probe fails")
+	}
+}
+
+func TestOnlineReusesRecentResults(t *testing.T) {
+	_, clock, checks := stubNetwork(t, connectivityFull, nil, nil)
+
+	for range 3 {
+		Online(context.Background(), "https://api.github.com/graphql")
+	}
+	if *checks != 1 {
+		t.Fatalf("expected one check within the cache window, got %d", *checks)
+	}
+
+	Online(context.Background(), "https://api.linear.app/graphql")
+	if *checks != 2 {
+		t.Fatalf("expected another host to be checked on its own, got %d checks", *checks)
+	}
+
+	*clock = clock.Add(cacheTTL)
+	Online(context.Background(), "https://api.github.com/graphql")
+	if *checks != 3 {
+		t.Fatalf("expected an expired result to be checked again, got %d checks", *checks)
+	}
+}
+
+func TestProbeAddress(t *testing.T) {
+	cases := map[string]string{
+		"https://api.github.com":        "api.github.com:443",
+		"http://localhost:8080/graphql": "localhost:8080",
+		"http://ghe.internal/api/v3":    "ghe.internal:80",
+		"not a url":                     "",
+		"":                              "",
+	}
+	for endpoint, want := range cases {
+		if got := probeAddress(endpoint); got != want {
+			t.Fatalf("probeAddress(%q) = %q, want %q", endpoint, got, want)
+		}
+	}
+}
+
+func TestCachedNote(t *testing.T) {
+	_, clock, _ := stubNetwork(t, connectivityFull, nil, nil)
+
+	tests := []struct {
+		age  time.Duration
+		want string
+	}{
+		{age: 30 * time.Second, want: "Offline; showing data from just now"},
+		{age: 5 * time.Minute, want: "Offline; showing data from 5m ago"},
+		{age: 47 * time.Hour, want: "Offline; showing data from 47h ago"},
+		{age: 72 * time.Hour, want: "Offline; showing data from 3d ago"},
+	}
+	for _, tt := range tests {
+		if got := CachedNote("Offline", clock.Add(-tt.age)); got != tt.want {
+			t.Fatalf("age %s: expected %q, got %q", tt.age, tt.want, got)
+		}
+	}
+}
+
+func TestWithinStaleAge(t *testing.T) {
+	_, clock, _ := stubNetwork(t, connectivityFull, nil, nil)
+
+	tests := []struct {
+		name       string
+		fetchedAt  time.Time
+		staleAfter time.Duration
+		want       bool
+	}{
+		{name: "recent", fetchedAt: clock.Add(-time.Minute), staleAfter: 15 * time.Minute, want: true},
+		{name: "too old", fetchedAt: clock.Add(-15 * time.Minute), staleAfter: 15 * time.Minute, want: false},
+		{name: "disabled", fetchedAt: clock.Add(-time.Minute), staleAfter: 0, want: false},
+		{name: "never fetched", staleAfter: 15 * time.Minute, want: false},
+	}
+	for _, tt := range tests {
+		if got := WithinStaleAge(tt.fetchedAt, tt.staleAfter); got != tt.want {
+			t.Fatalf("%s: expected %v, got %v", tt.name, tt.want, got)
+		}
+	}
+}
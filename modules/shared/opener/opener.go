@@ -0,0 +1,197 @@
+// Package opener launches URLs from menu actions, with per-URL rules and a
+// fallback through xdg-open, gio and the desktop portal.
+package opener
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/url"
+	"os"
+	"os/exec"
+	"regexp"
+	"strconv"
+	"strings"
+	"syscall"
+)
+
+type Config struct {
+	// Command is a template such as "firefox -P work {url}" used when no rule matches.
+	Command   string
+	RulesFile string
+}
+
+type Rule struct {
+	Pattern *regexp.Regexp
+	Command string
+}
+
+var lookPath = exec.LookPath
+
+// Open launches url with the first matching rule, the configured command, or
+// the first available of xdg-open, gio open and the desktop portal.
+//
+// The opener outlives the command: ctx only guards the start, since a rule may
+// launch the browser itself. It runs in its own session so signals aimed at
+// Waybar's module process do not reach it, and it is waited for in the
+// background so a long-running daemon does not collect zombies.
+func Open(ctx context.Context, cfg Config, rawURL string) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	argv, err := Resolve(cfg, rawURL)
+	if err != nil {
+		return err
+	}
+
+	slog.Debug("opening url", "command", argv[0], "url", rawURL)
+	cmd := exec.Command(argv[0], argv[1:]...)
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setsid: true}
+	if err := cmd.Start(); err != nil {
+		return fmt.Errorf("open url: %w", err)
+	}
+	go func() {
+		if err := cmd.Wait(); err != nil {
+			slog.Debug("url opener exited", "command", argv[0], "error", err)
+		}
+	}()
+	return nil
+}
+
+func Resolve(cfg Config, rawURL string) ([]string, error) {
+	rawURL = strings.TrimSpace(rawURL)
+	if rawURL == "" {
+		return nil, errors.New("empty url")
+	}
+
+	rules, err := LoadRules(cfg.RulesFile)
+	if err != nil {
+		return nil, err
+	}
+	for _, rule := range rules {
+		if match := rule.Pattern.FindStringSubmatch(rawURL); match != nil {
+			return expand(rule.Command, rawURL, match)
+		}
+	}
+
+	if strings.TrimSpace(cfg.Command) != "" {
+		return expand(cfg.Command, rawURL, nil)
+	}
+
+	return fallback(rawURL)
+}
+
+// Describe reports which opener handles URLs that match no rule.
+func Describe(cfg Config) (string, error) {
+	if command := strings.TrimSpace(cfg.Command); command != "" {
+		name := strings.Fields(command)[0]
+		if _, err := lookPath(name); err != nil {
+			return "", fmt.Errorf("opener command %q not found", name)
+		}
+		return command, nil
+	}
+
+	argv, err := fallback("{url}")
+	if err != nil {
+		return "", err
+	}
+	return strings.Join(argv, " "), nil
+}
+
+// LoadRules parses "<regexp> <command template>" lines; a missing file has no rules.
+func LoadRules(path string) ([]Rule, error) {
+	if strings.TrimSpace(path) == "" {
+		return nil, nil
+	}
+
+	file, err := os.Open(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("open opener rules %s: %w", path, err)
+	}
+	defer func() {
+		_ = file.Close()
+	}()
+
+	var rules []Rule
+	scanner := bufio.NewScanner(file)
+	for lineNumber := 1; scanner.Scan(); lineNumber++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		pattern, command := line, ""
+		if split := strings.IndexAny(line, " \t"); split > 0 {
+			pattern, command = line[:split], strings.TrimSpace(line[split:])
+		}
+		if command == "" {
+			return nil, fmt.Errorf("%s line %d: expected <pattern> <command>", path, lineNumber)
+		}
+
+		compiled, err := regexp.Compile(pattern)
+		if err != nil {
+			return nil, fmt.Errorf("%s line %d: %w", path, lineNumber, err)
+		}
+		rules = append(rules, Rule{Pattern: compiled, Command: command})
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("scan opener rules %s: %w", path, err)
+	}
+	return rules, nil
+}
+
+// expand substitutes {url}, {host}, {path} and regexp groups {1}..{9} per argument,
+// so URLs never pass through a shell.
+func expand(template, rawURL string, match []string) ([]string, error) {
+	fields := strings.Fields(template)
+	if len(fields) == 0 {
+		return nil, errors.New("empty opener command")
+	}
+
+	replacements := []string{"{url}", rawURL}
+	if parsed, err := url.Parse(rawURL); err == nil {
+		pathAndQuery := strings.TrimPrefix(parsed.RequestURI(), "/")
+		replacements = append(replacements, "{host}", parsed.Host, "{path}", pathAndQuery)
+	}
+	for i := 1; i < len(match) && i <= 9; i++ {
+		replacements = append(replacements, "{"+strconv.Itoa(i)+"}", match[i])
+	}
+	replacer := strings.NewReplacer(replacements...)
+
+	argv := make([]string, 0, len(fields)+1)
+	hasURL := false
+	for _, field := range fields {
+		if field != replacer.Replace(field) {
+			hasURL = true
+		}
+		argv = append(argv, replacer.Replace(field))
+	}
+	if !hasURL {
+		argv = append(argv, rawURL)
+	}
+	return argv, nil
+}
+
+func fallback(rawURL string) ([]string, error) {
+	if _, err := lookPath("xdg-open"); err == nil {
+		return []string{"xdg-open", rawURL}, nil
+	}
+	if _, err := lookPath("gio"); err == nil {
+		return []string{"gio", "open", rawURL}, nil
+	}
+	if _, err := lookPath("gdbus"); err == nil {
+		return []string{
+			"gdbus", "call", "--session",
+			"--dest", "org.freedesktop.portal.Desktop",
+			"--object-path", "/org/freedesktop/portal/desktop",
+			"--method", "org.freedesktop.portal.OpenURI.OpenURI",
+			"", rawURL, "{}",
+		}, nil
+	}
+	return nil, errors.New("no url opener found (install xdg-open, gio or gdbus)")
+}
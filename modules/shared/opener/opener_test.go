@@ -0,0 +1,149 @@
+package opener
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestResolveUsesFirstMatchingRule(t *testing.T) {
+	rulesFile := filepath.Join(t.TempDir(), "url-opener.rules")
+	content := "# work profile\n" +
+		`^https://github\.com/ firefox -P work --new-tab {url}` + "\n" +
+		`^https://[a-z0-9.]*zoom\.us/j/(\d+)\?pwd=(\w+) xdg-open zoommtg://zoom.us/join?confno={1}&pwd={2}` + "\n" +
+		`^https://linear\.app/ xdg-open linear://{path}` + "\n"
+	if err := os.WriteFile(rulesFile, []byte(content), 0o600); err != nil {
+		t.Fatalf("write rules: %v", err)
+	}
+	cfg := Config{Command: "chromium", RulesFile: rulesFile}
+
+	cases := map[string][]string{
+		"https://github.com/rbright/waybar-modules/pull/1": {"firefox", "-P", "work", "--new-tab", "https://github.com/rbright/waybar-modules/pull/1"},
+		"https://acme.zoom.us/j/123456?pwd=abc":            {"xdg-open", "zoommtg://zoom.us/join?confno=123456&pwd=abc"},
+		"https://linear.app/team/issue/ENG-1":              {"xdg-open", "linear://team/issue/ENG-1"},
+		"https://meet.google.com/abc-defg-hij":             {"chromium", "https://meet.google.com/abc-defg-hij"},
+	}
+	for url, want := range cases {
+		got, err := Resolve(cfg, url)
+		if err != nil {
+			t.Fatalf("resolve %s: %v", url, err)
+		}
+		if !reflect.DeepEqual(got, want) {
+			t.Fatalf("resolve %s: expected %q, got %q", url, want, got)
+		}
+	}
+}
+
+func TestResolveRejectsInvalidRules(t *testing.T) {
+	rulesFile := filepath.Join(t.TempDir(), "url-opener.rules")
+	if err := os.WriteFile(rulesFile, []byte("^https://github.com/\n"), 0o600); err != nil {
+		t.Fatalf("write rules: %v", err)
+	}
+	if _, err := Resolve(Config{RulesFile: rulesFile}, "https://github.com/"); err == nil {
+		t.Fatal("expected error for rule without command")
+	}
+}
+
+func TestResolveFallbackOrder(t *testing.T) {
+	original := lookPath
+	t.Cleanup(func() {
+		lookPath = original
+	})
+
+	available := map[string]bool{"gio": true, "gdbus": true}
+	lookPath = func(name string) (string, error) {
+		if available[name] {
+			return "/usr/bin/" + name, nil
+		}
+		return "", errors.New("not found")
+	}
+
+	got, err := Resolve(Config{}, "https://example.com")
+	if err != nil {
+		t.Fatalf("resolve: %v", err)
+	}
+	if want := []string{"gio", "open", "https://example.com"}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("expected %q, got %q", want, got)
+	}
+
+	delete(available, "gio")
+	got, err = Resolve(Config{}, "https://example.com")
+	if err != nil {
+		t.Fatalf("resolve: %v", err)
+	}
+	if got[0] != "gdbus" || got[len(got)-2] != "https://example.com" {
+		t.Fatalf("expected portal call, got %q", got)
+	}
+
+	delete(available, "gdbus")
+	if _, err := Resolve(Config{}, "https://example.com"); err == nil {
+		t.Fatal("expected error when no opener is available")
+	}
+}
+
+// TestOpenOutlivesCommandAndIsReaped runs a rule whose opener keeps running
+// after the command's context is gone, like a browser launched directly.
+func TestOpenOutlivesCommandAndIsReaped(t *testing.T) {
+	dir := t.TempDir()
+	script := filepath.Join(dir, "browser")
+	out := filepath.Join(dir, "opened")
+	content := "#!/bin/sh\nsleep 0.2\nprintf '%s %s' \"$$\" \"$1\" > " + out + ".tmp\nmv " + out + ".tmp " + out + "\n"
+	if err := os.WriteFile(script, []byte(content), 0o755); err != nil {
+		t.Fatalf("write script: %v", err)
+	}
+	rulesFile := filepath.Join(dir, "url-opener.rules")
+	if err := os.WriteFile(rulesFile, []byte("^https:// "+script+" {url}\n"), 0o600); err != nil {
+		t.Fatalf("write rules: %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	if err := Open(ctx, Config{RulesFile: rulesFile}, "https://github.com/rbright/waybar-modules"); err != nil {
+		t.Fatalf("open: %v", err)
+	}
+	cancel()
+
+	var opened string
+	waitFor(t, func() bool {
+		raw, err := os.ReadFile(out)
+		opened = string(raw)
+		return err == nil
+	})
+	pid, url, _ := strings.Cut(opened, " ")
+	if url != "https://github.com/rbright/waybar-modules" {
+		t.Fatalf("expected the opener to receive the url, got %q", opened)
+	}
+	if _, err := strconv.Atoi(pid); err != nil {
+		t.Fatalf("expected opener pid, got %q", pid)
+	}
+	waitFor(t, func() bool {
+		_, err := os.Stat(filepath.Join("/proc", pid))
+		return os.IsNotExist(err)
+	})
+}
+
+func TestOpenRefusesCancelledContext(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err := Open(ctx, Config{Command: "true"}, "https://example.com"); !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected context error, got %v", err)
+	}
+}
+
+func waitFor(t *testing.T, condition func() bool) {
+	t.Helper()
+
+	deadline := time.Now().Add(5 * time.Second)
+	for time.Now().Before(deadline) {
+		if condition() {
+			return
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Fatal("condition not met before deadline")
+}
@@ -0,0 +1,234 @@
+package secrets
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	"github.com/godbus/dbus/v5"
+)
+
+const (
+	serviceName      = "org.freedesktop.secrets"
+	servicePath      = dbus.ObjectPath("/org/freedesktop/secrets")
+	serviceInterface = "org.freedesktop.Secret.Service"
+	promptInterface  = "org.freedesktop.Secret.Prompt"
+	noPrompt         = dbus.ObjectPath("/")
+)
+
+var (
+	ErrNotFound = errors.New("secret not found")
+	ErrLocked   = errors.New("keyring is locked")
+)
+
+type secret struct {
+	Session     dbus.ObjectPath
+	Parameters  []byte
+	Value       []byte
+	ContentType string
+}
+
+// ParseAttributes reads "key=value" pairs separated by spaces or commas.
+func ParseAttributes(raw string) (map[string]string, error) {
+	attributes := make(map[string]string)
+	for _, field := range strings.FieldsFunc(raw, func(r rune) bool { return r == ',' || r == ' ' }) {
+		key, value, ok := strings.Cut(field, "=")
+		if !ok || strings.TrimSpace(key) == "" {
+			return nil, fmt.Errorf("invalid secret attribute %q (expected key=value)", field)
+		}
+		attributes[strings.TrimSpace(key)] = strings.TrimSpace(value)
+	}
+	return attributes, nil
+}
+
+func FormatAttributes(attributes map[string]string) string {
+	keys := make([]string, 0, len(attributes))
+	for key := range attributes {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	pairs := make([]string, 0, len(keys))
+	for _, key := range keys {
+		pairs = append(pairs, key+"="+attributes[key])
+	}
+	return strings.Join(pairs, " ")
+}
+
+// ReadValue reads a secret from the first line of r, as piped to a
+// store-token command. It returns "" when r holds nothing.
+func ReadValue(r io.Reader) (string, error) {
+	line, err := bufio.NewReader(r).ReadString('\n')
+	if err != nil && !errors.Is(err, io.EOF) {
+		return "", err
+	}
+	return strings.TrimSpace(line), nil
+}
+
+// Lookup returns the first secret matching attributes from the session keyring.
+// It never shows an unlock prompt, since it runs on every bar poll; a keyring
+// that needs one yields ErrLocked.
+func Lookup(ctx context.Context, attributes map[string]string) (string, error) {
+	if len(attributes) == 0 {
+		return "", ErrNotFound
+	}
+
+	conn, err := dbus.ConnectSessionBus(dbus.WithContext(ctx))
+	if err != nil {
+		return "", fmt.Errorf("connect session bus: %w", err)
+	}
+	defer func() {
+		_ = conn.Close()
+	}()
+
+	service := conn.Object(serviceName, servicePath)
+	session, err := openSession(ctx, service)
+	if err != nil {
+		return "", err
+	}
+
+	var unlocked, locked []dbus.ObjectPath
+	if err := service.CallWithContext(ctx, serviceInterface+".SearchItems", 0, attributes).Store(&unlocked, &locked); err != nil {
+		return "", fmt.Errorf("search secret items: %w", err)
+	}
+	if len(unlocked) == 0 && len(locked) > 0 {
+		unlocked, err = unlock(ctx, conn, service, locked[:1], false)
+		if err != nil {
+			return "", err
+		}
+	}
+	if len(unlocked) == 0 {
+		return "", ErrNotFound
+	}
+
+	var found map[dbus.ObjectPath]secret
+	if err := service.CallWithContext(ctx, serviceInterface+".GetSecrets", 0, unlocked[:1], session).Store(&found); err != nil {
+		return "", fmt.Errorf("read secret: %w", err)
+	}
+	value, ok := found[unlocked[0]]
+	if !ok {
+		return "", ErrNotFound
+	}
+	return strings.TrimSpace(string(value.Value)), nil
+}
+
+// Store creates or replaces the secret identified by attributes in the default collection.
+func Store(ctx context.Context, label string, attributes map[string]string, value string) error {
+	if len(attributes) == 0 {
+		return errors.New("secret attributes are empty")
+	}
+
+	conn, err := dbus.ConnectSessionBus(dbus.WithContext(ctx))
+	if err != nil {
+		return fmt.Errorf("connect session bus: %w", err)
+	}
+	defer func() {
+		_ = conn.Close()
+	}()
+
+	service := conn.Object(serviceName, servicePath)
+	session, err := openSession(ctx, service)
+	if err != nil {
+		return err
+	}
+
+	var collectionPath dbus.ObjectPath
+	if err := service.CallWithContext(ctx, serviceInterface+".ReadAlias", 0, "default").Store(&collectionPath); err != nil {
+		return fmt.Errorf("resolve default collection: %w", err)
+	}
+	if collectionPath == noPrompt {
+		return errors.New("secret service has no default collection")
+	}
+	if _, err := unlock(ctx, conn, service, []dbus.ObjectPath{collectionPath}, true); err != nil {
+		return err
+	}
+
+	properties := map[string]dbus.Variant{
+		"org.freedesktop.Secret.Item.Label":      dbus.MakeVariant(label),
+		"org.freedesktop.Secret.Item.Attributes": dbus.MakeVariant(attributes),
+	}
+	payload := secret{Session: session, Parameters: []byte{}, Value: []byte(value), ContentType: "text/plain"}
+
+	var item, prompt dbus.ObjectPath
+	collection := conn.Object(serviceName, collectionPath)
+	if err := collection.CallWithContext(ctx, "org.freedesktop.Secret.Collection.CreateItem", 0, properties, payload, true).Store(&item, &prompt); err != nil {
+		return fmt.Errorf("create secret item: %w", err)
+	}
+	if prompt != noPrompt {
+		if _, err := runPrompt(ctx, conn, prompt); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func openSession(ctx context.Context, service dbus.BusObject) (dbus.ObjectPath, error) {
+	var output dbus.Variant
+	var session dbus.ObjectPath
+	if err := service.CallWithContext(ctx, serviceInterface+".OpenSession", 0, "plain", dbus.MakeVariant("")).Store(&output, &session); err != nil {
+		return "", fmt.Errorf("open secret service session: %w", err)
+	}
+	return session, nil
+}
+
+func unlock(ctx context.Context, conn *dbus.Conn, service dbus.BusObject, objects []dbus.ObjectPath, interactive bool) ([]dbus.ObjectPath, error) {
+	var unlocked []dbus.ObjectPath
+	var prompt dbus.ObjectPath
+	if err := service.CallWithContext(ctx, serviceInterface+".Unlock", 0, objects).Store(&unlocked, &prompt); err != nil {
+		return nil, fmt.Errorf("unlock keyring: %w", err)
+	}
+	if prompt == noPrompt {
+		return unlocked, nil
+	}
+	if !interactive {
+		return nil, ErrLocked
+	}
+
+	result, err := runPrompt(ctx, conn, prompt)
+	if err != nil {
+		return nil, err
+	}
+	paths, ok := result.Value().([]dbus.ObjectPath)
+	if !ok {
+		return nil, errors.New("unexpected unlock prompt result")
+	}
+	return paths, nil
+}
+
+func runPrompt(ctx context.Context, conn *dbus.Conn, prompt dbus.ObjectPath) (dbus.Variant, error) {
+	if err := conn.AddMatchSignalContext(ctx,
+		dbus.WithMatchObjectPath(prompt),
+		dbus.WithMatchInterface(promptInterface),
+		dbus.WithMatchMember("Completed"),
+	); err != nil {
+		return dbus.Variant{}, fmt.Errorf("watch keyring prompt: %w", err)
+	}
+
+	signals := make(chan *dbus.Signal, 1)
+	conn.Signal(signals)
+	defer conn.RemoveSignal(signals)
+
+	if err := conn.Object(serviceName, prompt).CallWithContext(ctx, promptInterface+".Prompt", 0, "").Err; err != nil {
+		return dbus.Variant{}, fmt.Errorf("show keyring prompt: %w", err)
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			return dbus.Variant{}, ctx.Err()
+		case signal := <-signals:
+			if signal.Path != prompt || len(signal.Body) < 2 {
+				continue
+			}
+			if dismissed, _ := signal.Body[0].(bool); dismissed {
+				return dbus.Variant{}, errors.New("keyring prompt dismissed")
+			}
+			result, _ := signal.Body[1].(dbus.Variant)
+			return result, nil
+		}
+	}
+}
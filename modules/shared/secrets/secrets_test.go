@@ -0,0 +1,226 @@
+package secrets
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/godbus/dbus/v5"
+)
+
+const collectionPath = dbus.ObjectPath("/org/freedesktop/secrets/collection/login")
+
+// fakeService is a minimal in-memory org.freedesktop.Secret.Service.
+type fakeService struct {
+	mu     sync.Mutex
+	items  map[dbus.ObjectPath]fakeItem
+	locked bool
+}
+
+type fakeItem struct {
+	attributes map[string]string
+	value      []byte
+}
+
+func (s *fakeService) OpenSession(algorithm string, _ dbus.Variant) (dbus.Variant, dbus.ObjectPath, *dbus.Error) {
+	if algorithm != "plain" {
+		return dbus.Variant{}, "", dbus.NewError("org.freedesktop.DBus.Error.NotSupported", nil)
+	}
+	return dbus.MakeVariant(""), "/org/freedesktop/secrets/session/1", nil
+}
+
+func (s *fakeService) SearchItems(attributes map[string]string) ([]dbus.ObjectPath, []dbus.ObjectPath, *dbus.Error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var matched []dbus.ObjectPath
+	for path, item := range s.items {
+		if matches(item.attributes, attributes) {
+			matched = append(matched, path)
+		}
+	}
+	if s.locked {
+		return []dbus.ObjectPath{}, matched, nil
+	}
+	return matched, []dbus.ObjectPath{}, nil
+}
+
+func (s *fakeService) Unlock(objects []dbus.ObjectPath) ([]dbus.ObjectPath, dbus.ObjectPath, *dbus.Error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.locked = false
+	return objects, "/", nil
+}
+
+func (s *fakeService) GetSecrets(items []dbus.ObjectPath, session dbus.ObjectPath) (map[dbus.ObjectPath]secret, *dbus.Error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	out := make(map[dbus.ObjectPath]secret)
+	for _, path := range items {
+		if item, ok := s.items[path]; ok {
+			out[path] = secret{Session: session, Parameters: []byte{}, Value: item.value, ContentType: "text/plain"}
+		}
+	}
+	return out, nil
+}
+
+func (s *fakeService) ReadAlias(name string) (dbus.ObjectPath, *dbus.Error) {
+	if name != "default" {
+		return "/", nil
+	}
+	return collectionPath, nil
+}
+
+type fakeCollection struct {
+	service *fakeService
+}
+
+func (c *fakeCollection) CreateItem(properties map[string]dbus.Variant, value secret, replace bool) (dbus.ObjectPath, dbus.ObjectPath, *dbus.Error) {
+	attributes, _ := properties["org.freedesktop.Secret.Item.Attributes"].Value().(map[string]string)
+
+	c.service.mu.Lock()
+	defer c.service.mu.Unlock()
+
+	if replace {
+		for path, item := range c.service.items {
+			if matches(item.attributes, attributes) && len(item.attributes) == len(attributes) {
+				c.service.items[path] = fakeItem{attributes: attributes, value: value.Value}
+				return path, "/", nil
+			}
+		}
+	}
+	path := dbus.ObjectPath(string(collectionPath) + "/item" + string(rune('0'+len(c.service.items))))
+	c.service.items[path] = fakeItem{attributes: attributes, value: value.Value}
+	return path, "/", nil
+}
+
+func matches(have, want map[string]string) bool {
+	for key, value := range want {
+		if have[key] != value {
+			return false
+		}
+	}
+	return true
+}
+
+func startPrivateSecretService(t *testing.T) *fakeService {
+	t.Helper()
+
+	daemonPath, err := exec.LookPath("dbus-daemon")
+	if err != nil {
+		t.Skip("dbus-daemon not available")
+	}
+
+	address := "unix:path=" + filepath.Join(t.TempDir(), "bus")
+	cmd := exec.Command(daemonPath, "--session", "--nofork", "--nopidfile", "--print-address", "--address="+address)
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		t.Fatalf("dbus-daemon stdout: %v", err)
+	}
+	if err := cmd.Start(); err != nil {
+		t.Fatalf("start dbus-daemon: %v", err)
+	}
+	t.Cleanup(func() {
+		_ = cmd.Process.Kill()
+		_ = cmd.Wait()
+	})
+
+	line, err := bufio.NewReader(stdout).ReadString('\n')
+	if err != nil {
+		t.Fatalf("read bus address: %v", err)
+	}
+	t.Setenv("DBUS_SESSION_BUS_ADDRESS", strings.TrimSpace(line))
+
+	conn, err := dbus.ConnectSessionBus()
+	if err != nil {
+		t.Fatalf("connect private bus: %v", err)
+	}
+	t.Cleanup(func() {
+		_ = conn.Close()
+	})
+
+	service := &fakeService{items: make(map[dbus.ObjectPath]fakeItem)}
+	if err := conn.Export(service, servicePath, serviceInterface); err != nil {
+		t.Fatalf("export service: %v", err)
+	}
+	if err := conn.Export(&fakeCollection{service: service}, collectionPath, "org.freedesktop.Secret.Collection"); err != nil {
+		t.Fatalf("export collection: %v", err)
+	}
+	reply, err := conn.RequestName(serviceName, dbus.NameFlagDoNotQueue)
+	if err != nil || reply != dbus.RequestNameReplyPrimaryOwner {
+		t.Fatalf("request name: %v %v", reply, err)
+	}
+	return service
+}
+
+func TestStoreAndLookupRoundTrip(t *testing.T) {
+	service := startPrivateSecretService(t)
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	attributes := map[string]string{"service": "waybar-test", "account": "default"}
+	if _, err := Lookup(ctx, attributes); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("expected ErrNotFound before storing, got %v", err)
+	}
+
+	if err := Store(ctx, "waybar test token", attributes, "first-token"); err != nil {
+		t.Fatalf("store: %v", err)
+	}
+	if err := Store(ctx, "waybar test token", attributes, "second-token"); err != nil {
+		t.Fatalf("replace: %v", err)
+	}
+	service.mu.Lock()
+	count := len(service.items)
+	service.locked = true
+	service.mu.Unlock()
+	if count != 1 {
+		t.Fatalf("expected replace to keep one item, got %d", count)
+	}
+
+	token, err := Lookup(ctx, attributes)
+	if err != nil {
+		t.Fatalf("lookup: %v", err)
+	}
+	if token != "second-token" {
+		t.Fatalf("expected replaced token, got %q", token)
+	}
+}
+
+func TestParseAttributes(t *testing.T) {
+	attributes, err := ParseAttributes("service=waybar-linear, account=work")
+	if err != nil {
+		t.Fatalf("parse: %v", err)
+	}
+	if attributes["service"] != "waybar-linear" || attributes["account"] != "work" {
+		t.Fatalf("unexpected attributes %v", attributes)
+	}
+	if got := FormatAttributes(attributes); got != "account=work service=waybar-linear" {
+		t.Fatalf("unexpected format %q", got)
+	}
+	if _, err := ParseAttributes("service"); err == nil {
+		t.Fatal("expected error for attribute without value")
+	}
+}
+
+func TestReadValue(t *testing.T) {
+	for input, want := range map[string]string{
+		"ghp_secret\nignored\n": "ghp_secret",
+		"  lin_api_key  ":       "lin_api_key",
+		"":                      "",
+	} {
+		got, err := ReadValue(strings.NewReader(input))
+		if err != nil {
+			t.Fatalf("read %q: %v", input, err)
+		}
+		if got != want {
+			t.Fatalf("expected %q from %q, got %q", want, input, got)
+		}
+	}
+}
@@ -3,9 +3,14 @@ package main
 import (
 	"context"
 	"fmt"
+	"log/slog"
 	"os"
+	"os/signal"
+	"syscall"
 	"time"
 
+	"github.com/rbright/waybar-shared/daemon"
+	"github.com/rbright/waybar-shared/logging"
 	"github.com/rbright/waybar-sotto/internal/app"
 	"github.com/rbright/waybar-sotto/internal/config"
 )
@@ -20,26 +25,87 @@ func main() {
 		}
 	}
 
+	args, client := extractFlag(args, "--client")
+	args, debug := extractFlag(args, "--debug")
+
 	cfg, err := config.Load()
 	if err != nil {
 		fmt.Fprintln(os.Stderr, err)
 		os.Exit(2)
 	}
 
-	timeout := cfg.Timeout + 5*time.Second
-	if timeout < 10*time.Second {
-		timeout = 10 * time.Second
+	logLevel := cfg.LogLevel
+	if debug {
+		logLevel = "debug"
+	}
+	if logFile, err := logging.Setup(cfg.LogPath, logLevel); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+	} else {
+		defer func() {
+			_ = logFile.Close()
+		}()
+	}
+
+	timeout := app.Timeout(cfg, args)
+
+	if len(args) > 0 && args[0] == "daemon" {
+		if err := runDaemon(cfg); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(2)
+		}
+		return
+	}
+
+	if client {
+		if err := runClient(cfg, args, timeout); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(2)
+		}
+		return
 	}
 
 	ctx, cancel := context.WithTimeout(context.Background(), timeout)
 	defer cancel()
 
 	if err := app.Run(ctx, args, cfg, os.Stdout); err != nil {
+		slog.Error("command failed", "args", args, "error", err)
 		fmt.Fprintln(os.Stderr, err)
 		os.Exit(2)
 	}
 }
 
+func runDaemon(cfg config.Runtime) error {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	return daemon.Serve(ctx, app.Service(cfg))
+}
+
+func runClient(cfg config.Runtime, args []string, timeout time.Duration) error {
+	if len(args) == 0 || args[0] == "status" {
+		ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+		defer stop()
+		return daemon.Subscribe(ctx, cfg.SocketPath, []string{"status"}, os.Stdout)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	return daemon.Call(ctx, cfg.SocketPath, args, os.Stdout)
+}
+
+func extractFlag(args []string, flag string) ([]string, bool) {
+	filtered := make([]string, 0, len(args))
+	found := false
+	for _, arg := range args {
+		if arg == flag {
+			found = true
+			continue
+		}
+		filtered = append(filtered, arg)
+	}
+	return filtered, found
+}
+
 func printUsage() {
-	fmt.Println("waybar-sotto <status|refresh|select-item N|select-input>")
+	fmt.Println("waybar-sotto <status|refresh|select-item N|select-input|logs [N]|doctor|daemon> [--client] [--debug]")
 }
@@ -4,9 +4,13 @@ import (
 	"context"
 	"fmt"
 	"io"
+	"log/slog"
 	"strconv"
 	"strings"
 
+	"github.com/rbright/waybar-shared/barsignal"
+	"github.com/rbright/waybar-shared/itemref"
+	"github.com/rbright/waybar-shared/logging"
 	"github.com/rbright/waybar-sotto/internal/audio"
 	"github.com/rbright/waybar-sotto/internal/config"
 	"github.com/rbright/waybar-sotto/internal/selector"
@@ -16,7 +20,7 @@ import (
 )
 
 func Run(ctx context.Context, args []string, cfg config.Runtime, stdout io.Writer) error {
-	cmd, index, err := parseArgs(args)
+	cmd, ref, lines, err := parseArgs(args)
 	if err != nil {
 		return err
 	}
@@ -29,39 +33,61 @@ func Run(ctx context.Context, args []string, cfg config.Runtime, stdout io.Write
 		}
 		return writeOutput(stdout, out)
 	case "refresh":
-		_, err := buildStatus(ctx, cfg)
-		return err
+		if _, err := buildStatus(ctx, cfg); err != nil {
+			return err
+		}
+		signalBar(cfg)
+		return nil
 	case "select-item":
-		return selectItem(ctx, cfg, index)
+		return selectItem(ctx, cfg, ref)
 	case "select-input":
 		return selectInput(ctx, cfg)
+	case "logs":
+		return logging.Tail(cfg.LogPath, lines, stdout)
+	case "doctor":
+		return runDoctor(ctx, cfg, stdout)
 	default:
 		return fmt.Errorf("unsupported command %q", cmd)
 	}
 }
 
-func parseArgs(args []string) (command string, index int, err error) {
+// parseArgs returns the command, the item reference of item commands and the
+// line count of logs.
+func parseArgs(args []string) (command string, ref itemref.Ref, lines int, err error) {
 	if len(args) == 0 {
-		return "status", 0, nil
+		return "status", itemref.Ref{}, 0, nil
 	}
 
 	switch strings.TrimSpace(args[0]) {
-	case "status", "refresh", "select-input":
+	case "status", "refresh", "select-input", "doctor":
 		if len(args) > 1 {
-			return "", 0, fmt.Errorf("unexpected argument %q", args[1])
+			return "", itemref.Ref{}, 0, fmt.Errorf("unexpected argument %q", args[1])
 		}
-		return strings.TrimSpace(args[0]), 0, nil
+		return strings.TrimSpace(args[0]), itemref.Ref{}, 0, nil
 	case "select-item":
 		if len(args) != 2 {
-			return "", 0, fmt.Errorf("usage: waybar-sotto select-item <index>")
+			return "", itemref.Ref{}, 0, fmt.Errorf("usage: waybar-sotto select-item <N|id:KEY>")
+		}
+		ref, refErr := itemref.Parse(args[1])
+		if refErr != nil {
+			return "", itemref.Ref{}, 0, refErr
 		}
-		n, convErr := strconv.Atoi(strings.TrimSpace(args[1]))
-		if convErr != nil || n < 1 {
-			return "", 0, fmt.Errorf("invalid item index %q", args[1])
+		return "select-item", ref, 0, nil
+	case "logs":
+		if len(args) > 2 {
+			return "", itemref.Ref{}, 0, fmt.Errorf("usage: waybar-sotto logs [lines]")
 		}
-		return "select-item", n, nil
+		lines = 50
+		if len(args) == 2 {
+			n, convErr := strconv.Atoi(strings.TrimSpace(args[1]))
+			if convErr != nil || n < 1 {
+				return "", itemref.Ref{}, 0, fmt.Errorf("invalid line count %q", args[1])
+			}
+			lines = n
+		}
+		return "logs", itemref.Ref{}, lines, nil
 	default:
-		return "", 0, fmt.Errorf("usage: waybar-sotto <status|refresh|select-item N|select-input>")
+		return "", itemref.Ref{}, 0, fmt.Errorf("usage: waybar-sotto <status|refresh|select-item N|select-input|logs [N]|doctor>")
 	}
 }
 
@@ -72,8 +98,9 @@ func buildStatus(ctx context.Context, cfg config.Runtime) (waybar.Output, error)
 
 	devices, err := audio.ListDevices(ctx)
 	if err != nil {
+		slog.Warn("audio device discovery failed", "error", err)
 		statusLine := "Audio input discovery failed"
-		if saveErr := state.SaveItems(cfg.ItemsPath, []state.Item{}); saveErr != nil {
+		if _, saveErr := state.SaveItems(cfg.ItemsPath, []state.Item{}); saveErr != nil {
 			return waybar.Output{}, saveErr
 		}
 		if menuErr := state.WriteMenu(cfg.MenuPath, state.MenuData{StatusLine: statusLine}); menuErr != nil {
@@ -90,6 +117,7 @@ func buildStatus(ctx context.Context, cfg config.Runtime) (waybar.Output, error)
 
 	selection, selectionErr := sotto.ReadAudioSelection(cfg.SottoConfigFile)
 	if selectionErr != nil {
+		slog.Warn("sotto config unreadable", "path", cfg.SottoConfigFile, "error", selectionErr)
 		selection = sotto.AudioSelection{Input: "default", Fallback: "default"}
 	}
 	configuredInput := selection.Input
@@ -98,6 +126,7 @@ func buildStatus(ctx context.Context, cfg config.Runtime) (waybar.Output, error)
 	}
 
 	matchedConfiguredDevice, matched := audio.MatchConfiguredDevice(devices, configuredInput)
+	slog.Debug("audio devices discovered", "devices", len(devices), "active", len(activeDevices), "configured_input", configuredInput, "matched", matched)
 
 	items := make([]state.Item, 0, minInt(len(activeDevices), cfg.MaxItems))
 	truncatedCount := 0
@@ -122,7 +151,8 @@ func buildStatus(ctx context.Context, cfg config.Runtime) (waybar.Output, error)
 		}
 	}
 
-	if err := state.SaveItems(cfg.ItemsPath, items); err != nil {
+	slots, err := state.SaveItems(cfg.ItemsPath, items)
+	if err != nil {
 		return waybar.Output{}, err
 	}
 
@@ -134,6 +164,7 @@ func buildStatus(ctx context.Context, cfg config.Runtime) (waybar.Output, error)
 		StatusLine:     statusLine,
 		Items:          items,
 		TruncatedCount: truncatedCount,
+		Slots:          slots,
 	}); err != nil {
 		return waybar.Output{}, err
 	}
@@ -157,16 +188,12 @@ func buildStatus(ctx context.Context, cfg config.Runtime) (waybar.Output, error)
 	}, nil
 }
 
-func selectItem(ctx context.Context, cfg config.Runtime, index int) error {
-	items, err := state.LoadItems(cfg.ItemsPath)
-	if err != nil {
+func selectItem(ctx context.Context, cfg config.Runtime, ref itemref.Ref) error {
+	item, ok, err := state.ResolveItem(cfg.ItemsPath, ref)
+	if err != nil || !ok {
 		return err
 	}
-	if len(items) == 0 || index > len(items) {
-		return nil
-	}
 
-	item := items[index-1]
 	if strings.TrimSpace(item.ID) == "" {
 		return nil
 	}
@@ -175,8 +202,11 @@ func selectItem(ctx context.Context, cfg config.Runtime, index int) error {
 		return err
 	}
 
-	_, err = buildStatus(ctx, cfg)
-	return err
+	if _, err := buildStatus(ctx, cfg); err != nil {
+		return err
+	}
+	signalBar(cfg)
+	return nil
 }
 
 func selectInput(ctx context.Context, cfg config.Runtime) error {
@@ -219,8 +249,18 @@ func selectInput(ctx context.Context, cfg config.Runtime) error {
 		return err
 	}
 
-	_, err = buildStatus(ctx, cfg)
-	return err
+	if _, err := buildStatus(ctx, cfg); err != nil {
+		return err
+	}
+	signalBar(cfg)
+	return nil
+}
+
+func signalBar(cfg config.Runtime) {
+	if err := barsignal.Send(cfg.Signal); err != nil {
+		// The next interval catches up, so a failed signal only delays the bar.
+		slog.Debug("signal waybar failed", "signal", cfg.Signal, "error", err)
+	}
 }
 
 func writeOutput(w io.Writer, output waybar.Output) error {
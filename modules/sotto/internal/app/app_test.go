@@ -1,49 +1,89 @@
 package app
 
-import "testing"
+import (
+	"testing"
+
+	"github.com/rbright/waybar-shared/itemref"
+)
 
 func TestParseArgsDefaultsToStatus(t *testing.T) {
-	cmd, index, err := parseArgs(nil)
+	cmd, ref, _, err := parseArgs(nil)
 	if err != nil {
 		t.Fatalf("parseArgs returned error: %v", err)
 	}
 	if cmd != "status" {
 		t.Fatalf("expected status command, got %q", cmd)
 	}
-	if index != 0 {
-		t.Fatalf("expected zero index, got %d", index)
+	if ref != (itemref.Ref{}) {
+		t.Fatalf("expected no item reference, got %+v", ref)
 	}
 }
 
 func TestParseArgsSelectItem(t *testing.T) {
-	cmd, index, err := parseArgs([]string{"select-item", "3"})
+	cmd, ref, _, err := parseArgs([]string{"select-item", "3"})
 	if err != nil {
 		t.Fatalf("parseArgs returned error: %v", err)
 	}
 	if cmd != "select-item" {
 		t.Fatalf("expected select-item command, got %q", cmd)
 	}
-	if index != 3 {
-		t.Fatalf("expected index 3, got %d", index)
+	if ref.Slot != 3 {
+		t.Fatalf("expected slot 3, got %+v", ref)
+	}
+
+	_, ref, _, err = parseArgs([]string{"select-item", "id:alsa_input.usb"})
+	if err != nil {
+		t.Fatalf("parseArgs returned error: %v", err)
+	}
+	if ref.Key != "alsa_input.usb" {
+		t.Fatalf("expected key alsa_input.usb, got %+v", ref)
 	}
 }
 
 func TestParseArgsSelectInput(t *testing.T) {
-	cmd, index, err := parseArgs([]string{"select-input"})
+	cmd, ref, _, err := parseArgs([]string{"select-input"})
 	if err != nil {
 		t.Fatalf("parseArgs returned error: %v", err)
 	}
 	if cmd != "select-input" {
 		t.Fatalf("expected select-input command, got %q", cmd)
 	}
-	if index != 0 {
-		t.Fatalf("expected zero index, got %d", index)
+	if ref != (itemref.Ref{}) {
+		t.Fatalf("expected no item reference, got %+v", ref)
 	}
 }
 
 func TestParseArgsRejectsInvalidIndex(t *testing.T) {
-	_, _, err := parseArgs([]string{"select-item", "0"})
+	_, _, _, err := parseArgs([]string{"select-item", "0"})
 	if err == nil {
 		t.Fatal("expected error for invalid index")
 	}
 }
+
+func TestParseArgsLogs(t *testing.T) {
+	cmd, _, lines, err := parseArgs([]string{"logs"})
+	if err != nil {
+		t.Fatalf("parseArgs returned error: %v", err)
+	}
+	if cmd != "logs" || lines != 50 {
+		t.Fatalf("expected logs with default 50 lines, got %q %d", cmd, lines)
+	}
+
+	_, _, lines, err = parseArgs([]string{"logs", "200"})
+	if err != nil {
+		t.Fatalf("parseArgs returned error: %v", err)
+	}
+	if lines != 200 {
+		t.Fatalf("expected 200 lines, got %d", lines)
+	}
+}
+
+func TestParseArgsDoctor(t *testing.T) {
+	cmd, _, _, err := parseArgs([]string{"doctor"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if cmd != "doctor" {
+		t.Fatalf("expected doctor, got %q", cmd)
+	}
+}
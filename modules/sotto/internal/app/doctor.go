@@ -0,0 +1,40 @@
+package app
+
+import (
+	"context"
+	"fmt"
+	"io"
+
+	"github.com/rbright/waybar-shared/doctor"
+	"github.com/rbright/waybar-sotto/internal/audio"
+	"github.com/rbright/waybar-sotto/internal/config"
+	"github.com/rbright/waybar-sotto/internal/sotto"
+)
+
+func runDoctor(ctx context.Context, cfg config.Runtime, stdout io.Writer) error {
+	var report doctor.Report
+
+	report.ConfigFile("config", cfg.ConfigFile, config.ValidateEnvFile)
+	report.ConfigFile("sotto config", cfg.SottoConfigFile, func(path string) error {
+		_, err := sotto.ReadAudioSelection(path)
+		return err
+	})
+	report.LogLevel(cfg.LogLevel)
+	report.Command("fuzzel", "needed for select-input", true)
+	report.Command("hyprctl", "used to anchor the picker at the pointer", false)
+
+	if devices, err := audio.ListDevices(ctx); err != nil {
+		report.Fail("pulseaudio", err.Error())
+	} else {
+		active := audio.FilterActiveInputDevices(devices)
+		report.OK("pulseaudio", fmt.Sprintf("%d input device(s) available", len(active)))
+	}
+
+	report.WritableDir("state dir", cfg.StateDir)
+	report.WritableDir("menu dir", cfg.MenuDir)
+
+	if err := report.Write(stdout); err != nil {
+		return err
+	}
+	return report.Err()
+}
@@ -8,6 +8,7 @@ import (
 	"strings"
 	"time"
 
+	"github.com/rbright/waybar-shared/daemon"
 	"github.com/spf13/viper"
 )
 
@@ -25,6 +26,13 @@ type Runtime struct {
 	MenuDir   string
 	MenuPath  string
 	ItemsPath string
+
+	SocketPath     string
+	DaemonInterval time.Duration
+	Signal         int
+
+	LogLevel string
+	LogPath  string
 }
 
 func Load() (Runtime, error) {
@@ -62,6 +70,10 @@ func Load() (Runtime, error) {
 	_ = v.BindEnv("timeout_seconds", "WAYBAR_SOTTO_TIMEOUT_SECONDS")
 	_ = v.BindEnv("state_dir", "WAYBAR_SOTTO_STATE_DIR")
 	_ = v.BindEnv("menu_dir", "WAYBAR_SOTTO_MENU_DIR")
+	_ = v.BindEnv("socket_path", "WAYBAR_SOTTO_SOCKET_PATH")
+	_ = v.BindEnv("daemon_interval_seconds", "WAYBAR_SOTTO_DAEMON_INTERVAL_SECONDS")
+	_ = v.BindEnv("signal", "WAYBAR_SOTTO_SIGNAL")
+	_ = v.BindEnv("log_level", "WAYBAR_SOTTO_LOG_LEVEL")
 
 	v.SetDefault("sotto_config_file", filepath.Join(xdgConfig, "sotto", "config.jsonc"))
 	v.SetDefault("icon", "󰍬")
@@ -69,6 +81,10 @@ func Load() (Runtime, error) {
 	v.SetDefault("timeout_seconds", 10)
 	v.SetDefault("state_dir", filepath.Join(xdgState, "waybar", "sotto-input"))
 	v.SetDefault("menu_dir", filepath.Join(xdgState, "waybar", "menus"))
+	v.SetDefault("socket_path", daemon.SocketPath("sotto"))
+	v.SetDefault("daemon_interval_seconds", 60)
+	v.SetDefault("signal", 0)
+	v.SetDefault("log_level", "warn")
 
 	maxItems := v.GetInt("max_items")
 	if maxItems < 1 {
@@ -103,6 +119,21 @@ func Load() (Runtime, error) {
 		menuDir = filepath.Join(xdgState, "waybar", "menus")
 	}
 
+	socketPath := strings.TrimSpace(v.GetString("socket_path"))
+	if socketPath == "" {
+		socketPath = daemon.SocketPath("sotto")
+	}
+
+	daemonIntervalSeconds := v.GetInt("daemon_interval_seconds")
+	if daemonIntervalSeconds <= 0 {
+		daemonIntervalSeconds = 60
+	}
+
+	signal := v.GetInt("signal")
+	if signal < 0 || signal > 30 {
+		signal = 0
+	}
+
 	return Runtime{
 		ConfigFile:      configFile,
 		SottoConfigFile: sottoConfigFile,
@@ -113,6 +144,13 @@ func Load() (Runtime, error) {
 		MenuDir:         menuDir,
 		MenuPath:        filepath.Join(menuDir, "sotto-input.xml"),
 		ItemsPath:       filepath.Join(stateDir, "items.json"),
+
+		SocketPath:     socketPath,
+		DaemonInterval: time.Duration(daemonIntervalSeconds) * time.Second,
+		Signal:         signal,
+
+		LogLevel: strings.TrimSpace(v.GetString("log_level")),
+		LogPath:  filepath.Join(stateDir, "sotto.log"),
 	}, nil
 }
 
@@ -168,3 +206,37 @@ func loadEnvFile(path string) error {
 	}
 	return nil
 }
+
+// ValidateEnvFile reports lines that the env-file loader would silently skip.
+func ValidateEnvFile(path string) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("open env file %s: %w", path, err)
+	}
+	defer func() {
+		_ = file.Close()
+	}()
+
+	var problems []string
+	scanner := bufio.NewScanner(file)
+	for lineNumber := 1; scanner.Scan(); lineNumber++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		line = strings.TrimSpace(strings.TrimPrefix(line, "export "))
+
+		key, _, ok := strings.Cut(line, "=")
+		if !ok || strings.TrimSpace(key) == "" {
+			problems = append(problems, fmt.Sprintf("line %d", lineNumber))
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return fmt.Errorf("scan env file %s: %w", path, err)
+	}
+
+	if len(problems) > 0 {
+		return fmt.Errorf("%s: malformed KEY=VALUE on %s", path, strings.Join(problems, ", "))
+	}
+	return nil
+}
@@ -1,12 +1,13 @@
 package state
 
 import (
-	"encoding/json"
 	"fmt"
-	"html"
 	"os"
 	"path/filepath"
 	"strings"
+
+	"github.com/rbright/waybar-shared/itemref"
+	"github.com/rbright/waybar-shared/menu"
 )
 
 type Item struct {
@@ -22,6 +23,8 @@ type MenuData struct {
 	StatusLine     string
 	Items          []Item
 	TruncatedCount int
+	// Slots are the menu slots of Items, which row ids use.
+	Slots []int
 }
 
 func EnsureDirs(stateDir, menuDir string) error {
@@ -34,33 +37,22 @@ func EnsureDirs(stateDir, menuDir string) error {
 	return nil
 }
 
-func SaveItems(path string, items []Item) error {
-	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
-		return fmt.Errorf("create items dir: %w", err)
-	}
-
-	payload, err := json.MarshalIndent(items, "", "  ")
-	if err != nil {
-		return fmt.Errorf("marshal items: %w", err)
-	}
+// SaveItems writes items and returns the menu slot of each one.
+func SaveItems(path string, items []Item) ([]int, error) {
+	return itemref.Save(path, items, itemKey)
+}
 
-	return writeFileAtomically(path, append(payload, '\n'))
+// LoadItems returns the saved items and the menu slot of each one.
+func LoadItems(path string) ([]Item, []int, error) {
+	return itemref.Load(path, itemKey)
 }
 
-func LoadItems(path string) ([]Item, error) {
-	raw, err := os.ReadFile(path)
-	if err != nil {
-		if os.IsNotExist(err) {
-			return nil, nil
-		}
-		return nil, fmt.Errorf("read items file: %w", err)
-	}
+func ResolveItem(path string, ref itemref.Ref) (Item, bool, error) {
+	return itemref.Resolve(path, ref, itemKey)
+}
 
-	var items []Item
-	if err := json.Unmarshal(raw, &items); err != nil {
-		return nil, fmt.Errorf("decode items file: %w", err)
-	}
-	return items, nil
+func itemKey(item Item) string {
+	return item.ID
 }
 
 func WriteMenu(path string, data MenuData) error {
@@ -68,57 +60,33 @@ func WriteMenu(path string, data MenuData) error {
 		return fmt.Errorf("create menu dir: %w", err)
 	}
 
-	var b strings.Builder
-	b.WriteString("<?xml version=\"1.0\" encoding=\"UTF-8\"?>\n")
-	b.WriteString("<interface>\n")
-	b.WriteString("  <object class=\"GtkMenu\" id=\"menu\">\n")
-
+	m := menu.New()
 	if strings.TrimSpace(data.CurrentLine) != "" {
-		writeMenuItem(&b, "noop_current", data.CurrentLine)
-		writeSeparator(&b, "separator_current")
+		m.Info(data.CurrentLine)
+		m.Separator()
 	}
 
 	if len(data.Items) > 0 {
 		for idx, item := range data.Items {
 			label := item.Label
-			if item.Active {
-				label = "✓ " + label
-			}
 			if item.Muted {
 				label += " (muted)"
 			}
-			writeMenuItem(&b, fmt.Sprintf("select_%d", idx+1), label)
+			m.Check(fmt.Sprintf("select_%d", itemref.Slot(data.Slots, idx)), label, item.Active)
 		}
 	} else {
-		writeMenuItem(&b, "noop_empty", fallback(data.StatusLine, "No active microphone inputs"))
+		m.Info(fallback(data.StatusLine, "No active microphone inputs"))
 	}
 
 	if data.TruncatedCount > 0 {
-		writeSeparator(&b, "separator_truncated")
-		writeMenuItem(&b, "noop_truncated", fmt.Sprintf("%d additional inputs hidden", data.TruncatedCount))
+		m.Separator()
+		m.Info(fmt.Sprintf("%d additional inputs hidden", data.TruncatedCount))
 	}
 
-	writeSeparator(&b, "separator_footer")
-	writeMenuItem(&b, "refresh", "Refresh")
-
-	b.WriteString("  </object>\n")
-	b.WriteString("</interface>\n")
-
-	return writeFileAtomically(path, []byte(b.String()))
-}
-
-func writeMenuItem(b *strings.Builder, id, label string) {
-	b.WriteString("    <child>\n")
-	_, _ = fmt.Fprintf(b, "      <object class=\"GtkMenuItem\" id=\"%s\">\n", html.EscapeString(id))
-	_, _ = fmt.Fprintf(b, "        <property name=\"label\">%s</property>\n", html.EscapeString(label))
-	b.WriteString("      </object>\n")
-	b.WriteString("    </child>\n")
-}
+	m.Separator()
+	m.Item("refresh", "Refresh")
 
-func writeSeparator(b *strings.Builder, id string) {
-	b.WriteString("    <child>\n")
-	_, _ = fmt.Fprintf(b, "      <object class=\"GtkSeparatorMenuItem\" id=\"%s\" />\n", html.EscapeString(id))
-	b.WriteString("    </child>\n")
+	return writeFileAtomically(path, m.Bytes())
 }
 
 func writeFileAtomically(path string, content []byte) error {